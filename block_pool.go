@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkdoug"
 	"github.com/eris-ltd/thelonious/monklog"
 	"github.com/eris-ltd/thelonious/monkreact"
 	"github.com/eris-ltd/thelonious/monkutil"
@@ -34,6 +35,9 @@ type BlockPool struct {
 	hashPool [][]byte
 	pool     map[string]*block
 
+	// blocks from the future, held until their time comes
+	future map[string]*monkchain.Block
+
 	td   *big.Int
 	quit chan bool
 
@@ -49,11 +53,12 @@ type BlockPool struct {
 
 func NewBlockPool(eth *Thelonious) *BlockPool {
 	return &BlockPool{
-		eth:   eth,
-		pool:  make(map[string]*block),
-		td:    monkutil.Big0,
-		quit:  make(chan bool),
-		start: make(chan monkreact.Event),
+		eth:    eth,
+		pool:   make(map[string]*block),
+		future: make(map[string]*monkchain.Block),
+		td:     monkutil.Big0,
+		quit:   make(chan bool),
+		start:  make(chan monkreact.Event),
 	}
 }
 
@@ -147,8 +152,8 @@ func (self *BlockPool) Add(b *monkchain.Block, peer *Peer) {
 		return
 	}
 
-	// if we have the block, do nothing
-	if cman.HasBlock(b.Hash()) {
+	// if we have the block, or are holding it, do nothing
+	if cman.HasBlock(b.Hash()) || self.future[hash] != nil {
 		return
 	}
 
@@ -173,6 +178,54 @@ func (self *BlockPool) Add(b *monkchain.Block, peer *Peer) {
 	self.BlocksProcessed++
 }
 
+// Max number of future blocks we hold on to
+var MaxFutureBlocks = 256
+
+// Take blocks from number on out of the pool until their time comes.
+// When we're full, the block furthest in the future makes room
+func (self *BlockPool) holdFuture(blocks []*monkchain.Block, number uint64) {
+	for _, b := range blocks {
+		if b.Number.Uint64() < number {
+			continue
+		}
+		self.Remove(b.Hash())
+		self.mut.Lock()
+		if len(self.future) >= MaxFutureBlocks {
+			var furthest string
+			for hash, f := range self.future {
+				if furthest == "" || f.Time > self.future[furthest].Time {
+					furthest = hash
+				}
+			}
+			if self.future[furthest].Time > b.Time {
+				delete(self.future, furthest)
+			}
+		}
+		if len(self.future) < MaxFutureBlocks {
+			self.future[string(b.Hash())] = b
+		}
+		self.mut.Unlock()
+	}
+}
+
+// Put future blocks whose time has come back in the pool
+func (self *BlockPool) releaseFuture() {
+	self.mut.Lock()
+	defer self.mut.Unlock()
+
+	now := monkdoug.Clock().Unix()
+	for hash, b := range self.future {
+		if b.Time > now {
+			continue
+		}
+		delete(self.future, hash)
+		if self.pool[hash] == nil {
+			self.hashPool = append(self.hashPool, b.Hash())
+			self.pool[hash] = &block{nil, nil, b, time.Now(), 0}
+		}
+	}
+}
+
 func (self *BlockPool) Remove(hash []byte) {
 	self.mut.Lock()
 	defer self.mut.Unlock()
@@ -333,6 +386,8 @@ out:
 		case <-self.quit:
 			break out
 		case <-procTimer.C:
+			self.releaseFuture()
+
 			// We'd need to make sure that the pools are properly protected by a mutex
 			blocks := self.Blocks()
 			monkchain.BlockBy(monkchain.Number).Sort(blocks)
@@ -373,11 +428,20 @@ out:
 				bchain := monkchain.NewChain(blocks)
 				// validate the chain
 				_, err := chainManager.TestChain(bchain)
+				for _, ev := range chainManager.DetectedEvidence() {
+					self.eth.ReportEvidence(ev)
+				}
 
+				// Blocks from the future wait their turn.
 				// If validation failed, we flush the pool
 				// and punish the peer
-				if err != nil && !monkchain.IsTDError(err) {
+				if ferr, ok := err.(*monkchain.FutureBlockErr); ok {
+					self.holdFuture(blocks, ferr.Number)
+				} else if err != nil && !monkchain.IsTDError(err) {
 					poollogger.Debugln(err)
+					if ierr, ok := err.(*monkchain.InvalidBlockErr); ok {
+						self.quarantine(blocks, ierr)
+					}
 
 					self.Reset()
 					//self.punishPeer()
@@ -402,6 +466,35 @@ out:
 	}
 }
 
+// Keep the block that failed validation, and who sent it, for debugging
+func (self *BlockPool) quarantine(blocks []*monkchain.Block, err *monkchain.InvalidBlockErr) {
+	for _, b := range blocks {
+		if bytes.Compare(b.Hash(), err.Hash) == 0 {
+			self.eth.ChainManager().AddBadBlock(b, err, self.blockPeer(err.Hash))
+			return
+		}
+	}
+}
+
+// Address of the peer we got a block from
+func (self *BlockPool) blockPeer(hash []byte) string {
+	self.mut.Lock()
+	defer self.mut.Unlock()
+
+	item := self.pool[string(hash)]
+	if item == nil {
+		return ""
+	}
+	peer := item.peer
+	if peer == nil {
+		peer = item.from
+	}
+	if peer == nil || peer.conn == nil {
+		return ""
+	}
+	return peer.conn.RemoteAddr().String()
+}
+
 func (self *BlockPool) punishPeer() {
 	/*
 		                        TODO: fix this peer handling!
@@ -0,0 +1,58 @@
+package thelonious
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkdb"
+	"github.com/eris-ltd/thelonious/monkdoug"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func init() {
+	monkutil.ReadConfig(".ethtest", "/tmp/ethtest", "")
+	db, _ := monkdb.NewMemDatabase()
+	monkutil.Config.Db = db
+}
+
+func futureBlock(n, t int64) *monkchain.Block {
+	block := monkchain.CreateBlock(nil, monkchain.ZeroHash256, nil, big.NewInt(1), nil, "")
+	block.Number = big.NewInt(n)
+	block.Time = t
+	return block
+}
+
+func TestHoldFuture(t *testing.T) {
+	now := time.Unix(1000, 0)
+	monkdoug.Clock = func() time.Time { return now }
+	defer func() { monkdoug.Clock = time.Now }()
+	defer func(n int) { MaxFutureBlocks = n }(MaxFutureBlocks)
+	MaxFutureBlocks = 2
+
+	pool := NewBlockPool(nil)
+	early, late, later := futureBlock(1, 1010), futureBlock(2, 1050), futureBlock(3, 1100)
+	// blocks before the failed one aren't held
+	pool.holdFuture([]*monkchain.Block{futureBlock(0, 900), late, later}, 2)
+	if len(pool.future) != 2 {
+		t.Fatalf("expected 2 future blocks, got %d", len(pool.future))
+	}
+	// a nearer block pushes out the furthest
+	pool.holdFuture([]*monkchain.Block{early}, 1)
+	if len(pool.future) != 2 || pool.future[string(later.Hash())] != nil || pool.future[string(early.Hash())] == nil {
+		t.Fatal("expected the furthest block to be evicted")
+	}
+	// but a further one doesn't get in
+	pool.holdFuture([]*monkchain.Block{later}, 3)
+	if pool.future[string(later.Hash())] != nil {
+		t.Fatal("expected the furthest block to stay out")
+	}
+
+	// blocks go back in the pool when their time comes
+	now = time.Unix(1020, 0)
+	pool.releaseFuture()
+	if len(pool.future) != 1 || pool.pool[string(early.Hash())] == nil || pool.Len() != 1 {
+		t.Fatal("expected the early block to be released")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/eris-ltd/thelonious/monkdoug"
+)
+
+/*
+   Simulate a chain's difficulty over time, without a network or real pow:
+       diffsim -config sim.json -format csv > out.csv
+
+   sim.json looks like
+   {
+       "consensus":"robin",
+       "difficulty":20,
+       "blocktime":5,
+       "blocks":1000,
+       "miners":[
+           {"name":"fast", "hashrate":500000},
+           {"name":"slow", "hashrate":100000, "skew":-2},
+           {"name":"late", "hashrate":200000, "join":600, "leave":3600}
+       ]
+   }
+*/
+
+var (
+	config = flag.String("config", "", "simulation config (json)")
+	format = flag.String("format", "csv", "output format (csv or json)")
+	out    = flag.String("out", "", "output file (default stdout)")
+	blocks = flag.Int("blocks", 0, "number of blocks (overrides the config)")
+	seed   = flag.Int64("seed", 0, "random seed (overrides the config)")
+)
+
+func main() {
+	flag.Parse()
+	if *config == "" {
+		log.Fatal("Please specify a simulation config with -config")
+	}
+
+	b, err := ioutil.ReadFile(*config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c := new(monkdoug.SimConfig)
+	if err := json.Unmarshal(b, c); err != nil {
+		log.Fatal("Bad simulation config: ", err)
+	}
+	if *blocks > 0 {
+		c.Blocks = *blocks
+	}
+	if *seed != 0 {
+		c.Seed = *seed
+	}
+
+	rows, err := monkdoug.Simulate(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "json":
+		err = json.NewEncoder(w).Encode(rows)
+	case "csv":
+		err = writeCSV(w, c, rows)
+	default:
+		err = fmt.Errorf("Unknown format %s", *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// One row per block, with a share column for each miner
+func writeCSV(w io.Writer, c *monkdoug.SimConfig, rows []*monkdoug.SimBlock) error {
+	cw := csv.NewWriter(w)
+	header := []string{"number", "time", "interval", "difficulty", "miner"}
+	for _, m := range c.Miners {
+		header = append(header, "share:"+m.Name)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		record := []string{
+			strconv.Itoa(r.Number),
+			strconv.FormatFloat(r.Time, 'f', 3, 64),
+			strconv.FormatFloat(r.Interval, 'f', 3, 64),
+			r.Difficulty.String(),
+			r.Miner,
+		}
+		for _, m := range c.Miners {
+			record = append(record, strconv.FormatFloat(r.Shares[m.Name], 'f', 4, 64))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/eris-ltd/thelonious/monk"
+	"github.com/eris-ltd/thelonious/monkchain"
+)
+
+// monk export <file> [from] [to]
+func RunExport(m *monk.MonkModule, args []string) {
+	if len(args) == 0 || len(args) > 3 {
+		fmt.Println("usage: monk export <file> [from] [to]")
+		os.Exit(1)
+	}
+	var from, to uint64 = 0, math.MaxUint64
+	var err error
+	if len(args) > 1 {
+		if from, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+			fmt.Println("Invalid block number", args[1])
+			os.Exit(1)
+		}
+	}
+	if len(args) > 2 {
+		if to, err = strconv.ParseUint(args[2], 10, 64); err != nil {
+			fmt.Println("Invalid block number", args[2])
+			os.Exit(1)
+		}
+	}
+
+	m.Init()
+	n, err := m.ExportChain(args[0], from, to)
+	if err != nil {
+		fmt.Println("Export failed:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %d blocks to %s\n", n, args[0])
+	os.Exit(0)
+}
+
+// monk import <file>
+func RunImport(m *monk.MonkModule, args []string) {
+	if len(args) != 1 {
+		fmt.Println("usage: monk import <file>")
+		os.Exit(1)
+	}
+
+	m.Init()
+	start := time.Now()
+	last := start
+	n, err := m.ImportChain(args[0], func(block *monkchain.Block) {
+		if time.Since(last) > 5*time.Second {
+			fmt.Printf("Imported up to #%v (%x)\n", block.Number, block.Hash()[:4])
+			last = time.Now()
+		}
+	})
+	fmt.Printf("Imported %d blocks in %v\n", n, time.Since(start))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// monk sethead <n>
+func RunSetHead(m *monk.MonkModule, args []string) {
+	if len(args) != 1 {
+		fmt.Println("usage: monk sethead <n>")
+		os.Exit(1)
+	}
+	n, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		fmt.Println("Invalid block number", args[0])
+		os.Exit(1)
+	}
+
+	m.Init()
+	if err := m.SetHead(n); err != nil {
+		fmt.Println("SetHead failed:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Chain head set to #%d\n", n)
+	os.Exit(0)
+}
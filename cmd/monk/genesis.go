@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/eris-ltd/thelonious/monk"
+)
+
+// monk genesis plan [genesis.json]
+func RunGenesis(m *monk.MonkModule, args []string) {
+	if len(args) == 0 || args[0] != "plan" {
+		fmt.Println("usage: monk genesis plan [genesis.json]")
+		os.Exit(1)
+	}
+	file := m.Config.GenesisConfig
+	if len(args) > 1 {
+		file = args[1]
+	}
+	GenesisPlan(m, file)
+	os.Exit(0)
+}
+
+func GenesisPlan(m *monk.MonkModule, file string) {
+	plan, errs := m.PlanGenesis(file)
+	if len(errs) > 0 {
+		fmt.Println("Invalid genesis config", file)
+		for _, err := range errs {
+			fmt.Println("\t", err)
+		}
+		os.Exit(1)
+	}
+
+	fmt.Printf("ChainId:\t%x\n", plan.ChainId)
+	fmt.Printf("State root:\t%x\n", plan.StateRoot)
+	fmt.Println("Accounts:")
+	for _, acc := range plan.Accounts {
+		fmt.Printf("\t%x (%s)\tbalance: %v\tstake: %v\n", acc.Address, acc.Name, acc.Balance, acc.Stake)
+		perms := []string{}
+		for perm := range acc.Permissions {
+			perms = append(perms, perm)
+		}
+		sort.Strings(perms)
+		for _, perm := range perms {
+			fmt.Printf("\t\t%s: %v\n", perm, acc.Permissions[perm])
+		}
+	}
+	if len(plan.Singles) > 0 {
+		fmt.Println("GenDoug singles:")
+		for _, single := range plan.Singles {
+			fmt.Printf("\t%s: %s\n", single[0], single[1])
+		}
+	}
+}
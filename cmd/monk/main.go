@@ -16,6 +16,7 @@ var (
 	rpcHost    = flag.String("rpc-host", "", "Set rpc host ip address")
 	rpcPort    = flag.Int("rpc-port", 30304, "Set rpc host port")
 	serveRpc   = flag.Bool("serve-rpc", false, "Run the rpc server")
+	rpcAdmin   = flag.Bool("rpc-admin", false, "Serve admin calls (eg. SetHead) over rpc")
 
 	chainId   = flag.String("chainId", "", "Select chain by chainId")
 	chainName = flag.String("name", "", "Select chain by name")
@@ -65,6 +66,7 @@ func main() {
 	m.Config.RpcHost = *rpcHost
 	m.Config.RpcPort = *rpcPort
 	m.Config.ServeRpc = *serveRpc
+	m.Config.RpcAdmin = *rpcAdmin
 
 	m.Config.ChainId = *chainId
 	m.Config.ChainName = *chainName
@@ -97,6 +99,22 @@ func main() {
 	m.Config.DebugFile = *debugFile
 	m.Config.LogLevel = *logLevel
 
+	if flag.Arg(0) == "genesis" {
+		RunGenesis(m, flag.Args()[1:])
+	}
+
+	if flag.Arg(0) == "export" {
+		RunExport(m, flag.Args()[1:])
+	}
+
+	if flag.Arg(0) == "import" {
+		RunImport(m, flag.Args()[1:])
+	}
+
+	if flag.Arg(0) == "sethead" {
+		RunSetHead(m, flag.Args()[1:])
+	}
+
 	if *test != "" {
 		RunTest(m, *test)
 	}
@@ -39,6 +39,7 @@ type ChainConfig struct {
 	RpcHost    string `json:"rpc_host"`
 	RpcPort    int    `json:"rpc_port"`
 	ServeRpc   bool   `json:"serve_rpc"`
+	RpcAdmin   bool   `json:"rpc_admin"`
 
 	// ChainId and Name
 	ChainId   string `json:"chain_id"`
@@ -58,6 +59,9 @@ type ChainConfig struct {
 	UseCheckpoint    bool   `json:"use_checkpoint"`
 	LatestCheckpoint string `json:"latest_checkpoint"`
 
+	// Hex addresses trusted to sign the genesis block (any if empty)
+	TrustedSigners []string `json:"trusted_signers"`
+
 	// Paths
 	ConfigFile    string `json:"config_file"`
 	RootDir       string `json:"root_dir"`
@@ -90,6 +94,7 @@ var DefaultConfig = &ChainConfig{
 	RpcHost:    "",
 	RpcPort:    30304,
 	ServeRpc:   false,
+	RpcAdmin:   false,
 
 	// ChainId and Name
 	ChainId:   "",
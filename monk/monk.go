@@ -152,6 +152,10 @@ func (mod *MonkModule) Init() error {
 	}
 
 	monkdoug.Adversary = mod.Config.Adversary
+	monkdoug.TrustedSigners = nil
+	for _, signer := range mod.Config.TrustedSigners {
+		monkdoug.TrustedSigners = append(monkdoug.TrustedSigners, monkutil.UserHex2Bytes(signer))
+	}
 
 	// if no thelonious instance
 	if m.thelonious == nil {
@@ -190,7 +194,7 @@ func (mod *MonkModule) Start() (err error) {
 	}
 
 	if m.config.ServeRpc {
-		StartRpc(m.thelonious, m.config.RpcHost, m.config.RpcPort)
+		StartRpc(m.thelonious, m.config.RpcHost, m.config.RpcPort, m.config.RpcAdmin)
 	}
 
 	m.Subscribe("newBlock", "newBlock", "")
@@ -256,6 +260,14 @@ func (mod *MonkModule) Block(hash string) *modules.Block {
 	return mod.monk.Block(hash)
 }
 
+func (mod *MonkModule) BlockByNumber(n int) *modules.Block {
+	return mod.monk.BlockByNumber(n)
+}
+
+func (mod *MonkModule) Transaction(hash string) (*modules.Transaction, string) {
+	return mod.monk.Transaction(hash)
+}
+
 func (mod *MonkModule) IsScript(target string) bool {
 	return mod.monk.IsScript(target)
 }
@@ -344,6 +356,49 @@ func (mod *MonkModule) LoadGenesis(file string) *monkdoug.GenesisConfig {
 	return g
 }
 
+// Validate a genesis json file and dry run its deploy in a memory db.
+// Returns the plan, or every problem with the config
+func (mod *MonkModule) PlanGenesis(file string) (*monkdoug.GenesisPlan, []error) {
+	mod.setLLLPath()
+	g := monkdoug.LoadGenesis(file)
+	if errs := g.Validate(); len(errs) > 0 {
+		return nil, errs
+	}
+	plan, err := g.Plan()
+	if err != nil {
+		return nil, []error{err}
+	}
+	return plan, nil
+}
+
+// Write canonical blocks from..to to file as a stream of rlp blocks.
+// Init must have been called
+func (mod *MonkModule) ExportChain(file string, from, to uint64) (uint64, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return mod.monk.thelonious.ChainManager().Export(f, from, to)
+}
+
+// Validate and add the blocks in an exported file to our chain.
+// Init must have been called
+func (mod *MonkModule) ImportChain(file string, progress func(*monkchain.Block)) (uint64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return mod.monk.thelonious.ChainManager().Import(f, progress)
+}
+
+// Rewind the chain so block n is the head.
+// Init must have been called
+func (mod *MonkModule) SetHead(n uint64) error {
+	return mod.monk.thelonious.BlockManager().SetHead(n)
+}
+
 // Set the genesis json object. This can only be done once
 func (mod *MonkModule) SetGenesis(genJson *monkdoug.GenesisConfig) {
 	// reset the permission model struct (since config may have changed)
@@ -453,6 +508,20 @@ func (monk *Monk) Block(hash string) *modules.Block {
 	return convertBlock(block)
 }
 
+func (monk *Monk) BlockByNumber(n int) *modules.Block {
+	block := monk.thelonious.ChainManager().GetBlockByNumber(uint64(n))
+	return convertBlock(block)
+}
+
+// A canonical tx, and the hash of the block it's in
+func (monk *Monk) Transaction(hash string) (*modules.Transaction, string) {
+	tx, block, _ := monk.thelonious.ChainManager().GetTransaction(monkutil.Hex2Bytes(hash))
+	if tx == nil {
+		return nil, ""
+	}
+	return convertTx(tx), monkutil.Bytes2Hex(block.Hash())
+}
+
 func (monk *Monk) IsScript(target string) bool {
 	// is contract if storage is empty and no bytecode
 	obj := monk.Account(target)
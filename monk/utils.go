@@ -160,19 +160,25 @@ func KeyTasks(keyManager *monkcrypto.KeyManager, KeyRing string, GenAddr bool, S
 	}
 }
 
-func StartRpc(ethereum *eth.Thelonious, RpcHost string, RpcPort int) {
+func StartRpc(ethereum *eth.Thelonious, RpcHost string, RpcPort int, RpcAdmin bool) {
 	var err error
 	rpcAddr := RpcHost + ":" + strconv.Itoa(RpcPort)
 	ethereum.RpcServer, err = monkrpc.NewJsonRpcServer(monkpipe.NewJSPipe(ethereum), rpcAddr)
 	if err != nil {
 		logger.Errorf("Could not start RPC interface (port %v): %v", RpcPort, err)
 	} else {
+		if RpcAdmin {
+			ethereum.RpcServer.EnableAdmin()
+		}
 		go ethereum.RpcServer.Start()
 	}
 }
 
 var miner *monkminer.Miner
 
+// validators in a bft chain run rounds instead of mining
+var bftMiner *monkminer.BftMiner
+
 func GetMiner() *monkminer.Miner {
 	return miner
 }
@@ -183,6 +189,21 @@ func StartMining(ethereum *eth.Thelonious) bool {
 		ethereum.Mining = true
 		addr := ethereum.KeyManager().Address()
 
+		if model, ok := bftModel(ethereum); ok {
+			go func() {
+				logger.Infoln("Start bft rounds")
+				for !ethereum.IsUpToDate() {
+					time.Sleep(5 * time.Second)
+				}
+				bftMiner = monkminer.NewBftMiner(addr, ethereum, model)
+				bftMiner.Start()
+			}()
+			RegisterInterrupt(func(os.Signal) {
+				StopMining(ethereum)
+			})
+			return true
+		}
+
 		go func() {
 			logger.Infoln("Start mining")
 			if miner == nil {
@@ -216,7 +237,24 @@ func FormatTransactionData(data string) []byte {
 	return d
 }
 
+// The bft model, if the chain uses it
+func bftModel(ethereum *eth.Thelonious) (*monkdoug.BftModel, bool) {
+	p, ok := ethereum.Protocol().(*monkdoug.Protocol)
+	if !ok {
+		return nil, false
+	}
+	model, ok := p.Consensus().(*monkdoug.BftModel)
+	return model, ok
+}
+
 func StopMining(ethereum *eth.Thelonious) bool {
+	if ethereum.Mining && bftMiner != nil {
+		bftMiner.Stop()
+		logger.Infoln("Stopped bft rounds")
+		ethereum.Mining = false
+		bftMiner = nil
+		return true
+	}
 	if ethereum.Mining && miner != nil {
 		miner.Stop()
 		logger.Infoln("Stopped mining")
@@ -0,0 +1,136 @@
+package monkchain
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+// Max number of rejected blocks we hold on to.
+// The oldest are dropped to make room for new ones
+var MaxBadBlocks = 128
+
+var badBlocksKey = []byte("BadBlocks")
+
+func badBlockKey(hash []byte) []byte {
+	return append([]byte("BadBlock"), hash...)
+}
+
+// A block that failed validation, kept so consensus
+// splits can be debugged by replaying it locally
+type BadBlock struct {
+	Hash   []byte
+	Number uint64
+	// why it was rejected
+	Err string
+	// who sent it, if anyone
+	Peer string
+	// unix time it was rejected at
+	Time int64
+	// the rlp encoded block
+	Raw []byte
+}
+
+func NewBadBlockFromValue(val *monkutil.Value) *BadBlock {
+	b := &BadBlock{}
+	b.Hash = val.Get(0).Bytes()
+	b.Number = val.Get(1).Uint()
+	b.Err = val.Get(2).Str()
+	b.Peer = val.Get(3).Str()
+	b.Time = val.Get(4).Int()
+	b.Raw = val.Get(5).Bytes()
+	return b
+}
+
+func (b *BadBlock) RlpData() []interface{} {
+	return []interface{}{b.Hash, b.Number, b.Err, b.Peer, b.Time, b.Raw}
+}
+
+func (b *BadBlock) Block() *Block {
+	return NewBlockFromBytes(b.Raw)
+}
+
+func (b *BadBlock) String() string {
+	return fmt.Sprintf("BadBlock{#%d %x from: %q at: %v err: %q}", b.Number, b.Hash, b.Peer, time.Unix(b.Time, 0), b.Err)
+}
+
+// Hashes of the bad blocks we hold, oldest first
+func (bc *ChainManager) badBlockHashes() [][]byte {
+	data, _ := monkutil.Config.Db.Get(badBlocksKey)
+	if len(data) == 0 {
+		return nil
+	}
+	val := monkutil.NewValueFromBytes(data)
+	hashes := make([][]byte, val.Len())
+	for i := range hashes {
+		hashes[i] = val.Get(i).Bytes()
+	}
+	return hashes
+}
+
+// Quarantine a block that failed validation, along with the
+// reason and the peer that sent it
+func (bc *ChainManager) AddBadBlock(block *Block, reason error, peer string) {
+	// we want what the block did wrong, not where it was in the chain
+	if invalid, ok := reason.(*InvalidBlockErr); ok {
+		reason = invalid.Err
+	}
+	bad := &BadBlock{
+		Hash:   block.Hash(),
+		Number: block.Number.Uint64(),
+		Err:    reason.Error(),
+		Peer:   peer,
+		Time:   time.Now().Unix(),
+		Raw:    block.RlpEncode(),
+	}
+
+	bc.mut.Lock()
+	defer bc.mut.Unlock()
+
+	db := monkutil.Config.Db
+	hashes := bc.badBlockHashes()
+	for _, h := range hashes {
+		if bytes.Equal(h, bad.Hash) {
+			return
+		}
+	}
+	hashes = append(hashes, bad.Hash)
+	for len(hashes) > MaxBadBlocks {
+		db.Delete(badBlockKey(hashes[0]))
+		hashes = hashes[1:]
+	}
+	db.Put(badBlockKey(bad.Hash), monkutil.Encode(bad.RlpData()))
+	list := make([]interface{}, len(hashes))
+	for i, h := range hashes {
+		list[i] = h
+	}
+	db.Put(badBlocksKey, monkutil.Encode(list))
+
+	chainlogger.Infof("Quarantined bad block #%d (%x) from %s: %v\n", bad.Number, bad.Hash[:4], peer, reason)
+}
+
+// A quarantined block by hash
+func (bc *ChainManager) GetBadBlock(hash []byte) *BadBlock {
+	data, _ := monkutil.Config.Db.Get(badBlockKey(hash))
+	if len(data) == 0 {
+		return nil
+	}
+	return NewBadBlockFromValue(monkutil.NewValueFromBytes(data))
+}
+
+// All the quarantined blocks, newest first
+func (bc *ChainManager) BadBlocks() []*BadBlock {
+	bc.mut.Lock()
+	hashes := bc.badBlockHashes()
+	bc.mut.Unlock()
+
+	bad := make([]*BadBlock, 0, len(hashes))
+	for i := len(hashes) - 1; i >= 0; i-- {
+		if b := bc.GetBadBlock(hashes[i]); b != nil {
+			bad = append(bad, b)
+		}
+	}
+	return bad
+}
@@ -0,0 +1,52 @@
+package monkchain
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestBadBlocks(t *testing.T) {
+	initDB()
+	emptyDB(1)
+	bman, err := newCanonical(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := bman.bc
+	defer func(max int) { MaxBadBlocks = max }(MaxBadBlocks)
+	MaxBadBlocks = 2
+
+	var bad []*Block
+	for i := uint64(1); i <= 3; i++ {
+		b := bc.GetBlockByNumber(i)
+		b.Coinbase = []byte("someone else's coinbase")
+		bad = append(bad, b)
+	}
+	reason := fmt.Errorf("bad state root")
+	bc.AddBadBlock(bad[0], InvalidBlockError(bad[0], reason), "1.2.3.4:30303")
+	// seen it already
+	bc.AddBadBlock(bad[0], reason, "5.6.7.8:30303")
+
+	got := bc.GetBadBlock(bad[0].Hash())
+	if got == nil {
+		t.Fatal("expected to find the bad block")
+	}
+	if got.Number != 1 || got.Err != reason.Error() || got.Peer != "1.2.3.4:30303" || got.Time == 0 {
+		t.Fatalf("unexpected bad block %v", got)
+	}
+	if !bytes.Equal(got.Block().Hash(), bad[0].Hash()) {
+		t.Fatalf("raw block doesn't decode to the one we rejected")
+	}
+
+	bc.AddBadBlock(bad[1], reason, "")
+	bc.AddBadBlock(bad[2], reason, "")
+	list := bc.BadBlocks()
+	if len(list) != 2 || list[0].Number != 3 || list[1].Number != 2 {
+		t.Fatalf("expected the newest 2 bad blocks, got %v", list)
+	}
+	if bc.GetBadBlock(bad[0].Hash()) != nil {
+		t.Fatal("expected the oldest bad block to be dropped")
+	}
+	setDB(0)
+}
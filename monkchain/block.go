@@ -96,10 +96,17 @@ type Block struct {
 	Extra string
 	// Block Nonce for verification
 	Nonce monkutil.Bytes
+	// Bloom over the addresses and log topics of the block's txs.
+	// Left out of the header when empty
+	LogsBloom []byte
 	// List of transactions and/or contracts
 	transactions []*Transaction
 	receipts     []*Receipt
 	TxSha        []byte
+	// what the block hooks did (not part of the block)
+	hookReceipts []*HookReceipt
+	// what each tx did (not part of the block)
+	txReceipts []*TxReceipt
 
 	// signature for verified miners
 	v    byte
@@ -154,10 +161,14 @@ func (block *Block) Hash() monkutil.Bytes {
 }
 
 func (block *Block) HashNoNonce() []byte {
-	return monkcrypto.Sha3Bin(monkutil.Encode([]interface{}{block.PrevHash,
+	header := []interface{}{block.PrevHash,
 		block.UncleSha, block.Coinbase, block.state.Trie.Root,
 		block.TxSha, block.Difficulty, block.Number, block.MinGasPrice,
-		block.GasLimit, block.GasUsed, block.Time, block.Extra}))
+		block.GasLimit, block.GasUsed, block.Time, block.Extra}
+	if len(block.LogsBloom) != 0 {
+		header = append(header, block.LogsBloom)
+	}
+	return monkcrypto.Sha3Bin(monkutil.Encode(header))
 }
 
 func (block *Block) State() *monkstate.State {
@@ -311,6 +322,7 @@ func (block *Block) RlpValueDecode(decoder *monkutil.Value) {
 	block.Time = int64(header.Get(10).BigInt().Uint64())
 	block.Extra = header.Get(11).Str()
 	block.Nonce = header.Get(12).Bytes()
+	block.LogsBloom = header.Get(13).Bytes()
 
 	// Tx list might be empty if this is an uncle. Uncles only have their
 	// header set.
@@ -359,6 +371,7 @@ func NewUncleBlockFromValue(header *monkutil.Value) *Block {
 	block.Time = int64(header.Get(10).BigInt().Uint64())
 	block.Extra = header.Get(11).Str()
 	block.Nonce = header.Get(12).Bytes()
+	block.LogsBloom = header.Get(13).Bytes()
 
 	return block
 }
@@ -426,7 +439,7 @@ func (block *Block) Header() []interface{} {
 }
 
 func (block *Block) header() []interface{} {
-	return []interface{}{
+	header := []interface{}{
 		// Sha of the previous block
 		block.PrevHash,
 		// Sha of uncles
@@ -454,6 +467,11 @@ func (block *Block) header() []interface{} {
 		// Block's Nonce for validation
 		block.Nonce,
 	}
+	// older blocks and blocks without txs leave it out, so their hashes don't change
+	if len(block.LogsBloom) != 0 {
+		header = append(header, block.LogsBloom)
+	}
+	return header
 }
 
 func (block *Block) String() string {
@@ -472,6 +490,7 @@ func (block *Block) String() string {
 	Time:       %v
 	Extra:      %v
 	Nonce:      %x
+	LogsBloom:  %x
 	NumTx:      %v
 `,
 		block.Hash(),
@@ -489,6 +508,7 @@ func (block *Block) String() string {
 		block.Time,
 		block.Extra,
 		block.Nonce,
+		block.LogsBloom,
 		len(block.transactions),
 	)
 }
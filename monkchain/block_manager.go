@@ -14,6 +14,7 @@ import (
 	"github.com/eris-ltd/thelonious/monkreact"
 	"github.com/eris-ltd/thelonious/monkstate"
 	"github.com/eris-ltd/thelonious/monkutil"
+	"github.com/eris-ltd/thelonious/monkvm"
 	"github.com/eris-ltd/thelonious/monkwire"
 )
 
@@ -59,9 +60,11 @@ type Protocol interface {
 	// deploy genesis block containing protocol rules
 	// returns 20-byte chainId
 	Deploy(block *Block) ([]byte, error)
-	// validate the chain's Id
-	// (typically requires other info, like signatures)
+	// validate the chain's Id against the signed genesis block
+	// (see GenesisCert)
 	ValidateChainID(chainId []byte, genesisBlock *Block) error
+	// proof of work algorithm, fixed at genesis
+	PoW() PoW
 }
 
 // Model defining the consensus
@@ -76,8 +79,73 @@ type Consensus interface {
 	ValidateBlock(block *Block, bc *ChainManager) error
 	// validate a tx
 	ValidateTx(tx *Transaction, state *monkstate.State) error
-	// determine whether or not this checkpoint should be accepted
-	CheckPoint(proposed []byte, bc *ChainManager) bool
+	// determine whether or not this checkpoint certificate should be accepted
+	CheckPoint(cert *CheckpointCert, bc *ChainManager) bool
+}
+
+// Optionally implemented by a Consensus with native system txs
+// (like bonding stake). They are applied in place of running the vm
+type SystemTxs interface {
+	IsSystemTx(tx *Transaction, state *monkstate.State) bool
+	// must leave the state untouched if it returns an error
+	ApplySystemTx(tx *Transaction, state *monkstate.State, block *Block) error
+}
+
+// Optionally implemented by a Consensus that seals blocks with the
+// coinbase signature instead of a proof of work. Rather than searching
+// for a nonce, the miner waits until the seal time and signs
+type Sealer interface {
+	// when coinbase may seal on top of parent, if sealing by signature
+	SealTime(coinbase []byte, parent *Block) (time.Time, bool)
+}
+
+// Optionally implemented by a Protocol with a schedule of rule changes
+// (forks) at block heights. The rules for a block are the ones active
+// in its parent's state
+type Forks interface {
+	// error if this node can't run blocks on top of state
+	CheckFork(state *monkstate.State) error
+	// vm gas prices for blocks on top of state
+	GasSchedule(state *monkstate.State) *monkvm.GasSchedule
+	// must blocks on top of state carry a header bloom
+	HeaderBloom(state *monkstate.State) bool
+	// can the vm run LOG0-LOG4 on top of state
+	Logs(state *monkstate.State) bool
+	// make active any forks starting with the block after this one
+	ActivateForks(state *monkstate.State, block *Block)
+}
+
+// Optionally implemented by a Protocol with system code to run
+// at the start and end of every block, around the txs.
+// Hooks run on the block's state, so their changes are in the state root.
+// They return nil if there is nothing to run.
+// HasHook says if there's any code behind the "pre" or "post" hook
+// (anything that could fail and need reverting)
+type BlockHooks interface {
+	PreBlock(state *monkstate.State, block *Block) *HookReceipt
+	PostBlock(state *monkstate.State, block *Block) *HookReceipt
+	HasHook(name string, state *monkstate.State) bool
+}
+
+// Optionally implemented by a Protocol with rules on block times
+// (like the median time past). The miner stamps new blocks with it
+// instead of the local clock, so a node running behind still makes valid blocks
+type Timestamper interface {
+	NextBlockTime(parent *Block, bc *ChainManager) int64
+}
+
+// Optionally implemented by a Protocol whose admins can halt the chain.
+// While halted, ValidateTx turns away everyone else with a HaltedErr
+type Halter interface {
+	Halted(state *monkstate.State) bool
+}
+
+// Optionally implemented by a Consensus whose blocks are final once
+// a quorum signs a commit for them. Commits travel apart from blocks,
+// so nodes that don't vote can check and finalize them too
+type Committer interface {
+	// check a commit and return the hash and number of the block it commits
+	VerifyCommit(commit *monkutil.Value, bc *ChainManager) ([]byte, uint64, error)
 }
 
 // Private global genDoug variable for checking permissions on arbitrary
@@ -121,7 +189,7 @@ type BlockManager struct {
 func NewBlockManager(thelonious NodeManager) *BlockManager {
 	sm := &BlockManager{
 		mem: make(map[string]*big.Int),
-		Pow: &EasyPow{},
+		Pow: thelonious.Protocol().PoW(),
 		th:  thelonious,
 		bc:  thelonious.ChainManager(),
 	}
@@ -169,6 +237,7 @@ func (self *BlockManager) ProcessTransactions(coinbase *monkstate.StateObject, s
 		totalUsedGas       = big.NewInt(0)
 		err                error
 	)
+	block.txReceipts = nil
 
 done:
 	for i, tx := range txs {
@@ -177,7 +246,10 @@ done:
 		cb := state.GetStateObject(coinbase.Address())
 		// TODO: deal with this
 		st := NewStateTransitionEris(cb, tx, state, block, self.bc.Genesis()) // ERIS
+		state.EmptyLogs()
+		msgCount := len(state.Manifest().Messages)
 		err = st.TransitionState()
+		txErr := err
 		if err != nil {
 			statelogger.Infoln(err)
 			switch {
@@ -189,6 +261,14 @@ done:
 				self.th.Reactor().Post("newTx:post:fail", &TxFail{tx, err})
 				err = nil // ignore error
 				continue
+			case IsTxWorkErr(err):
+				self.th.Reactor().Post("newTx:post:fail", &TxFail{tx, err})
+				err = nil // ignore error
+				continue
+			case IsHaltedErr(err):
+				self.th.Reactor().Post("newTx:post:fail", &TxFail{tx, err})
+				err = nil // ignore error
+				continue
 			case IsGasLimitErr(err):
 				unhandled = txs[i:]
 				for _, t := range unhandled {
@@ -234,6 +314,9 @@ done:
 
 		receipts = append(receipts, receipt)
 		handled = append(handled, tx)
+		txReceipt := newTxReceipt(tx, st, txErr, txGas, accumelative, state.Logs())
+		txReceipt.addresses = messageAddresses(state.Manifest().Messages[msgCount:])
+		block.txReceipts = append(block.txReceipts, txReceipt)
 
 		if monkutil.Config.Diff && monkutil.Config.DiffType == "all" {
 			state.CreateOutputForDiff()
@@ -286,6 +369,14 @@ func (sm *BlockManager) ProcessWithParent(block, parent *Block) (td *big.Int, er
 		return
 	}
 
+	// blocks from before the bloom fork may leave it out,
+	// but a bloom that's there has to be right
+	bloom := CreateHeaderBloom(block.txReceipts)
+	if bytes.Compare(bloom, block.LogsBloom) != 0 && (len(block.LogsBloom) != 0 || headerBloomRequired(parent.State())) {
+		err = fmt.Errorf("Error validating header bloom. Received %x, got %x", block.LogsBloom, bloom)
+		return
+	}
+
 	// Block validation
 	if err = sm.ValidateBlock(block); err != nil {
 		statelogger.Errorln("Error validating block:", err)
@@ -313,8 +404,15 @@ func (sm *BlockManager) ProcessWithParent(block, parent *Block) (td *big.Int, er
 		//if dontReact == false {
 		sm.th.Reactor().Post("newBlock", block)
 		state.Manifest().Reset()
+		sm.postHalt(parent.State(), state, block)
 		//}
 
+		sm.writeHookReceipts(block)
+		sm.writeTxReceipts(block)
+		if logs := receiptLogs(block.txReceipts); len(logs) > 0 {
+			sm.th.Reactor().Post("logs", logs)
+		}
+
 		statelogger.Infof("Processed block #%d (%x...)\n", block.Number, block.Hash()[0:4])
 		sm.transState = nil
 		sm.state = state.Copy()
@@ -340,12 +438,16 @@ func (sm *BlockManager) ApplyDiff(state *monkstate.State, parent, block *Block)
 	coinbase := state.GetOrNewStateObject(block.Coinbase)
 	coinbase.SetGasPool(block.CalcGasLimit(parent))
 
+	sm.PreBlock(state, block)
+
 	// Process the transactions on to current block
 	receipts, _, _, err = sm.ProcessTransactions(coinbase, state, block, parent, block.Transactions())
 	if err != nil {
 		return nil, err
 	}
 
+	sm.PostBlock(state, block)
+
 	return receipts, nil
 }
 
@@ -376,8 +478,28 @@ func (sm *BlockManager) ValidateBlock(block *Block) error {
 	return sm.bc.protocol.ValidateBlock(block, sm.bc)
 }
 
+// Announce when a block halts or resumes the chain
+func (sm *BlockManager) postHalt(parent, state *monkstate.State, block *Block) {
+	halter, ok := sm.bc.protocol.(Halter)
+	if !ok {
+		return
+	}
+	was, is := halter.Halted(parent), halter.Halted(state)
+	switch {
+	case !was && is:
+		sm.th.Reactor().Post("chain:halt", block)
+	case was && !is:
+		sm.th.Reactor().Post("chain:resume", block)
+	}
+}
+
 func (sm *BlockManager) AccumelateRewards(state *monkstate.State, block, parent *Block) error {
-	reward := new(big.Int).Set(BlockReward)
+	policy := sm.RewardPolicy(state)
+	if !policy.Uncles && len(block.Uncles) > 0 {
+		return UncleError("Uncles not allowed")
+	}
+	blockReward := policy.Reward(block.Number)
+	reward := new(big.Int).Set(blockReward)
 
 	knownUncles := monkutil.Set(parent.Uncles)
 	nonces := monkutil.NewSet(block.Nonce)
@@ -403,12 +525,16 @@ func (sm *BlockManager) AccumelateRewards(state *monkstate.State, block, parent
 		nonces.Insert(uncle.Nonce)
 
 		r := new(big.Int)
-		r.Mul(BlockReward, big.NewInt(15)).Div(r, big.NewInt(16))
+		r.Mul(blockReward, big.NewInt(15)).Div(r, big.NewInt(16))
 
 		uncleAccount := state.GetAccount(uncle.Coinbase)
 		uncleAccount.AddAmount(r)
 
-		reward.Add(reward, new(big.Int).Div(BlockReward, big.NewInt(32)))
+		reward.Add(reward, new(big.Int).Div(blockReward, big.NewInt(32)))
+	}
+	reward, treasury := policy.Split(reward)
+	if treasury.Sign() > 0 {
+		state.GetAccount(policy.Treasury).AddAmount(treasury)
 	}
 	// Get the account associated with the coinbase
 	account := state.GetAccount(block.Coinbase)
@@ -418,6 +544,16 @@ func (sm *BlockManager) AccumelateRewards(state *monkstate.State, block, parent
 	return nil
 }
 
+// The protocol's reward policy, or ethereum's
+func (sm *BlockManager) RewardPolicy(state *monkstate.State) *RewardPolicy {
+	if rewards, ok := sm.th.Protocol().(Rewards); ok {
+		if policy := rewards.RewardPolicy(state); policy != nil {
+			return policy
+		}
+	}
+	return DefaultRewardPolicy
+}
+
 func (sm *BlockManager) Stop() {
 	sm.bc.Stop()
 }
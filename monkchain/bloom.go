@@ -1,5 +1,10 @@
 package monkchain
 
+import (
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+)
+
 type BloomFilter struct {
 	bin []byte
 }
@@ -45,3 +50,62 @@ func (self *BloomFilter) Search(addr []byte) bool {
 func (self *BloomFilter) Bin() []byte {
 	return self.bin
 }
+
+// Size in bytes of the header bloom (2048 bits)
+const HeaderBloomSize = 256
+
+// Set the three bits for data, picked from its sha3
+func headerBloomAdd(bin, data []byte) {
+	h := monkcrypto.Sha3Bin(data)
+	for i := 0; i < 6; i += 2 {
+		bit := (uint(h[i])<<8 | uint(h[i+1])) & (HeaderBloomSize*8 - 1)
+		bin[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Whether data might be in the header bloom.
+// An empty bloom has nothing in it
+func HeaderBloomLookup(bin, data []byte) bool {
+	if len(bin) != HeaderBloomSize {
+		return false
+	}
+	h := monkcrypto.Sha3Bin(data)
+	for i := 0; i < 6; i += 2 {
+		bit := (uint(h[i])<<8 | uint(h[i+1])) & (HeaderBloomSize*8 - 1)
+		if bin[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// The bloom that goes in a block's header: every address a tx's messages
+// went to or from, and the address and topics of every log.
+// Nil if the block has no txs
+func CreateHeaderBloom(receipts []*TxReceipt) []byte {
+	if len(receipts) == 0 {
+		return nil
+	}
+	bin := make([]byte, HeaderBloomSize)
+	for _, r := range receipts {
+		for _, addr := range r.addresses {
+			headerBloomAdd(bin, addr)
+		}
+		for _, log := range r.Logs {
+			headerBloomAdd(bin, log.Address)
+			for _, topic := range log.Topics {
+				headerBloomAdd(bin, topic)
+			}
+		}
+	}
+	return bin
+}
+
+// Must blocks on top of state carry a header bloom.
+// Chains with a fork schedule turn it on with a fork
+func headerBloomRequired(state *monkstate.State) bool {
+	if forks, ok := genDoug.(Forks); ok {
+		return forks.HeaderBloom(state)
+	}
+	return true
+}
@@ -68,6 +68,26 @@ type ChainManager struct {
 	latestCheckPointNumber uint64
 	waitingForCheckPoint   bool
 
+	// The certificate for our latest checkpoint (if it came with one)
+	// and partial certificates still gathering signatures
+	latestCheckPointCert *CheckpointCert
+	pendingCerts         map[string]*CheckpointCert
+	certMut              sync.Mutex
+
+	// Our latest final block. Final blocks were committed
+	// by a quorum of validators and can never be reverted
+	latestFinalHash   []byte
+	latestFinalNumber uint64
+
+	// signed headers we've processed, by number and coinbase,
+	// so we notice a miner signing two blocks at the same height.
+	// evidence we detected but haven't reported yet,
+	// and the hashes of evidence we've already seen
+	signedHeaders    map[string]*Block
+	detectedEvidence []*Evidence
+	seenEvidence     map[string]uint64
+	evidenceMut      sync.Mutex
+
 	// sync access to current state (block, hash, num)
 	mut sync.Mutex
 	// sync access to TestChain/InsertChain
@@ -78,12 +98,17 @@ func NewChainManager(protocol Protocol) *ChainManager {
 	bc := &ChainManager{}
 	bc.genesisBlock = NewBlockFromBytes(monkutil.Encode(Genesis))
 	bc.workingTree = make(map[string]*link)
+	bc.pendingCerts = make(map[string]*CheckpointCert)
+	bc.signedHeaders = make(map[string]*Block)
+	bc.seenEvidence = make(map[string]uint64)
 	bc.protocol = protocol
 
 	// set last block we know of or deploy genesis
 	bc.setLastBlock()
 	// load the latest checkpoint
 	bc.loadCheckpoint()
+	// load the latest final block
+	bc.loadFinal()
 
 	return bc
 }
@@ -94,6 +119,9 @@ func NewChainManager(protocol Protocol) *ChainManager {
 // satisfiability contract).
 // Proposals should come after NewChainManager and come from a separate
 // file or trusted process.
+// After the first boot a bare hash carries no signatures, so models
+// that need a certificate (std, vm) will refuse it: the checkpoint has to
+// come in through ReceiveCheckpointCert, signed by checkpoint holders.
 // TODO: allow proposals to come out of the vm
 func (bc *ChainManager) CheckPoint(proposed []byte) {
 	if proposed == nil {
@@ -108,10 +136,118 @@ func (bc *ChainManager) CheckPoint(proposed []byte) {
 		return
 	}
 
-	// check if the checkpoint satisfies consensus rules
-	if bc.protocol.CheckPoint(proposed, bc) {
+	// check if the checkpoint satisfies consensus rules.
+	// a bare hash comes without signatures
+	cert := NewCheckpointCert(proposed, 0)
+	if b := bc.GetBlock(proposed); b != nil {
+		cert.Number = b.Number.Uint64()
+	}
+	if bc.protocol.CheckPoint(cert, bc) {
 		bc.updateCheckpoint(proposed)
+	} else {
+		chainlogger.Infof("Checkpoint %x refused. It needs a signed certificate\n", proposed)
+	}
+}
+
+// Max number of blocks we gather checkpoint signatures for at once
+var MaxPendingCerts = 64
+
+// Receive a (possibly partial) checkpoint certificate from a peer or signer.
+// Its signatures are merged with any others we have for the same block,
+// and once the protocol accepts them the block becomes our checkpoint.
+// Returns the merged certificate if it has anything new (so it should be
+// gossiped on), and whether the checkpoint was updated
+func (bc *ChainManager) ReceiveCheckpointCert(cert *CheckpointCert) (*CheckpointCert, bool) {
+	if cert == nil || len(cert.Hash) == 0 || len(cert.Sigs) == 0 {
+		return nil, false
+	}
+	// old news
+	if cert.Number <= bc.LatestCheckPointNumber() {
+		return nil, false
+	}
+	// only for blocks we have on canonical. A cert for anything
+	// else would leave us waiting on a block that may never come
+	if !bytes.Equal(bc.CanonicalHash(cert.Number), cert.Hash) {
+		return nil, false
+	}
+
+	// only hold on to signatures that count
+	state := bc.CurrentBlock().State()
+	cert = cert.Filter(func(signer []byte) bool {
+		return bc.protocol.ValidatePerm(signer, "checkpoint", state) == nil
+	})
+	if len(cert.Sigs) == 0 {
+		return nil, false
+	}
+
+	bc.certMut.Lock()
+	defer bc.certMut.Unlock()
+
+	key := string(cert.Hash)
+	pending, ok := bc.pendingCerts[key]
+	if !ok {
+		if len(bc.pendingCerts) >= MaxPendingCerts && !bc.evictPendingCert(cert.Number) {
+			chainlogger.Debugf("Too many pending checkpoints. Dropping %x\n", cert.Hash)
+			return nil, false
+		}
+		pending = NewCheckpointCert(cert.Hash, cert.Number)
+		bc.pendingCerts[key] = pending
+	}
+	if !pending.Merge(cert) {
+		return nil, false
+	}
+
+	if !bc.protocol.CheckPoint(pending, bc) {
+		return pending, false
+	}
+
+	// certified! forget anything older
+	for k, c := range bc.pendingCerts {
+		if c.Number <= pending.Number {
+			delete(bc.pendingCerts, k)
+		}
+	}
+	bc.mut.Lock()
+	bc.latestCheckPointCert = pending
+	bc.mut.Unlock()
+	monkutil.Config.Db.Put([]byte("LatestCheckPointCert"), monkutil.Encode(pending.RlpData()))
+	chainlogger.Infof("Checkpoint certificate for block (#%d) %x has %d signatures\n", pending.Number, pending.Hash, len(pending.Sigs))
+
+	bc.updateCheckpoint(pending.Hash)
+	return pending, true
+}
+
+// Make room for a certificate for block number. Stale entries
+// (at or below our checkpoint, or reorged off canonical) go first,
+// then the lowest one, unless number is lower still. Entries are never
+// above our head when they come in, so the queue can't be pinned by
+// far off blocks. Expects certMut to be held
+func (bc *ChainManager) evictPendingCert(number uint64) bool {
+	latest := bc.LatestCheckPointNumber()
+	var lowest string
+	for k, c := range bc.pendingCerts {
+		if c.Number <= latest || !bytes.Equal(bc.CanonicalHash(c.Number), c.Hash) {
+			delete(bc.pendingCerts, k)
+			continue
+		}
+		if lowest == "" || c.Number < bc.pendingCerts[lowest].Number {
+			lowest = k
+		}
 	}
+	if len(bc.pendingCerts) < MaxPendingCerts {
+		return true
+	}
+	if bc.pendingCerts[lowest].Number >= number {
+		return false
+	}
+	delete(bc.pendingCerts, lowest)
+	return true
+}
+
+func (bc *ChainManager) LatestCheckPointCert() *CheckpointCert {
+	bc.mut.Lock()
+	defer bc.mut.Unlock()
+	return bc.latestCheckPointCert
 }
 
 func (bc *ChainManager) IsCheckpoint(hash []byte) bool {
@@ -171,6 +307,98 @@ func (bc *ChainManager) loadCheckpoint() {
 	} else {
 		bc.updateCheckpoint(bc.genesisBlock.Hash())
 	}
+
+	data, _ = monkutil.Config.Db.Get([]byte("LatestCheckPointCert"))
+	if len(data) != 0 {
+		bc.latestCheckPointCert = NewCheckpointCertFromValue(monkutil.NewValueFromBytes(data))
+	}
+}
+
+// Mark a canonical block as final. Forks branching
+// below a final block are refused by TestChain and reOrg.
+// Finality only moves forward: finalizing a different block
+// at or below the current final height is an error
+func (bc *ChainManager) Finalize(hash []byte) error {
+	block := bc.GetBlockCanonical(hash)
+	if block == nil {
+		return fmt.Errorf("Can not finalize unknown block %x", hash)
+	}
+	number := block.Number.Uint64()
+
+	// blocks at or below the final block are already final
+	// if they are on our chain
+	if bc.LatestFinalHash() != nil && number <= bc.LatestFinalNumber() {
+		if b := bc.GetBlockByNumber(number); b != nil && bytes.Compare(b.Hash(), hash) == 0 {
+			return nil
+		}
+		return FinalityError(hash, number, bc.LatestFinalNumber())
+	}
+
+	bc.mut.Lock()
+	defer bc.mut.Unlock()
+	bc.latestFinalHash = hash
+	bc.latestFinalNumber = number
+	monkutil.Config.Db.Put([]byte("LatestFinal"), hash)
+	chainlogger.Infof("Finalized block: (#%d) %x\n", number, hash)
+	return nil
+}
+
+func (bc *ChainManager) IsFinal(hash []byte) bool {
+	block := bc.GetBlockCanonical(hash)
+	if block == nil {
+		return false
+	}
+	return block.Number.Uint64() <= bc.LatestFinalNumber()
+}
+
+// load final block from db or set to genesis
+func (bc *ChainManager) loadFinal() {
+	data, _ := monkutil.Config.Db.Get([]byte("LatestFinal"))
+	if len(data) == 0 {
+		data = bc.genesisBlock.Hash()
+	}
+	if err := bc.Finalize(data); err != nil {
+		chainlogger.Infoln("Failed to load final block:", err)
+	}
+}
+
+// Forks must branch at or above the final block
+func (bc *ChainManager) checkFinality(branchParent *Block) error {
+	number := branchParent.Number.Uint64()
+	if final := bc.LatestFinalNumber(); number < final {
+		return FinalityError(branchParent.Hash(), number, final)
+	}
+	return nil
+}
+
+// Forks must branch at or above the latest checkpoint,
+// unless they lead to the checkpoint (and we're not on it)
+func (bc *ChainManager) checkCheckpoint(branchParent *Block, chain *BlockChain) error {
+	number := branchParent.Number.Uint64()
+	checkpoint := bc.LatestCheckPointNumber()
+	if number >= checkpoint || bc.forkContains(chain, bc.LatestCheckPointHash()) {
+		return nil
+	}
+	return CheckpointError(branchParent.Hash(), number, checkpoint)
+}
+
+// Is hash in the chain, or in the working tree behind it
+func (bc *ChainManager) forkContains(chain *BlockChain, hash []byte) bool {
+	for e := chain.Front(); e != nil; e = e.Next() {
+		if bytes.Compare(e.Value.(*link).block.Hash(), hash) == 0 {
+			return true
+		}
+	}
+	front := chain.Front()
+	if front == nil {
+		return false
+	}
+	for l := bc.workingTree[string(front.Value.(*link).block.PrevHash)]; l != nil; l = l.parent {
+		if bytes.Compare(l.block.Hash(), hash) == 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func (bc *ChainManager) SetProcessor(proc BlockProcessor) {
@@ -204,6 +432,9 @@ func (bc *ChainManager) NewBlock(coinbase []byte) *Block {
 
 	parent := bc.CurrentBlock()
 	if parent != nil {
+		if ts, ok := genDoug.(Timestamper); ok {
+			block.Time = ts.NextBlockTime(parent, bc)
+		}
 		block.Difficulty = genDoug.Difficulty(block, parent)
 		block.Number = new(big.Int).Add(parent.Number, monkutil.Big1)
 		block.GasLimit = monkutil.BigPow(10, 50) //block.CalcGasLimit(bc.CurrentBlock)
@@ -291,6 +522,9 @@ func (bc *ChainManager) setLastBlock() {
 		if err != nil {
 			log.Fatal("Genesis deploy failed:", err)
 		}
+		if err := bc.protocol.ValidateChainID(chainId, bc.genesisBlock); err != nil {
+			log.Fatal(err)
+		}
 		monkutil.Config.Db.Put([]byte("GenesisBlock"), bc.genesisBlock.RlpEncode())
 		monkutil.Config.Db.Put([]byte("ChainID"), chainId[:])
 		bc.chainID = chainId
@@ -309,6 +543,9 @@ func (bc *ChainManager) setLastBlock() {
 	// set the genDoug model (global var) for determining chain permissions
 	genDoug = bc.protocol
 
+	// chains from before the indexes need them built
+	bc.reindex()
+
 	//bc.SetTotalDifficulty(monkutil.Big("0"))
 
 	// Set the last know difficulty (might be 0x0 as initial value, Genesis)
@@ -343,8 +580,13 @@ func (bc *ChainManager) add(block *Block) {
 	bc.currentBlockHash = block.Hash()
 
 	encodedBlock := block.RlpEncode()
-	monkutil.Config.Db.Put(block.Hash(), encodedBlock)
-	monkutil.Config.Db.Put([]byte("LastBlock"), encodedBlock)
+	batch := monkutil.Config.Db.NewBatch()
+	batch.Put(block.Hash(), encodedBlock)
+	bc.indexBlock(batch, block)
+	batch.Put([]byte("LastBlock"), encodedBlock)
+	if err := batch.Write(); err != nil {
+		chainlogger.Errorln("Failed to write block:", err)
+	}
 }
 
 func (bc *ChainManager) ChainID() []byte {
@@ -353,6 +595,11 @@ func (bc *ChainManager) ChainID() []byte {
 	return bc.chainID
 }
 
+// Certificate authenticating our chainId
+func (bc *ChainManager) GenesisCert() *GenesisCert {
+	return NewGenesisCert(bc.genesisBlock)
+}
+
 func (bc *ChainManager) CurrentBlock() *Block {
 	bc.mut.Lock()
 	defer bc.mut.Unlock()
@@ -395,6 +642,18 @@ func (bc *ChainManager) LatestCheckPointNumber() uint64 {
 	return bc.latestCheckPointNumber
 }
 
+func (bc *ChainManager) LatestFinalHash() []byte {
+	bc.mut.Lock()
+	defer bc.mut.Unlock()
+	return bc.latestFinalHash
+}
+
+func (bc *ChainManager) LatestFinalNumber() uint64 {
+	bc.mut.Lock()
+	defer bc.mut.Unlock()
+	return bc.latestFinalNumber
+}
+
 func (self *ChainManager) CalcTotalDiff(block *Block) (*big.Int, error) {
 	parent := self.GetBlock(block.PrevHash)
 	if parent == nil {
@@ -454,6 +713,11 @@ func (self *ChainManager) GetBlockCanonical(hash []byte) *Block {
 }
 
 func (self *ChainManager) GetBlockByNumber(num uint64) *Block {
+	if hash := self.CanonicalHash(num); hash != nil {
+		return self.GetBlockCanonical(hash)
+	}
+
+	// not indexed. walk back from the head
 	block := self.CurrentBlock()
 	for ; block != nil; block = self.GetBlock(block.PrevHash) {
 		if block.Number.Uint64() == num {
@@ -577,6 +841,14 @@ func (self *ChainManager) TestChain(chain *BlockChain) (td *big.Int, err error)
 	parent, fork = self.detectFork(chain)
 	if fork {
 		fmt.Println("Fork!")
+		// never consider forks that would revert a final block
+		// or rewrite history below the checkpoint
+		if err = self.checkFinality(parent); err != nil {
+			return
+		}
+		if err = self.checkCheckpoint(parent, chain); err != nil {
+			return
+		}
 		if _, ok := self.workingTree[string(parent.Hash())]; !ok {
 			chainlogger.Infof("New fork detected off parent %x at height %d. Head %x at %d", parent.Hash(), parent.Number, self.CurrentBlockHash(), self.CurrentBlockNumber())
 		} else {
@@ -597,17 +869,32 @@ func (self *ChainManager) TestChain(chain *BlockChain) (td *big.Int, err error)
 			return
 		}
 
+		// refuse to run past a fork we don't support
+		if forks, ok := self.protocol.(Forks); ok {
+			if ferr := forks.CheckFork(parent.State()); ferr != nil {
+				err = UnsupportedForkError(block.Number.Uint64(), ferr)
+				chainlogger.Warnln(err)
+				return
+			}
+		}
+
 		//var messages state.Messages
 		td, err = self.processor.ProcessWithParent(block, parent)
+		if IsFutureBlockErr(err) {
+			// not invalid, just early
+			chainlogger.Infoln(err)
+			return
+		}
 		if err != nil {
 			chainlogger.Infoln(err)
 			chainlogger.Debugf("Block #%v failed (%x...)\n", block.Number, block.Hash()[0:4])
 			chainlogger.Debugln(block)
-			err = fmt.Errorf("incoming chain failed %v\n", err)
+			err = InvalidBlockError(block, err)
 			return
 		} else {
 			chainlogger.Debugf("Block #%v passed (%x...)\n", block.Number, block.Hash()[0:4])
 		}
+		self.watchEquivocation(block)
 
 		l.td = td
 		//l.messages = messages
@@ -638,6 +925,13 @@ func (self *ChainManager) insertChain(chain *BlockChain) {
 		self.SetTotalDifficulty(link.td)
 		self.add(link.block)
 
+		// its commit may have beaten it here
+		if hash := link.block.Hash(); self.HasCommit(hash) {
+			if err := self.Finalize(hash); err != nil {
+				chainlogger.Infoln(err)
+			}
+		}
+
 		// XXX: Post. Do we do this here? Prob better for caller ...
 		//self.Thelonious.Reactor().Post(NewBlockEvent{link.block})
 		//self.Thelonious.Reactor().Post(link.messages)
@@ -705,6 +999,16 @@ func (self *ChainManager) reOrg(chain *BlockChain) {
 	ancestorHash := bchain.Front().Value.(*link).block.PrevHash
 	ancestor := self.GetBlockCanonical(ancestorHash)
 
+	// TestChain should have caught this, but we're paranoid
+	if err := self.checkFinality(ancestor); err != nil {
+		chainlogger.Infoln("Reorg refused:", err)
+		return
+	}
+	if err := self.checkCheckpoint(ancestor, bchain); err != nil {
+		chainlogger.Infoln("Reorg refused:", err)
+		return
+	}
+
 	oldHeadHash := self.CurrentBlockHash()
 	oldHead := self.GetBlockCanonical(oldHeadHash)
 
@@ -714,6 +1018,7 @@ func (self *ChainManager) reOrg(chain *BlockChain) {
 	self.mut.Lock()
 	self.currentBlock = ancestor
 	self.currentBlockHash = ancestorHash
+	self.currentBlockNumber = ancestor.Number.Uint64()
 	self.mut.Unlock()
 
 	// process the new chain on top
@@ -726,18 +1031,32 @@ func (self *ChainManager) reOrg(chain *BlockChain) {
 		self.mut.Lock()
 		self.currentBlock = oldHead
 		self.currentBlockHash = oldHeadHash
+		self.currentBlockNumber = oldHead.Number.Uint64()
 		self.mut.Unlock()
 		return
 	}
+	// the old canonical is not canonical anymore. drop its index entries
+	// before the new chain goes in, so nothing is left pointing at it
+	// if we stop halfway. a head without entries is reindexed on boot
+	oldChain := &BlockChain{list.New()}
+	batch := monkutil.Config.Db.NewBatch()
+	self.mut.Lock()
+	for b := oldHead; bytes.Compare(b.Hash(), ancestorHash) != 0; b = self.GetBlock(b.PrevHash) {
+		oldChain.PushFront(&link{b, nil, nil, nil})
+		self.unindexBlock(batch, b)
+		// TODO: remove from database
+	}
+	err = batch.Write()
+	self.mut.Unlock()
+	if err != nil {
+		chainlogger.Errorln("Failed to drop the old canonical's index:", err)
+	}
+
 	chainlogger.Infof("Inserting chain")
 	self.InsertChain(bchain)
 
 	// move old canonical into workingTree chain
-	bchain = &BlockChain{list.New()}
-	for b := oldHead; bytes.Compare(b.Hash(), ancestorHash) != 0; b = self.GetBlock(b.PrevHash) {
-		bchain.PushFront(&link{b, nil, nil, nil})
-		// TODO: remove from database
-	}
+	bchain = oldChain
 
 	// again, we have already processed, since its fucking canonical
 	// but this is easy for now, gives an extra check
@@ -67,18 +67,21 @@ func (e *fakeEth) Protocol() Protocol                                     { retu
 type fakeDoug struct{}
 
 func (d *fakeDoug) Doug() []byte { return nil }
-func (d *fakeDoug) Deploy(block *Block) []byte {
-	return nil
+func (d *fakeDoug) Deploy(block *Block) ([]byte, error) {
+	return nil, nil
 }
 func (d *fakeDoug) ValidateChainID(chainId []byte, genBlock *Block) error {
 	return nil
 }
+func (d *fakeDoug) PoW() PoW {
+	return fakePow{}
+}
 func (d *fakeDoug) Participate(coinbase []byte, parent *Block) bool                     { return false }
 func (d *fakeDoug) Difficulty(block, parent *Block) *big.Int                            { return nil }
 func (d *fakeDoug) ValidatePerm(addr []byte, role string, state *monkstate.State) error { return nil }
 func (d *fakeDoug) ValidateBlock(block *Block, bc *ChainManager) error                  { return nil }
 func (d *fakeDoug) ValidateTx(tx *Transaction, state *monkstate.State) error            { return nil }
-func (d *fakeDoug) CheckPoint(proposed []byte, bc *ChainManager) bool                   { return false }
+func (d *fakeDoug) CheckPoint(cert *CheckpointCert, bc *ChainManager) bool              { return false }
 
 var (
 	FakeEth  = &fakeEth{}
@@ -107,7 +110,9 @@ func makeBlock(bman *BlockManager, parent *Block, i int) *Block {
 	block := newBlockFromParent(addr, parent)
 	cbase := block.State().GetOrNewStateObject(addr)
 	cbase.SetGasPool(block.CalcGasLimit(parent))
+	bman.PreBlock(block.State(), block)
 	receipts, txs, _, _ := bman.ProcessTransactions(cbase, block.State(), block, block, Transactions{})
+	bman.PostBlock(block.State(), block)
 	//block.SetTransactions(txs)
 	block.SetTxHash(receipts)
 	block.SetReceipts(receipts, txs)
@@ -168,6 +173,8 @@ func newChainManager(block *Block, protocol Protocol) *ChainManager {
 	bc.protocol = protocol
 	bc.genesisBlock = NewBlockFromBytes(monkutil.Encode(Genesis))
 	bc.workingTree = make(map[string]*link)
+	bc.signedHeaders = make(map[string]*Block)
+	bc.seenEvidence = make(map[string]uint64)
 	genDoug = bc.protocol
 	if block == nil {
 		bc.protocol.Deploy(bc.genesisBlock)
@@ -0,0 +1,117 @@
+package monkchain
+
+import (
+	"bytes"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkutil"
+	"github.com/obscuren/secp256k1-go"
+)
+
+// A checkpoint certificate is a block hash and number signed
+// by accounts holding the "checkpoint" permission.
+// Certificates are gossiped between peers, merging signatures
+// as they go, until the Protocol finds enough of them to accept
+type CheckpointCert struct {
+	Hash   []byte
+	Number uint64
+
+	// 65 byte signatures (v is last, offset by 27)
+	Sigs [][]byte
+}
+
+func NewCheckpointCert(hash []byte, number uint64) *CheckpointCert {
+	return &CheckpointCert{Hash: hash, Number: number}
+}
+
+func NewCheckpointCertFromValue(val *monkutil.Value) *CheckpointCert {
+	cert := &CheckpointCert{}
+	cert.Hash = val.Get(0).Bytes()
+	cert.Number = val.Get(1).Uint()
+	sigs := val.Get(2)
+	for i := 0; i < sigs.Len(); i++ {
+		cert.Sigs = append(cert.Sigs, sigs.Get(i).Bytes())
+	}
+	return cert
+}
+
+// What the signers sign
+func (cert *CheckpointCert) SigHash() []byte {
+	return monkcrypto.Sha3Bin(monkutil.Encode([]interface{}{cert.Hash, cert.Number}))
+}
+
+func (cert *CheckpointCert) Sign(privk []byte) {
+	sig, _ := secp256k1.Sign(cert.SigHash(), privk)
+	sig[64] += 27
+	cert.Sigs = append(cert.Sigs, sig)
+}
+
+func sigSigner(hash, sig []byte) []byte {
+	if len(sig) != 65 {
+		return nil
+	}
+	s := append([]byte{}, sig[:64]...)
+	s = append(s, sig[64]-27)
+
+	pubkey, _ := secp256k1.RecoverPubkey(hash, s)
+	if len(pubkey) == 0 || pubkey[0] != 4 {
+		return nil
+	}
+	return monkcrypto.Sha3Bin(pubkey[1:])[12:]
+}
+
+// Addresses of the distinct signers. Bad signatures are skipped
+func (cert *CheckpointCert) Signers() [][]byte {
+	hash := cert.SigHash()
+	signers := [][]byte{}
+	seen := make(map[string]bool)
+	for _, sig := range cert.Sigs {
+		signer := sigSigner(hash, sig)
+		if signer == nil || seen[string(signer)] {
+			continue
+		}
+		seen[string(signer)] = true
+		signers = append(signers, signer)
+	}
+	return signers
+}
+
+// A copy of the certificate with only the signatures
+// whose signers pass keep
+func (cert *CheckpointCert) Filter(keep func(signer []byte) bool) *CheckpointCert {
+	hash := cert.SigHash()
+	filtered := NewCheckpointCert(cert.Hash, cert.Number)
+	for _, sig := range cert.Sigs {
+		if signer := sigSigner(hash, sig); signer != nil && keep(signer) {
+			filtered.Sigs = append(filtered.Sigs, sig)
+		}
+	}
+	return filtered
+}
+
+// Add any signatures from other that we don't have.
+// Returns true if we learned something
+func (cert *CheckpointCert) Merge(other *CheckpointCert) bool {
+	if !bytes.Equal(cert.Hash, other.Hash) || cert.Number != other.Number {
+		return false
+	}
+	added := false
+	for _, sig := range other.Sigs {
+		have := false
+		for _, s := range cert.Sigs {
+			if bytes.Equal(s, sig) {
+				have = true
+				break
+			}
+		}
+		if !have {
+			cert.Sigs = append(cert.Sigs, sig)
+			added = true
+		}
+	}
+	return added
+}
+
+func (cert *CheckpointCert) RlpData() []interface{} {
+	return []interface{}{cert.Hash, cert.Number, monkutil.ByteSliceToInterface(cert.Sigs)}
+}
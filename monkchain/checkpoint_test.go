@@ -0,0 +1,200 @@
+package monkchain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+// Accepts certificates with two signers
+type certDoug struct {
+	fakeDoug
+}
+
+func (d *certDoug) CheckPoint(cert *CheckpointCert, bc *ChainManager) bool {
+	return len(cert.Signers()) >= 2
+}
+
+// and keeps the checkpoint permission from one address
+type banDoug struct {
+	certDoug
+	banned []byte
+}
+
+func (d *banDoug) ValidatePerm(addr []byte, role string, state *monkstate.State) error {
+	if bytes.Equal(addr, d.banned) {
+		return InvalidPermError(addr, role)
+	}
+	return nil
+}
+
+func TestCheckpointCertSigners(t *testing.T) {
+	keys := []*monkcrypto.KeyPair{monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair()}
+	cert := NewCheckpointCert([]byte("somehash"), 7)
+	cert.Sign(keys[0].PrivateKey)
+	cert.Sign(keys[1].PrivateKey)
+	// signing twice doesn't count twice
+	cert.Sign(keys[1].PrivateKey)
+
+	// over the wire and back
+	cert = NewCheckpointCertFromValue(monkutil.NewValueFromBytes(monkutil.Encode(cert.RlpData())))
+	if cert.Number != 7 || !bytes.Equal(cert.Hash, []byte("somehash")) {
+		t.Fatalf("bad decode: %x #%d", cert.Hash, cert.Number)
+	}
+	signers := cert.Signers()
+	if len(signers) != 2 {
+		t.Fatalf("expected 2 signers, got %d", len(signers))
+	}
+	for i, signer := range signers {
+		if !bytes.Equal(signer, keys[i].Address()) {
+			t.Fatalf("signer %d is %x, expected %x", i, signer, keys[i].Address())
+		}
+	}
+
+	// a signature for a different number is no good
+	cert.Number = 8
+	for _, signer := range cert.Signers() {
+		for _, k := range keys {
+			if bytes.Equal(signer, k.Address()) {
+				t.Fatal("signature survived changing the number")
+			}
+		}
+	}
+}
+
+func TestCheckpointRefusesFork(t *testing.T) {
+	initDB()
+	bman, err := newCanonical(10)
+	if err != nil {
+		t.Fatal("Could not make new canonical chain:", err)
+	}
+	bc := bman.bc
+	bc.protocol = &certDoug{}
+	bc.pendingCerts = make(map[string]*CheckpointCert)
+
+	checkpoint := bc.GetBlockByNumber(5)
+	keys := []*monkcrypto.KeyPair{monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair()}
+	certs := make([]*CheckpointCert, 2)
+	for i, k := range keys {
+		certs[i] = NewCheckpointCert(checkpoint.Hash(), 5)
+		certs[i].Sign(k.PrivateKey)
+	}
+
+	// one signature is gossiped but not enough
+	if news, ok := bc.ReceiveCheckpointCert(certs[0]); news == nil || ok {
+		t.Fatal("expected first signature to be news, but not a checkpoint")
+	}
+	if news, _ := bc.ReceiveCheckpointCert(certs[0]); news != nil {
+		t.Fatal("expected repeated signature not to be news")
+	}
+	news, ok := bc.ReceiveCheckpointCert(certs[1])
+	if !ok || len(news.Sigs) != 2 {
+		t.Fatal("expected two signatures to make a checkpoint")
+	}
+	if !bc.IsCheckpoint(checkpoint.Hash()) || bc.LatestCheckPointNumber() != 5 {
+		t.Fatalf("checkpoint not updated. Have #%d %x", bc.LatestCheckPointNumber(), bc.LatestCheckPointHash())
+	}
+
+	// a heavier fork off block 2 would rewrite the checkpoint
+	setDB(1)
+	bman2, err := newCanonical(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bman2.bc.SetProcessor(bman2)
+	chainB := makeChain(bman2, bman2.bc.CurrentBlock(), 12)
+	setDB(0)
+	chainB = flushChain(chainB)
+	if _, err := bc.TestChain(chainB); !IsCheckpointErr(err) {
+		t.Fatal("expected a checkpoint error, got", err)
+	}
+
+	// but forks above it are fine
+	setDB(1)
+	bman2, err = newCanonical(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bman2.bc.SetProcessor(bman2)
+	chainB = makeChain(bman2, bman2.bc.CurrentBlock(), 8)
+	setDB(0)
+	chainB = flushChain(chainB)
+	if _, err := bc.TestChain(chainB); err != nil {
+		t.Fatal("expected fork above the checkpoint to pass, got", err)
+	}
+}
+
+func TestPendingCerts(t *testing.T) {
+	// no index entries left over from longer chains
+	emptyDB(0)
+	bman, err := newCanonical(8)
+	if err != nil {
+		t.Fatal("Could not make new canonical chain:", err)
+	}
+	bc := bman.bc
+	banned := monkcrypto.GenerateNewKeyPair()
+	bc.protocol = &banDoug{banned: banned.Address()}
+	bc.pendingCerts = make(map[string]*CheckpointCert)
+	hash := func(number uint64) []byte {
+		if b := bc.GetBlockByNumber(number); b != nil {
+			return b.Hash()
+		}
+		return []byte("none")
+	}
+
+	// signers without the permission don't take up space
+	cert := NewCheckpointCert(hash(8), 8)
+	cert.Sign(banned.PrivateKey)
+	if news, _ := bc.ReceiveCheckpointCert(cert); news != nil || len(bc.pendingCerts) != 0 {
+		t.Fatal("expected a cert from an unpermitted signer to be dropped")
+	}
+
+	// nor do certs for blocks that aren't on our canonical chain
+	signer := monkcrypto.GenerateNewKeyPair()
+	for _, cert := range []*CheckpointCert{NewCheckpointCert([]byte("unknown"), 4), NewCheckpointCert(hash(4), 5)} {
+		cert.Sign(signer.PrivateKey)
+		if news, _ := bc.ReceiveCheckpointCert(cert); news != nil || len(bc.pendingCerts) != 0 || bc.WaitingForCheckpoint() {
+			t.Fatalf("expected a cert for #%d %x to be dropped", cert.Number, cert.Hash)
+		}
+	}
+
+	defer func(n int) { MaxPendingCerts = n }(MaxPendingCerts)
+	MaxPendingCerts = 3
+	receive := func(number uint64) *CheckpointCert {
+		cert := NewCheckpointCert(hash(number), number)
+		cert.Sign(signer.PrivateKey)
+		news, _ := bc.ReceiveCheckpointCert(cert)
+		return news
+	}
+	for _, n := range []uint64{3, 5, 6} {
+		receive(n)
+	}
+	// a full queue makes room for higher blocks
+	if receive(7) == nil {
+		t.Fatal("expected a full queue to evict the lowest entry")
+	}
+	if _, ok := bc.pendingCerts[string(hash(3))]; ok || len(bc.pendingCerts) != 3 {
+		t.Fatalf("expected #3 to be evicted, have %d entries", len(bc.pendingCerts))
+	}
+	// but not for lower ones
+	if receive(4) != nil {
+		t.Fatal("expected a lower entry to be dropped when full")
+	}
+	// or ones above our head
+	if receive(9) != nil || receive(100) != nil {
+		t.Fatal("expected certs above our head to be dropped")
+	}
+
+	// entries reorged off canonical go first, however high
+	bc.pendingCerts["reorged"] = NewCheckpointCert([]byte("reorged"), 8)
+	delete(bc.pendingCerts, string(hash(5)))
+	if receive(2) == nil {
+		t.Fatal("expected a full queue to evict the reorged entry")
+	}
+	if _, ok := bc.pendingCerts["reorged"]; ok {
+		t.Fatal("expected the reorged entry to be evicted")
+	}
+}
@@ -0,0 +1,50 @@
+package monkchain
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func commitKey(hash []byte) []byte {
+	return append(monkutil.CopyBytes(hash), []byte("Commit")...)
+}
+
+// Take a commit from a peer or our own validator. Good commits are
+// kept, and finalize their block once it's on our chain.
+// Returns true if the commit is news to us
+func (bc *ChainManager) ReceiveCommit(commit *monkutil.Value) (bool, error) {
+	committer, ok := bc.protocol.(Committer)
+	if !ok {
+		return false, fmt.Errorf("Consensus does not commit blocks")
+	}
+	hash, number, err := committer.VerifyCommit(commit, bc)
+	if err != nil {
+		return false, err
+	}
+	if bc.HasCommit(hash) {
+		return false, nil
+	}
+	monkutil.Config.Db.Put(commitKey(hash), commit.Encode())
+
+	if canonical := bc.CanonicalHash(number); canonical != nil && bytes.Equal(canonical, hash) {
+		return true, bc.Finalize(hash)
+	}
+	// we'll finalize it when it gets here
+	return true, nil
+}
+
+func (bc *ChainManager) HasCommit(hash []byte) bool {
+	data, _ := monkutil.Config.Db.Get(commitKey(hash))
+	return len(data) != 0
+}
+
+// The commit for a block, if we have one
+func (bc *ChainManager) GetCommit(hash []byte) *monkutil.Value {
+	data, _ := monkutil.Config.Db.Get(commitKey(hash))
+	if len(data) == 0 {
+		return nil
+	}
+	return monkutil.NewValueFromBytes(data)
+}
@@ -0,0 +1,83 @@
+package monkchain
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+// Takes any commit naming a block, like a bft model would
+// take one with a quorum of precommits
+type commitDoug struct {
+	fakeDoug
+}
+
+func (d *commitDoug) VerifyCommit(commit *monkutil.Value, bc *ChainManager) ([]byte, uint64, error) {
+	hash := commit.Get(0).Bytes()
+	if len(hash) == 0 {
+		return nil, 0, fmt.Errorf("no block")
+	}
+	return hash, commit.Get(1).Uint(), nil
+}
+
+func commitFor(block *Block) *monkutil.Value {
+	return monkutil.NewValueFromBytes(monkutil.Encode([]interface{}{block.Hash(), block.Number.Uint64()}))
+}
+
+// A node that doesn't vote finalizes from commits,
+// and then won't reorg past them
+func TestCommitRefusesReorg(t *testing.T) {
+	initDB()
+	bman, err := newCanonical(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := bman.bc
+	bc.protocol = &commitDoug{}
+
+	committed := bc.GetBlockByNumber(4)
+	if news, err := bc.ReceiveCommit(commitFor(committed)); !news || err != nil {
+		t.Fatalf("expected the commit to be news, got %v %v", news, err)
+	}
+	if news, _ := bc.ReceiveCommit(commitFor(committed)); news {
+		t.Fatal("expected a repeated commit not to be news")
+	}
+	if !bc.IsFinal(committed.Hash()) || bc.LatestFinalNumber() != 4 {
+		t.Fatalf("expected #4 to be final, final is #%d", bc.LatestFinalNumber())
+	}
+
+	// the commit can arrive before the block
+	next := makeChain(bman, bc.CurrentBlock(), 1)
+	block := next.Front().Value.(*link).block
+	if news, err := bc.ReceiveCommit(commitFor(block)); !news || err != nil {
+		t.Fatalf("expected the commit to be news, got %v %v", news, err)
+	}
+	if bc.LatestFinalNumber() != 4 {
+		t.Fatal("finalized a block we don't have")
+	}
+	if _, err := bc.TestChain(next); err != nil {
+		t.Fatal(err)
+	}
+	bc.InsertChain(next)
+	if !bc.IsFinal(block.Hash()) || bc.LatestFinalNumber() != 7 {
+		t.Fatalf("expected #7 to be final, final is #%d", bc.LatestFinalNumber())
+	}
+
+	// a longer chain off block 2, signed by whoever, is refused
+	setDB(1)
+	bman2, err := newCanonical(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bman2.bc.SetProcessor(bman2)
+	chainB := makeChain(bman2, bman2.bc.CurrentBlock(), 12)
+	setDB(0)
+	chainB = flushChain(chainB)
+	if _, err := bc.TestChain(chainB); !IsFinalityErr(err) {
+		t.Fatal("expected a finality error, got", err)
+	}
+	if bc.CurrentBlockNumber() != 7 {
+		t.Fatalf("expected head to stay at #7, got #%d", bc.CurrentBlockNumber())
+	}
+}
@@ -97,6 +97,78 @@ func GasLimitTxError(is, max *big.Int) *GasLimitTxErr {
 	return &GasLimitTxErr{Message: fmt.Sprintf("GasLimitTx error. Max %s, transaction would take %s", max, is), Is: is, Max: max}
 }
 
+type GasPriceErr struct {
+	Message string
+	Is, Min *big.Int
+}
+
+func IsGasPriceErr(err error) bool {
+	_, ok := err.(*GasPriceErr)
+
+	return ok
+}
+func (err *GasPriceErr) Error() string {
+	return err.Message
+}
+func GasPriceError(is, min *big.Int) *GasPriceErr {
+	return &GasPriceErr{Message: fmt.Sprintf("Gas price too low. Min %s, transaction offers %s", min, is), Is: is, Min: min}
+}
+
+type TxWorkErr struct {
+	Message string
+}
+
+func (err *TxWorkErr) Error() string {
+	return err.Message
+}
+
+func TxWorkError(hash []byte, diff *big.Int) *TxWorkErr {
+	return &TxWorkErr{Message: fmt.Sprintf("Not enough work on tx %x. Difficulty %v", hash, diff)}
+}
+
+func IsTxWorkErr(err error) bool {
+	_, ok := err.(*TxWorkErr)
+	return ok
+}
+
+// A block too far ahead of our clock. It isn't invalid,
+// so it can be tried again once its time comes
+type FutureBlockErr struct {
+	Message string
+	Number  uint64
+	Time    int64
+}
+
+func (err *FutureBlockErr) Error() string {
+	return err.Message
+}
+
+func FutureBlockError(number uint64, blockTime, now int64) *FutureBlockErr {
+	return &FutureBlockErr{Message: fmt.Sprintf("Block #%d is from the future (%v > %v)", number, blockTime, now), Number: number, Time: blockTime}
+}
+
+func IsFutureBlockErr(err error) bool {
+	_, ok := err.(*FutureBlockErr)
+	return ok
+}
+
+type HaltedErr struct {
+	Message string
+}
+
+func (err *HaltedErr) Error() string {
+	return err.Message
+}
+
+func HaltedError(sender []byte) *HaltedErr {
+	return &HaltedErr{Message: fmt.Sprintf("Chain is halted. Tx from %x rejected", sender)}
+}
+
+func IsHaltedErr(err error) bool {
+	_, ok := err.(*HaltedErr)
+	return ok
+}
+
 type NonceErr struct {
 	Message string
 	Is, Exp uint64
@@ -193,3 +265,137 @@ func IsTDError(e error) bool {
 	_, ok := e.(*TDError)
 	return ok
 }
+
+// A chain that would revert a finalized block
+type FinalityErr struct {
+	Message string
+	Number  uint64
+}
+
+func (err *FinalityErr) Error() string {
+	return err.Message
+}
+
+func FinalityError(branch []byte, branchNumber, finalNumber uint64) *FinalityErr {
+	return &FinalityErr{Message: fmt.Sprintf("Fork off block %x (#%d) would revert final block #%d", branch, branchNumber, finalNumber), Number: finalNumber}
+}
+
+func IsFinalityErr(err error) bool {
+	_, ok := err.(*FinalityErr)
+
+	return ok
+}
+
+// A chain that would rewrite history below the latest checkpoint
+type CheckpointErr struct {
+	Message string
+	Number  uint64
+}
+
+func (err *CheckpointErr) Error() string {
+	return err.Message
+}
+
+func CheckpointError(branch []byte, branchNumber, checkpointNumber uint64) *CheckpointErr {
+	return &CheckpointErr{Message: fmt.Sprintf("Fork off block %x (#%d) would rewrite history below checkpoint #%d", branch, branchNumber, checkpointNumber), Number: checkpointNumber}
+}
+
+func IsCheckpointErr(err error) bool {
+	_, ok := err.(*CheckpointErr)
+
+	return ok
+}
+
+// A genesis block that can't be authenticated
+type GenesisCertErr struct {
+	Message string
+}
+
+func (err *GenesisCertErr) Error() string {
+	return err.Message
+}
+
+func GenesisCertError(format string, v ...interface{}) *GenesisCertErr {
+	return &GenesisCertErr{fmt.Sprintf(format, v...)}
+}
+
+func IsGenesisCertErr(err error) bool {
+	_, ok := err.(*GenesisCertErr)
+
+	return ok
+}
+
+// A block past a scheduled fork that this node doesn't support
+type UnsupportedForkErr struct {
+	Message string
+	Number  uint64
+}
+
+func (err *UnsupportedForkErr) Error() string {
+	return err.Message
+}
+
+func UnsupportedForkError(number uint64, reason error) *UnsupportedForkErr {
+	return &UnsupportedForkErr{Message: fmt.Sprintf("Can not run block #%d past an unsupported fork: %v", number, reason), Number: number}
+}
+
+func IsUnsupportedForkErr(err error) bool {
+	_, ok := err.(*UnsupportedForkErr)
+
+	return ok
+}
+
+// A block in an incoming chain failed processing.
+// Err is what ProcessWithParent returned
+type InvalidBlockErr struct {
+	Message string
+	Number  uint64
+	Hash    []byte
+	Err     error
+}
+
+func (err *InvalidBlockErr) Error() string {
+	return err.Message
+}
+
+func InvalidBlockError(block *Block, reason error) *InvalidBlockErr {
+	return &InvalidBlockErr{Message: fmt.Sprintf("incoming chain failed %v\n", reason), Number: block.Number.Uint64(), Hash: block.Hash(), Err: reason}
+}
+
+func IsInvalidBlockErr(err error) bool {
+	_, ok := err.(*InvalidBlockErr)
+
+	return ok
+}
+
+// Importing a chain stopped on a bad block.
+// Position is where the block is in the stream (0 is the first).
+// Number is 0 if the block couldn't be read
+type ImportErr struct {
+	Message  string
+	Position uint64
+	Number   uint64
+	Err      error
+}
+
+func (err *ImportErr) Error() string {
+	return err.Message
+}
+
+func ImportError(position, number uint64, hash []byte, reason error) *ImportErr {
+	// get at the reason the block was invalid
+	if invalid, ok := reason.(*InvalidBlockErr); ok {
+		reason = invalid.Err
+	}
+	return &ImportErr{Message: fmt.Sprintf("Import failed on block #%d (%x), %d in the stream: %v", number, hash, position, reason), Position: position, Number: number, Err: reason}
+}
+
+func ImportReadError(position uint64, reason error) *ImportErr {
+	return &ImportErr{Message: fmt.Sprintf("Import failed reading block %d in the stream: %v", position, reason), Position: position, Err: reason}
+}
+
+func IsImportErr(err error) bool {
+	_, ok := err.(*ImportErr)
+
+	return ok
+}
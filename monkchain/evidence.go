@@ -0,0 +1,184 @@
+package monkchain
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+// Evidence that a miner equivocated: two different headers
+// for the same block number, both signed by their coinbase.
+// The pair is kept in hash order so the same equivocation
+// always makes the same evidence
+type Evidence struct {
+	A, B *Block
+}
+
+// Protocols that punish equivocation say how evidence gets into a block.
+// The tx comes back unsigned and without a nonce or gas price
+type Equivocation interface {
+	EvidenceTx(ev *Evidence, state *monkstate.State) *Transaction
+}
+
+func NewEvidence(a, b *Block) *Evidence {
+	if bytes.Compare(a.Hash(), b.Hash()) > 0 {
+		a, b = b, a
+	}
+	return &Evidence{A: a, B: b}
+}
+
+// Returns nil if the value isn't shaped like evidence
+func NewEvidenceFromValue(val *monkutil.Value) *Evidence {
+	if !val.IsList() || val.Len() != 2 {
+		return nil
+	}
+	blocks := make([]*Block, 2)
+	for i := range blocks {
+		side := val.Get(i)
+		if !side.IsList() || side.Len() != 2 || !side.Get(0).IsList() || (side.Get(0).Len() != 13 && side.Get(0).Len() != 14) || !side.Get(1).IsList() || side.Get(1).Len() != 3 {
+			return nil
+		}
+		block := NewUncleBlockFromValue(side.Get(0))
+		sig := side.Get(1)
+		block.v = sig.Get(0).Byte()
+		block.r = sig.Get(1).Bytes()
+		block.s = sig.Get(2).Bytes()
+		blocks[i] = block
+	}
+	return NewEvidence(blocks[0], blocks[1])
+}
+
+func evidenceSide(block *Block) []interface{} {
+	return []interface{}{block.header(), []interface{}{block.v, block.r, block.s}}
+}
+
+func (ev *Evidence) RlpData() []interface{} {
+	return []interface{}{evidenceSide(ev.A), evidenceSide(ev.B)}
+}
+
+func (ev *Evidence) Hash() []byte {
+	return monkcrypto.Sha3Bin(monkutil.Encode(ev.RlpData()))
+}
+
+// The equivocating miner
+func (ev *Evidence) Offender() []byte {
+	return ev.A.Coinbase
+}
+
+func (ev *Evidence) Number() uint64 {
+	return ev.A.Number.Uint64()
+}
+
+// Address that signed the block, or nil if the signature is bad
+func headerSigner(block *Block) []byte {
+	if len(block.r) == 0 || len(block.s) == 0 {
+		return nil
+	}
+	sig := append(monkutil.LeftPadBytes(block.r, 32), monkutil.LeftPadBytes(block.s, 32)...)
+	return sigSigner(block.Hash(), append(sig, block.v))
+}
+
+// Check the headers really are an equivocation
+func (ev *Evidence) Verify() error {
+	a, b := ev.A, ev.B
+	if a.Number == nil || b.Number == nil || a.Number.Cmp(b.Number) != 0 {
+		return fmt.Errorf("Evidence headers have different numbers")
+	}
+	if !bytes.Equal(a.Coinbase, b.Coinbase) {
+		return fmt.Errorf("Evidence headers have different coinbases")
+	}
+	if bytes.Equal(a.Hash(), b.Hash()) {
+		return fmt.Errorf("Evidence headers are the same block")
+	}
+	for _, block := range []*Block{a, b} {
+		if signer := headerSigner(block); !bytes.Equal(signer, block.Coinbase) {
+			return fmt.Errorf("Evidence header %x signed by %x, not its coinbase %x", block.Hash(), signer, block.Coinbase)
+		}
+	}
+	return nil
+}
+
+// How many blocks back we remember signed headers and evidence for
+var EquivocationWindow uint64 = 256
+
+// Remember who signed block, and if they already signed
+// a different one at the same height, keep the evidence
+// for DetectedEvidence
+func (bc *ChainManager) watchEquivocation(block *Block) {
+	if len(block.r) == 0 {
+		return
+	}
+	number := block.Number.Uint64()
+
+	bc.evidenceMut.Lock()
+	defer bc.evidenceMut.Unlock()
+
+	key := fmt.Sprintf("%d:%x", number, block.Coinbase)
+	prev, ok := bc.signedHeaders[key]
+	if !ok {
+		bc.signedHeaders[key] = block
+		bc.pruneEvidence(number)
+		return
+	}
+	if bytes.Equal(prev.Hash(), block.Hash()) {
+		return
+	}
+	ev := NewEvidence(prev, block)
+	if err := ev.Verify(); err != nil {
+		return
+	}
+	chainlogger.Infof("Miner %x signed two blocks at #%d (%x, %x)\n", block.Coinbase, number, prev.Hash()[:4], block.Hash()[:4])
+	bc.detectedEvidence = append(bc.detectedEvidence, ev)
+}
+
+// forget anything too far below number
+func (bc *ChainManager) pruneEvidence(number uint64) {
+	if number <= EquivocationWindow {
+		return
+	}
+	for k, b := range bc.signedHeaders {
+		if b.Number.Uint64()+EquivocationWindow < number {
+			delete(bc.signedHeaders, k)
+		}
+	}
+	for k, n := range bc.seenEvidence {
+		if n+EquivocationWindow < number {
+			delete(bc.seenEvidence, k)
+		}
+	}
+}
+
+// Evidence found while testing chains since the last call.
+// It should go through ReceiveEvidence like any other
+func (bc *ChainManager) DetectedEvidence() []*Evidence {
+	bc.evidenceMut.Lock()
+	defer bc.evidenceMut.Unlock()
+	evs := bc.detectedEvidence
+	bc.detectedEvidence = nil
+	return evs
+}
+
+// Receive evidence from a peer or our own detection.
+// Returns true if it's valid and new, so it should be gossiped on
+// and submitted for inclusion
+func (bc *ChainManager) ReceiveEvidence(ev *Evidence) bool {
+	if ev == nil || ev.Verify() != nil {
+		return false
+	}
+	// too old to bother with
+	if ev.Number()+EquivocationWindow < bc.CurrentBlockNumber() {
+		return false
+	}
+
+	bc.evidenceMut.Lock()
+	defer bc.evidenceMut.Unlock()
+	key := string(ev.Hash())
+	if _, ok := bc.seenEvidence[key]; ok {
+		return false
+	}
+	bc.seenEvidence[key] = ev.Number()
+	return true
+}
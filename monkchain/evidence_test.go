@@ -0,0 +1,100 @@
+package monkchain
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func signedBlock(keys *monkcrypto.KeyPair, number int64, extra string) *Block {
+	block := CreateBlock(nil, ZeroHash256, keys.Address(), big.NewInt(4), nil, extra)
+	block.Number = big.NewInt(number)
+	block.Sign(keys.PrivateKey)
+	return block
+}
+
+func TestEvidenceVerify(t *testing.T) {
+	initDB()
+	keys := monkcrypto.GenerateNewKeyPair()
+	a, b := signedBlock(keys, 3, "a"), signedBlock(keys, 3, "b")
+
+	ev := NewEvidence(a, b)
+	if err := ev.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	// order doesn't matter
+	if !bytes.Equal(NewEvidence(b, a).Hash(), ev.Hash()) {
+		t.Fatal("evidence depends on the order of the headers")
+	}
+
+	// over the wire and back
+	ev = NewEvidenceFromValue(monkutil.NewValueFromBytes(monkutil.Encode(ev.RlpData())))
+	if ev == nil {
+		t.Fatal("failed to decode evidence")
+	}
+	if err := ev.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ev.Offender(), keys.Address()) || ev.Number() != 3 {
+		t.Fatalf("bad decode: %x #%d", ev.Offender(), ev.Number())
+	}
+	if NewEvidenceFromValue(monkutil.NewValue([]interface{}{"junk"})) != nil {
+		t.Fatal("decoded junk")
+	}
+
+	other := monkcrypto.GenerateNewKeyPair()
+	bad := []*Evidence{
+		// same block twice
+		NewEvidence(a, a),
+		// different heights
+		NewEvidence(a, signedBlock(keys, 4, "b")),
+		// different miners
+		NewEvidence(a, signedBlock(other, 3, "b")),
+	}
+	// signed by someone else
+	forged := signedBlock(other, 3, "b")
+	forged.Coinbase = keys.Address()
+	bad = append(bad, NewEvidence(a, forged))
+	for i, ev := range bad {
+		if ev.Verify() == nil {
+			t.Fatalf("bad evidence %d verified", i)
+		}
+	}
+}
+
+func TestEquivocationDetection(t *testing.T) {
+	initDB()
+	bc := newChainManager(nil, &fakeDoug{})
+	keys := monkcrypto.GenerateNewKeyPair()
+	a, b := signedBlock(keys, 3, "a"), signedBlock(keys, 3, "b")
+
+	bc.watchEquivocation(a)
+	bc.watchEquivocation(a)
+	// someone else at the same height is fine
+	bc.watchEquivocation(signedBlock(monkcrypto.GenerateNewKeyPair(), 3, "c"))
+	if evs := bc.DetectedEvidence(); len(evs) != 0 {
+		t.Fatalf("expected no evidence, got %d", len(evs))
+	}
+
+	bc.watchEquivocation(b)
+	evs := bc.DetectedEvidence()
+	if len(evs) != 1 || !bytes.Equal(evs[0].Offender(), keys.Address()) {
+		t.Fatalf("expected evidence against %x, got %v", keys.Address(), evs)
+	}
+	if len(bc.DetectedEvidence()) != 0 {
+		t.Fatal("evidence should only be handed out once")
+	}
+
+	if !bc.ReceiveEvidence(evs[0]) {
+		t.Fatal("expected the evidence to be news")
+	}
+	if bc.ReceiveEvidence(NewEvidence(b, a)) {
+		t.Fatal("expected repeated evidence not to be news")
+	}
+	if bc.ReceiveEvidence(NewEvidence(a, a)) {
+		t.Fatal("accepted invalid evidence")
+	}
+}
@@ -0,0 +1,109 @@
+package monkchain
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+/*
+   Chains are exported as a plain stream of rlp encoded blocks,
+   oldest first, so a file can be imported by reading blocks off
+   the front until it runs out
+*/
+
+// Write the canonical blocks from..to (inclusive) to w.
+// to is capped at the head. Returns the number written
+func (bc *ChainManager) Export(w io.Writer, from, to uint64) (uint64, error) {
+	if head := bc.CurrentBlockNumber(); to > head {
+		to = head
+	}
+	var n uint64
+	for i := from; i <= to; i++ {
+		block := bc.GetBlockByNumber(i)
+		if block == nil {
+			return n, fmt.Errorf("Missing canonical block #%d", i)
+		}
+		if _, err := w.Write(block.RlpEncode()); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Largest encoded block ReadBlock will take, so a bad length
+// in a stream can't make us allocate whatever it says
+var MaxBlockSize uint64 = 16 * 1024 * 1024
+
+// Read the next block off an exported stream.
+// Returns io.EOF when the stream ends cleanly
+func ReadBlock(r *bufio.Reader) (*Block, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	raw := []byte{prefix}
+
+	var size uint64
+	switch {
+	case prefix >= 0xc0 && prefix <= 0xf7:
+		size = uint64(prefix - 0xc0)
+	case prefix > 0xf7:
+		lenBytes := make([]byte, prefix-0xf7)
+		if _, err := io.ReadFull(r, lenBytes); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		raw = append(raw, lenBytes...)
+		size = monkutil.BigD(lenBytes).Uint64()
+	default:
+		return nil, fmt.Errorf("Expected an rlp list for a block, got prefix %x", prefix)
+	}
+	if size > MaxBlockSize {
+		return nil, fmt.Errorf("Block of %d bytes is over the max of %d", size, MaxBlockSize)
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return NewBlockFromBytes(append(raw, body...)), nil
+}
+
+// Replay an exported stream on top of our chain with full validation.
+// Blocks already on our canonical chain are skipped, so an interrupted import can just
+// be run again. Stops with an ImportErr on the first bad or unreadable block.
+// progress (if not nil) is called after each imported block
+func (bc *ChainManager) Import(r io.Reader, progress func(*Block)) (uint64, error) {
+	var (
+		br       = bufio.NewReader(r)
+		imported uint64
+	)
+	for position := uint64(0); ; position++ {
+		block, err := ReadBlock(br)
+		if err == io.EOF {
+			return imported, nil
+		}
+		if err != nil {
+			return imported, ImportReadError(position, err)
+		}
+		// already on our canonical chain
+		if number := block.Number.Uint64(); number <= bc.CurrentBlockNumber() && bytes.Equal(bc.CanonicalHash(number), block.Hash()) {
+			continue
+		}
+
+		chain := NewChain(Blocks{block})
+		if _, err := bc.TestChain(chain); err != nil {
+			return imported, ImportError(position, block.Number.Uint64(), block.Hash(), err)
+		}
+		bc.InsertChain(chain)
+
+		imported++
+		if progress != nil {
+			progress(block)
+		}
+	}
+}
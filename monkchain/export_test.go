@@ -0,0 +1,104 @@
+package monkchain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkdb"
+)
+
+// import into an empty db, so nothing's there from other tests
+func emptyDB(i int) {
+	DB[i], _ = monkdb.NewMemDatabase()
+	setDB(i)
+}
+
+func TestExportImport(t *testing.T) {
+	initDB()
+	bman, err := newCanonical(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if n, err := bman.bc.Export(&buf, 0, 100); err != nil || n != 11 {
+		t.Fatalf("expected to export 11 blocks, got %d (%v)", n, err)
+	}
+	exported := buf.Bytes()
+
+	emptyDB(1)
+	bman2, err := newCanonical(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen uint64
+	n, err := bman2.bc.Import(bytes.NewReader(exported), func(b *Block) { seen = b.Number.Uint64() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 || seen != 10 || !bytes.Equal(bman2.bc.CurrentBlockHash(), bman.bc.CurrentBlockHash()) {
+		t.Fatalf("imported %d blocks up to #%d, head %x, expected %x", n, seen, bman2.bc.CurrentBlockHash(), bman.bc.CurrentBlockHash())
+	}
+
+	// importing again picks up where it left off
+	if n, err := bman2.bc.Import(bytes.NewReader(exported), nil); err != nil || n != 0 {
+		t.Fatalf("expected nothing new on a second import, got %d (%v)", n, err)
+	}
+	setDB(0)
+}
+
+func TestImportBadBlock(t *testing.T) {
+	initDB()
+	bman, err := newCanonical(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	bman.bc.Export(&buf, 1, 3)
+	// a block whose state doesn't match what it claims
+	bad := bman.bc.GetBlockByNumber(4)
+	bad.Coinbase = []byte("someone else's coinbase")
+	buf.Write(bad.RlpEncode())
+	bman.bc.Export(&buf, 5, 6)
+	exported := buf.Bytes()
+
+	emptyDB(1)
+	bman2, err := newCanonical(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := bman2.bc.Import(bytes.NewReader(exported), nil)
+	if !IsImportErr(err) || err.(*ImportErr).Number != 4 || err.(*ImportErr).Position != 3 {
+		t.Fatalf("expected an import error on #4, got %v", err)
+	}
+	if n != 3 || bman2.bc.CurrentBlockNumber() != 3 {
+		t.Fatalf("expected 3 blocks imported before the bad one, got %d (head #%d)", n, bman2.bc.CurrentBlockNumber())
+	}
+	setDB(0)
+}
+
+func TestImportOversizedBlock(t *testing.T) {
+	initDB()
+	bman, err := newCanonical(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	bman.bc.Export(&buf, 1, 2)
+	// a list claiming to be far bigger than any block
+	buf.Write([]byte{0xff, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	exported := buf.Bytes()
+
+	emptyDB(1)
+	bman2, err := newCanonical(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := bman2.bc.Import(bytes.NewReader(exported), nil)
+	if !IsImportErr(err) || err.(*ImportErr).Position != 2 {
+		t.Fatalf("expected an import error at position 2, got %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 blocks imported before the bad one, got %d", n)
+	}
+	setDB(0)
+}
@@ -2,6 +2,64 @@ package monkchain
 
 import (
 	"math/big"
+
+	"github.com/eris-ltd/thelonious/monkstate"
 )
 
 var BlockReward *big.Int = big.NewInt(1.5e+18)
+
+// How a chain mints new coin.
+// A Protocol that implements Rewards picks the policy,
+// otherwise we do what ethereum does
+type RewardPolicy struct {
+	// none, fixed, halving, or treasury
+	Mode   string
+	Amount *big.Int
+	// blocks between halvings (Mode = halving)
+	HalvingInterval uint64
+	// where the treasury's share goes (Mode = treasury)
+	Treasury []byte
+	// percent of the reward paid to the treasury
+	TreasuryShare uint64
+	// are uncles allowed (and rewarded)
+	Uncles bool
+}
+
+var DefaultRewardPolicy = &RewardPolicy{Mode: "fixed", Amount: BlockReward, Uncles: true}
+
+type Rewards interface {
+	RewardPolicy(state *monkstate.State) *RewardPolicy
+}
+
+// Optionally implemented by a Protocol with a fee floor.
+// Txs paying less are kept out of the pool and out of blocks
+type FeePolicy interface {
+	MinGasPrice(state *monkstate.State) *big.Int
+}
+
+// The reward for mining block number
+func (p *RewardPolicy) Reward(number *big.Int) *big.Int {
+	switch p.Mode {
+	case "fixed", "treasury":
+		return new(big.Int).Set(p.Amount)
+	case "halving":
+		if p.HalvingInterval == 0 {
+			return new(big.Int).Set(p.Amount)
+		}
+		halvings := new(big.Int).Div(number, new(big.Int).SetUint64(p.HalvingInterval))
+		if halvings.Cmp(big.NewInt(256)) >= 0 {
+			return new(big.Int)
+		}
+		return new(big.Int).Rsh(p.Amount, uint(halvings.Uint64()))
+	}
+	return new(big.Int)
+}
+
+// Split a reward between the coinbase and the treasury
+func (p *RewardPolicy) Split(reward *big.Int) (coinbase, treasury *big.Int) {
+	treasury = new(big.Int)
+	if p.Mode == "treasury" {
+		treasury.Mul(reward, new(big.Int).SetUint64(p.TreasuryShare)).Div(treasury, big.NewInt(100))
+	}
+	return new(big.Int).Sub(reward, treasury), treasury
+}
@@ -0,0 +1,63 @@
+package monkchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+var treasuryAddr = monkutil.LeftPadBytes([]byte("treasury"), 20)
+
+type rewardDoug struct {
+	fakeDoug
+	policy *RewardPolicy
+}
+
+func (d *rewardDoug) RewardPolicy(state *monkstate.State) *RewardPolicy { return d.policy }
+
+type rewardEth struct {
+	fakeEth
+	doug *rewardDoug
+}
+
+func (e *rewardEth) Protocol() Protocol { return e.doug }
+
+func TestRewardHalving(t *testing.T) {
+	p := &RewardPolicy{Mode: "halving", Amount: big.NewInt(1000), HalvingInterval: 10}
+	for n, expected := range map[int64]int64{0: 1000, 9: 1000, 10: 500, 25: 250, 10000: 0} {
+		if r := p.Reward(big.NewInt(n)); r.Cmp(big.NewInt(expected)) != 0 {
+			t.Errorf("reward at #%d is %v, expected %d", n, r, expected)
+		}
+	}
+	if r := (&RewardPolicy{Mode: "none"}).Reward(big.NewInt(1)); r.Sign() != 0 {
+		t.Errorf("expected no reward, got %v", r)
+	}
+}
+
+func TestAccumulateRewardPolicy(t *testing.T) {
+	initDB()
+	doug := &rewardDoug{policy: &RewardPolicy{Mode: "treasury", Amount: big.NewInt(100), Treasury: treasuryAddr, TreasuryShare: 10}}
+	bman := &BlockManager{bc: newChainManager(nil, FakeDoug), Pow: fakePow{}, th: &rewardEth{doug: doug}}
+	bman.bc.SetProcessor(bman)
+
+	parent := bman.bc.CurrentBlock()
+	block := newBlockFromParent([]byte("coinbase"), parent)
+	state := parent.State().Copy()
+	if err := bman.AccumelateRewards(state, block, parent); err != nil {
+		t.Fatal(err)
+	}
+	if b := state.GetAccount(block.Coinbase).Balance; b.Cmp(big.NewInt(90)) != 0 {
+		t.Fatalf("coinbase has %v, expected 90", b)
+	}
+	if b := state.GetAccount(treasuryAddr).Balance; b.Cmp(big.NewInt(10)) != 0 {
+		t.Fatalf("treasury has %v, expected 10", b)
+	}
+
+	// no uncles on this chain
+	block.Uncles = []*Block{newBlockFromParent([]byte("uncle"), parent)}
+	if err := bman.AccumelateRewards(state, block, parent); !IsUncleErr(err) {
+		t.Fatal("expected an uncle error, got", err)
+	}
+}
@@ -25,8 +25,12 @@ type Filter struct {
 
 	altered []data
 
+	// log topics by position. An empty topic matches anything
+	topics [][]byte
+
 	BlockCallback   func(*Block)
 	MessageCallback func(monkstate.Messages)
+	LogCallback     func(monkstate.Logs)
 }
 
 // Create a new filter which uses a bloom filter on blocks to figure out whether a particular block
@@ -72,6 +76,15 @@ func NewFilterFromMap(object map[string]interface{}, eth NodeManager) *Filter {
 		filter.altered = makeAltered(object["altered"])
 	}
 
+	if object["topics"] != nil {
+		if topics, ok := object["topics"].([]interface{}); ok {
+			for _, topic := range topics {
+				str, _ := topic.(string)
+				filter.AddTopic(monkutil.Hex2Bytes(str))
+			}
+		}
+	}
+
 	return filter
 }
 
@@ -106,6 +119,15 @@ func (self *Filter) AddTo(addr []byte) {
 	self.to = append(self.to, addr)
 }
 
+func (self *Filter) SetTopics(topics [][]byte) {
+	self.topics = topics
+}
+
+// Match the next topic position. nil matches anything
+func (self *Filter) AddTopic(topic []byte) {
+	self.topics = append(self.topics, topic)
+}
+
 func (self *Filter) SetMax(max int) {
 	self.max = max
 }
@@ -114,53 +136,73 @@ func (self *Filter) SetSkip(skip int) {
 	self.skip = skip
 }
 
-// Run filters messages with the current parameters set
-func (self *Filter) Find() []*monkstate.Message {
-	var earliestBlockNo uint64 = uint64(self.earliest)
+func (self *Filter) blockRange() (earliest, latest uint64) {
+	current := self.eth.ChainManager().CurrentBlock().Number.Uint64()
+	earliest, latest = uint64(self.earliest), uint64(self.latest)
 	if self.earliest == -1 {
-		earliestBlockNo = self.eth.ChainManager().CurrentBlock().Number.Uint64()
+		earliest = current
 	}
-	var latestBlockNo uint64 = uint64(self.latest)
 	if self.latest == -1 {
-		latestBlockNo = self.eth.ChainManager().CurrentBlock().Number.Uint64()
+		latest = current
 	}
+	return
+}
 
-	var (
-		messages []*monkstate.Message
-		block    = self.eth.ChainManager().GetBlockByNumber(latestBlockNo)
-		quit     bool
-	)
-	for i := 0; !quit && block != nil; i++ {
-		// Quit on latest
-		switch {
-		case block.Number.Uint64() == earliestBlockNo:
-			quit = true
-		case self.max <= len(messages):
+// Walk the canonical blocks in range, newest first, using only the
+// header blooms. Blocks are loaded for fn when the bloom matches.
+// fn returns false to stop
+func (self *Filter) scan(match func(bloom []byte) bool, fn func(*Block) bool) {
+	chain := self.eth.ChainManager()
+	earliest, latest := self.blockRange()
+	for n := latest; n >= earliest; n-- {
+		if bloom, ok := chain.CanonicalBloom(n); ok && match(bloom) {
+			if block := chain.GetBlockByNumber(n); block != nil && !fn(block) {
+				return
+			}
+		}
+		if n == 0 {
 			break
 		}
+	}
+}
 
-		// Use bloom filtering to see if this block is interesting given the
-		// current parameters
-		if self.bloomFilter(block) {
-			// Get the messages of the block
-			msgs, err := self.eth.BlockManager().GetMessages(block)
-			if err != nil {
-				chainlogger.Warnln("err: filter get messages ", err)
-
-				break
-			}
-
-			messages = append(messages, self.FilterMessages(msgs)...)
+// Run filters messages with the current parameters set
+func (self *Filter) Find() []*monkstate.Message {
+	var messages []*monkstate.Message
+	self.scan(self.messageBloom, func(block *Block) bool {
+		// Get the messages of the block
+		msgs, err := self.eth.BlockManager().GetMessages(block)
+		if err != nil {
+			chainlogger.Warnln("err: filter get messages ", err)
+			return false
 		}
-
-		block = self.eth.ChainManager().GetBlock(block.PrevHash)
-	}
+		messages = append(messages, self.FilterMessages(msgs)...)
+		return self.max == 0 || len(messages) < self.max
+	})
 
 	skip := int(math.Min(float64(len(messages)), float64(self.skip)))
 
 	return messages[skip:]
 }
 
+// Run the filter over the logs in the receipts of the blocks in range.
+// Logs come back oldest first
+func (self *Filter) FindLogs() monkstate.Logs {
+	var logs monkstate.Logs
+	self.scan(self.logBloom, func(block *Block) bool {
+		var blockLogs monkstate.Logs
+		for _, receipt := range self.eth.ChainManager().GetTxReceipts(block.Hash()) {
+			blockLogs = append(blockLogs, self.FilterLogs(receipt.Logs)...)
+		}
+		logs = append(blockLogs, logs...)
+		return self.max == 0 || len(logs) < self.max
+	})
+
+	skip := int(math.Min(float64(len(logs)), float64(self.skip)))
+
+	return logs[skip:]
+}
+
 func includes(addresses [][]byte, a []byte) (found bool) {
 	for _, addr := range addresses {
 		if bytes.Compare(addr, a) == 0 {
@@ -212,39 +254,69 @@ func (self *Filter) FilterMessages(msgs []*monkstate.Message) []*monkstate.Messa
 	return messages
 }
 
-func (self *Filter) bloomFilter(block *Block) bool {
-	fk := append([]byte("bloom"), block.Hash()...)
-	bin, err := self.eth.Db().Get(fk)
-	if err != nil {
-		panic(err)
+// Logs match on the address that emitted them (to) and their topics
+func (self *Filter) FilterLogs(logs monkstate.Logs) monkstate.Logs {
+	var matched monkstate.Logs
+
+	for _, log := range logs {
+		if len(self.to) > 0 && !includes(self.to, log.Address) {
+			continue
+		}
+
+		if !self.matchTopics(log) {
+			continue
+		}
+
+		matched = append(matched, log)
 	}
 
-	bloom := NewBloomFilter(bin)
+	return matched
+}
 
-	var fromIncluded, toIncluded bool
-	if len(self.from) > 0 {
-		for _, from := range self.from {
-			if bloom.Search(from) {
-				fromIncluded = true
-				break
-			}
+func (self *Filter) matchTopics(log *monkstate.Log) bool {
+	if len(self.topics) > len(log.Topics) {
+		return false
+	}
+	for i, topic := range self.topics {
+		if len(topic) == 0 {
+			continue
+		}
+		if !bytes.Equal(monkutil.LeftPadBytes(topic, 32), log.Topics[i]) {
+			return false
 		}
-	} else {
-		fromIncluded = true
 	}
+	return true
+}
 
-	if len(self.to) > 0 {
-		for _, to := range self.to {
-			if bloom.Search(to) {
-				toIncluded = true
-				break
-			}
+// Could the block have messages from and to our addresses
+func (self *Filter) messageBloom(bloom []byte) bool {
+	return bloomHasAny(bloom, self.from) && bloomHasAny(bloom, self.to)
+}
+
+// Could the block have logs from our addresses with our topics
+func (self *Filter) logBloom(bloom []byte) bool {
+	if !bloomHasAny(bloom, self.to) {
+		return false
+	}
+	for _, topic := range self.topics {
+		if len(topic) != 0 && !HeaderBloomLookup(bloom, monkutil.LeftPadBytes(topic, 32)) {
+			return false
 		}
-	} else {
-		toIncluded = true
 	}
+	return true
+}
 
-	return fromIncluded && toIncluded
+// true if there's nothing to look for
+func bloomHasAny(bloom []byte, addrs [][]byte) bool {
+	if len(addrs) == 0 {
+		return true
+	}
+	for _, addr := range addrs {
+		if HeaderBloomLookup(bloom, addr) {
+			return true
+		}
+	}
+	return false
 }
 
 // Conversion methodn
@@ -1,7 +1,182 @@
 package monkchain
 
-import "testing"
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+	"github.com/eris-ltd/thelonious/monkvm"
+)
 
 func TestFilter(t *testing.T) {
 	//filter := NewFilter(nil)
 }
+
+// So filters can reach the chain
+type filterEth struct {
+	fakeEth
+	bc *ChainManager
+}
+
+func (e *filterEth) ChainManager() *ChainManager { return e.bc }
+
+var (
+	logAddr  = monkutil.LeftPadBytes([]byte("emitter"), 20)
+	logTopic = monkutil.LeftPadBytes([]byte("transfer"), 32)
+)
+
+// Extend bc by n empty blocks, with a log in every block whose number
+// is a multiple of every
+func makeLogChain(bc *ChainManager, n, every int) {
+	bman := &BlockManager{bc: bc}
+	parent := bc.CurrentBlock()
+	for i := 0; i < n; i++ {
+		block := newBlockFromParent(parent.Coinbase, parent)
+		if block.Number.Uint64()%uint64(every) == 0 {
+			log := &monkstate.Log{Address: logAddr, Topics: [][]byte{logTopic}, Data: block.Number.Bytes()}
+			block.txReceipts = []*TxReceipt{&TxReceipt{GasUsed: big.NewInt(0), CumulativeGasUsed: big.NewInt(0), Logs: monkstate.Logs{log}}}
+			block.LogsBloom = CreateHeaderBloom(block.txReceipts)
+			bman.writeTxReceipts(block)
+		}
+		bc.add(block)
+		parent = block
+	}
+}
+
+func TestHeaderBloom(t *testing.T) {
+	initDB()
+	bc := newChainManager(nil, FakeDoug)
+	makeLogChain(bc, 20, 7)
+
+	block := bc.GetBlockByNumber(14)
+	if !HeaderBloomLookup(block.LogsBloom, logAddr) || !HeaderBloomLookup(block.LogsBloom, logTopic) {
+		t.Fatal("expected the address and topic in the bloom")
+	}
+	if HeaderBloomLookup(block.LogsBloom, []byte("something else")) {
+		t.Fatal("unexpected bloom hit")
+	}
+	// the bloom survives encoding, and blocks without one keep the old header
+	if decoded := NewBlockFromBytes(block.RlpEncode()); !bytes.Equal(decoded.LogsBloom, block.LogsBloom) || !bytes.Equal(decoded.Hash(), block.Hash()) {
+		t.Fatal("bloom lost in encoding")
+	}
+	if l := len(bc.GetBlockByNumber(13).header()); l != 13 {
+		t.Fatalf("expected 13 header fields without a bloom, got %d", l)
+	}
+
+	filter := NewFilter(&filterEth{bc: bc})
+	filter.SetEarliestBlock(0)
+	filter.SetLatestBlock(-1)
+	filter.AddTo(logAddr)
+	filter.AddTopic(logTopic)
+	logs := filter.FindLogs()
+	if len(logs) != 2 || monkutil.BigD(logs[0].Data).Uint64() != 7 || monkutil.BigD(logs[1].Data).Uint64() != 14 {
+		t.Fatalf("expected logs from blocks 7 and 14, got %v", logs)
+	}
+
+	filter.SetTopics([][]byte{[]byte("nope")})
+	if logs := filter.FindLogs(); len(logs) != 0 {
+		t.Fatalf("expected no logs, got %v", logs)
+	}
+}
+
+// Blooms are required once the fork is on
+type bloomDoug struct {
+	fakeDoug
+	fork bool
+}
+
+func (d *bloomDoug) CheckFork(state *monkstate.State) error { return nil }
+func (d *bloomDoug) GasSchedule(state *monkstate.State) *monkvm.GasSchedule {
+	return monkvm.DefaultGasSchedule
+}
+func (d *bloomDoug) HeaderBloom(state *monkstate.State) bool            { return d.fork }
+func (d *bloomDoug) Logs(state *monkstate.State) bool                   { return d.fork }
+func (d *bloomDoug) ActivateForks(state *monkstate.State, block *Block) {}
+
+func TestReplayWithoutBloom(t *testing.T) {
+	initDB()
+	doug := &bloomDoug{}
+	bman := &BlockManager{bc: newChainManager(nil, doug), Pow: fakePow{}, th: FakeEth}
+	bman.bc.SetProcessor(bman)
+
+	keys := monkcrypto.GenerateNewKeyPair()
+	parent := bman.bc.CurrentBlock()
+	parent.State().GetOrNewStateObject(keys.Address()).AddAmount(monkutil.BigPow(10, 18))
+	parent.State().Update()
+	parent.State().Sync()
+
+	// a block from before blooms: it has a tx but no bloom
+	block := newBlockFromParent([]byte("coinbase"), parent)
+	tx := NewTransactionMessage(logAddr, big.NewInt(1), big.NewInt(1000), block.MinGasPrice, nil)
+	tx.Sign(keys.PrivateKey)
+	cbase := block.State().GetOrNewStateObject(block.Coinbase)
+	cbase.SetGasPool(block.CalcGasLimit(parent))
+	receipts, txs, _, err := bman.ProcessTransactions(cbase, block.State(), block, parent, Transactions{tx})
+	if err != nil || len(txs) != 1 {
+		t.Fatal("tx not processed:", err)
+	}
+	block.SetTxHash(receipts)
+	block.SetReceipts(receipts, txs)
+	bman.AccumelateRewards(block.State(), block, parent)
+	block.State().Update()
+	bloom := CreateHeaderBloom(block.txReceipts)
+
+	td := bman.bc.BlockInfo(parent).TD
+	process := func() error {
+		bman.transState = nil
+		bman.bc.workingChain = NewChain(Blocks{parent})
+		bman.bc.workingChain.Back().Value.(*link).td = td
+		_, err := bman.ProcessWithParent(block, parent)
+		return err
+	}
+	if err := process(); err != nil {
+		t.Fatal("expected a block without a bloom before the fork, got", err)
+	}
+	block.LogsBloom = make([]byte, HeaderBloomSize)
+	if err := process(); err == nil {
+		t.Fatal("expected a wrong bloom to fail before the fork")
+	}
+
+	doug.fork = true
+	block.LogsBloom = nil
+	if err := process(); err == nil {
+		t.Fatal("expected a block without a bloom to fail after the fork")
+	}
+	block.LogsBloom = bloom
+	if err := process(); err != nil {
+		t.Fatal(err)
+	}
+
+	// filters can't skip it by its bloom
+	bare := newBlockFromParent([]byte("coinbase"), parent)
+	bare.SetReceipts(receipts, txs)
+	if !HeaderBloomLookup(scanBloom(bare), []byte("anything")) {
+		t.Fatal("expected a block without a bloom to match every filter")
+	}
+}
+
+var benchChain *ChainManager
+
+func BenchmarkFilterLogs(b *testing.B) {
+	if benchChain == nil {
+		initDB()
+		benchChain = newChainManager(nil, FakeDoug)
+		makeLogChain(benchChain, 100000, 1000)
+	}
+	monkutil.Config.Db = DB[0]
+	filter := NewFilter(&filterEth{bc: benchChain})
+	filter.SetEarliestBlock(0)
+	filter.SetLatestBlock(-1)
+	filter.AddTo(logAddr)
+	filter.AddTopic(logTopic)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if logs := filter.FindLogs(); len(logs) != 100 {
+			b.Fatalf("expected 100 logs, got %d", len(logs))
+		}
+	}
+}
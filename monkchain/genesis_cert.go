@@ -0,0 +1,73 @@
+package monkchain
+
+import (
+	"bytes"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkutil"
+	"github.com/obscuren/secp256k1-go"
+)
+
+// A genesis certificate lets a peer authenticate a chainId.
+// The chainId is the leading 20 bytes of the sha3 of the
+// signature on the genesis block, so with the block, the signature
+// and the signer's pubkey anyone can check who made the chain
+type GenesisCert struct {
+	Block *Block
+	// 65 byte signature (v is last, offset by 27)
+	Sig []byte
+	// 65 byte uncompressed pubkey
+	PubKey []byte
+}
+
+// Certificate for a signed genesis block
+func NewGenesisCert(block *Block) *GenesisCert {
+	cert := &GenesisCert{Block: block, PubKey: block.PublicKey()}
+	if block.r != nil && block.s != nil {
+		cert.Sig = append(monkutil.LeftPadBytes(block.r, 32), monkutil.LeftPadBytes(block.s, 32)...)
+		cert.Sig = append(cert.Sig, block.v)
+	}
+	return cert
+}
+
+func NewGenesisCertFromValue(val *monkutil.Value) *GenesisCert {
+	cert := &GenesisCert{}
+	cert.Block = NewBlockFromRlpValue(val.Get(0))
+	cert.Sig = val.Get(1).Bytes()
+	cert.PubKey = val.Get(2).Bytes()
+	return cert
+}
+
+func (cert *GenesisCert) ChainId() []byte {
+	return monkcrypto.Sha3Bin(cert.Sig)[:20]
+}
+
+// Address of the signer
+func (cert *GenesisCert) Signer() []byte {
+	if len(cert.PubKey) != 65 {
+		return nil
+	}
+	return monkcrypto.Sha3Bin(cert.PubKey[1:])[12:]
+}
+
+// Check the signature is by PubKey over the genesis block
+// and that it produces chainId
+func (cert *GenesisCert) Verify(chainId []byte) error {
+	if len(cert.Sig) != 65 {
+		return GenesisCertError("Genesis block is not signed")
+	}
+	sig := append([]byte{}, cert.Sig[:64]...)
+	sig = append(sig, cert.Sig[64]-27)
+	pubkey, _ := secp256k1.RecoverPubkey(cert.Block.Hash(), sig)
+	if len(pubkey) == 0 || !bytes.Equal(pubkey, cert.PubKey) {
+		return GenesisCertError("Genesis signature does not match pubkey %x", cert.PubKey)
+	}
+	if !bytes.Equal(cert.ChainId(), chainId) {
+		return GenesisCertError("Genesis signature gives chainId %x, expected %x", cert.ChainId(), chainId)
+	}
+	return nil
+}
+
+func (cert *GenesisCert) RlpData() []interface{} {
+	return []interface{}{cert.Block.Value().Raw(), cert.Sig, cert.PubKey}
+}
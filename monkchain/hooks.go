@@ -0,0 +1,98 @@
+package monkchain
+
+import (
+	"math/big"
+
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+// What a block hook did.
+// Hooks have their own gas, which doesn't come out of the block's gas pool
+type HookReceipt struct {
+	Name    string
+	GasUsed *big.Int
+	Output  []byte
+	// set if the hook failed (its changes are reverted)
+	Err string
+}
+
+func NewHookReceiptFromValue(val *monkutil.Value) *HookReceipt {
+	r := &HookReceipt{}
+	r.Name = val.Get(0).Str()
+	r.GasUsed = val.Get(1).BigInt()
+	r.Output = val.Get(2).Bytes()
+	r.Err = val.Get(3).Str()
+	return r
+}
+
+func (r *HookReceipt) RlpData() []interface{} {
+	return []interface{}{r.Name, r.GasUsed, r.Output, r.Err}
+}
+
+// Run the protocol's hook before the block's txs
+func (sm *BlockManager) PreBlock(state *monkstate.State, block *Block) {
+	block.hookReceipts = nil
+	if hooks, ok := sm.th.Protocol().(BlockHooks); ok {
+		sm.runHook(hooks.PreBlock, hooks.HasHook("pre", state), state, block)
+	}
+}
+
+// Run the protocol's hook after the block's txs,
+// then activate any forks for the next block
+func (sm *BlockManager) PostBlock(state *monkstate.State, block *Block) {
+	if hooks, ok := sm.th.Protocol().(BlockHooks); ok {
+		sm.runHook(hooks.PostBlock, hooks.HasHook("post", state), state, block)
+	}
+	if forks, ok := sm.th.Protocol().(Forks); ok {
+		forks.ActivateForks(state, block)
+	}
+}
+
+// A failed hook is recorded, but leaves the state as it was.
+// The state is only copied if there's a hook that could fail
+func (sm *BlockManager) runHook(hook func(*monkstate.State, *Block) *HookReceipt, hasHook bool, state *monkstate.State, block *Block) {
+	var snapshot *monkstate.State
+	if hasHook {
+		snapshot = state.Copy()
+	}
+	receipt := hook(state, block)
+	if receipt == nil {
+		return
+	}
+	if receipt.Err != "" && snapshot != nil {
+		statelogger.Infof("Block hook %s failed on #%d: %s\n", receipt.Name, block.Number, receipt.Err)
+		state.Set(snapshot)
+	}
+	block.hookReceipts = append(block.hookReceipts, receipt)
+}
+
+// Receipts from the last run of the block hooks on this block
+func (block *Block) HookReceipts() []*HookReceipt {
+	return block.hookReceipts
+}
+
+func (sm *BlockManager) writeHookReceipts(block *Block) {
+	if len(block.hookReceipts) == 0 {
+		return
+	}
+	data := make([]interface{}, len(block.hookReceipts))
+	for i, r := range block.hookReceipts {
+		data[i] = r.RlpData()
+	}
+	monkutil.Config.Db.Put(append(block.Hash(), []byte("Hooks")...), monkutil.Encode(data))
+}
+
+// Hook receipts of a processed block
+func (sm *BlockManager) GetHookReceipts(hash []byte) []*HookReceipt {
+	data, _ := monkutil.Config.Db.Get(append(hash, []byte("Hooks")...))
+	if len(data) == 0 {
+		return nil
+	}
+	val := monkutil.NewValueFromBytes(data)
+	receipts := make([]*HookReceipt, val.Len())
+	for i := range receipts {
+		receipts[i] = NewHookReceiptFromValue(val.Get(i))
+	}
+	return receipts
+}
@@ -0,0 +1,62 @@
+package monkchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+var hookAddr = monkutil.LeftPadBytes([]byte("hook"), 20)
+
+// Pays hookAddr before each block, and fails after
+type hookDoug struct {
+	fakeDoug
+}
+
+func (d *hookDoug) PreBlock(state *monkstate.State, block *Block) *HookReceipt {
+	state.GetOrNewStateObject(hookAddr).AddAmount(big.NewInt(1))
+	return &HookReceipt{Name: "pre", GasUsed: big.NewInt(21), Output: block.Number.Bytes()}
+}
+
+func (d *hookDoug) PostBlock(state *monkstate.State, block *Block) *HookReceipt {
+	state.GetOrNewStateObject(hookAddr).AddAmount(big.NewInt(100))
+	return &HookReceipt{Name: "post", GasUsed: big.NewInt(0), Err: "out of gas"}
+}
+
+func (d *hookDoug) HasHook(name string, state *monkstate.State) bool { return true }
+
+type hookEth struct {
+	fakeEth
+}
+
+func (e *hookEth) Protocol() Protocol { return &hookDoug{} }
+
+func TestBlockHooks(t *testing.T) {
+	initDB()
+	bman := &BlockManager{bc: newChainManager(nil, FakeDoug), Pow: fakePow{}, th: &hookEth{}}
+	bman.bc.SetProcessor(bman)
+	lchain := makeChain(bman, bman.bc.CurrentBlock(), 3)
+	if _, err := bman.bc.TestChain(lchain); err != nil {
+		t.Fatal(err)
+	}
+	bman.bc.InsertChain(lchain)
+
+	head := bman.bc.CurrentBlock()
+	if b := head.State().GetStateObject(hookAddr).Balance; b.Cmp(big.NewInt(3)) != 0 {
+		t.Fatalf("hook address has %v, expected 3 (failed hooks should be reverted)", b)
+	}
+
+	receipts := bman.GetHookReceipts(head.Hash())
+	if len(receipts) != 2 {
+		t.Fatalf("expected 2 hook receipts, got %d", len(receipts))
+	}
+	pre, post := receipts[0], receipts[1]
+	if pre.Name != "pre" || pre.GasUsed.Cmp(big.NewInt(21)) != 0 || monkutil.BigD(pre.Output).Uint64() != 3 || pre.Err != "" {
+		t.Fatalf("bad pre hook receipt %v", pre)
+	}
+	if post.Name != "post" || post.Err != "out of gas" {
+		t.Fatalf("bad post hook receipt %v", post)
+	}
+}
@@ -0,0 +1,142 @@
+package monkchain
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+/*
+   Indexes over the canonical chain, kept in the db next to the blocks:
+       "BlockNum"+number   - hash of the canonical block at number
+       "TxLoc"+txhash      - rlp(block hash, index) of a canonical tx
+       "Bloom"+number      - rlp(header bloom) of the canonical block at number,
+                             so filters can scan without decoding blocks
+
+   They're written as blocks are added to canonical and cleaned up
+   when a reorg drops blocks from it. A block's entries go through a
+   batch so they land with the block or not at all. Chains from before
+   the indexes existed (or whose head lost its entries) get them built on boot
+*/
+
+func numberKey(number uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, number)
+	return append([]byte("BlockNum"), key...)
+}
+
+func bloomKey(number uint64) []byte {
+	return append([]byte("Bloom"), numberKey(number)[len("BlockNum"):]...)
+}
+
+func txKey(hash []byte) []byte {
+	return append([]byte("TxLoc"), hash...)
+}
+
+// Queue a block's entries on the batch.
+// not thread safe (caller should lock)
+func (bc *ChainManager) indexBlock(batch monkutil.Batch, block *Block) {
+	hash := block.Hash()
+	for i, tx := range block.Transactions() {
+		batch.Put(txKey(tx.Hash()), monkutil.Encode([]interface{}{hash, uint64(i)}))
+	}
+	batch.Put(numberKey(block.Number.Uint64()), hash)
+	batch.Put(bloomKey(block.Number.Uint64()), monkutil.Encode(scanBloom(block)))
+}
+
+// The bloom filters scan for a block. Blocks from before the bloom
+// fork can have txs and no bloom, so they match everything
+func scanBloom(block *Block) []byte {
+	if len(block.LogsBloom) == 0 && len(block.Transactions()) != 0 {
+		return bytes.Repeat([]byte{0xff}, HeaderBloomSize)
+	}
+	return block.LogsBloom
+}
+
+// Queue dropping the entries pointing at a block that's no longer canonical.
+// Entries already rewritten for the new canonical are left alone.
+// Checks against the db, so don't queue new entries before this
+func (bc *ChainManager) unindexBlock(batch monkutil.Batch, block *Block) {
+	hash := block.Hash()
+	db := monkutil.Config.Db
+	for _, tx := range block.Transactions() {
+		if h, _, ok := bc.TxLocation(tx.Hash()); ok && bytes.Equal(h, hash) {
+			batch.Delete(txKey(tx.Hash()))
+		}
+	}
+	key := numberKey(block.Number.Uint64())
+	if h, _ := db.Get(key); bytes.Equal(h, hash) {
+		batch.Delete(key)
+		batch.Delete(bloomKey(block.Number.Uint64()))
+	}
+}
+
+// Build the indexes for a chain that doesn't have them yet
+func (bc *ChainManager) reindex() {
+	head := bc.CurrentBlock()
+	if head == nil {
+		return
+	}
+	if h, _ := monkutil.Config.Db.Get(numberKey(head.Number.Uint64())); len(h) != 0 {
+		return
+	}
+	chainlogger.Infoln("Building the block number and tx indexes")
+	for b := head; b != nil; b = bc.GetBlockCanonical(b.PrevHash) {
+		batch := monkutil.Config.Db.NewBatch()
+		bc.indexBlock(batch, b)
+		if err := batch.Write(); err != nil {
+			chainlogger.Errorln("Failed to write index:", err)
+			return
+		}
+		if b.Number.Sign() == 0 {
+			break
+		}
+	}
+}
+
+// Hash of the canonical block at number
+func (bc *ChainManager) CanonicalHash(number uint64) []byte {
+	hash, _ := monkutil.Config.Db.Get(numberKey(number))
+	if len(hash) == 0 {
+		return nil
+	}
+	return hash
+}
+
+// Header bloom of the canonical block at number, without loading the block.
+// Chains indexed before blooms were fall back to the block
+func (bc *ChainManager) CanonicalBloom(number uint64) ([]byte, bool) {
+	data, _ := monkutil.Config.Db.Get(bloomKey(number))
+	if len(data) != 0 {
+		return monkutil.NewValueFromBytes(data).Bytes(), true
+	}
+	block := bc.GetBlockByNumber(number)
+	if block == nil {
+		return nil, false
+	}
+	return scanBloom(block), true
+}
+
+// Where a canonical tx is: its block's hash and its index in the block
+func (bc *ChainManager) TxLocation(hash []byte) ([]byte, uint64, bool) {
+	data, _ := monkutil.Config.Db.Get(txKey(hash))
+	if len(data) == 0 {
+		return nil, 0, false
+	}
+	val := monkutil.NewValueFromBytes(data)
+	return val.Get(0).Bytes(), val.Get(1).Uint(), true
+}
+
+// A canonical tx and the block it's in
+func (bc *ChainManager) GetTransaction(hash []byte) (*Transaction, *Block, uint64) {
+	blockHash, i, ok := bc.TxLocation(hash)
+	if !ok {
+		return nil, nil, 0
+	}
+	block := bc.GetBlockCanonical(blockHash)
+	if block == nil || i >= uint64(len(block.Transactions())) {
+		return nil, nil, 0
+	}
+	return block.Transactions()[i], block, i
+}
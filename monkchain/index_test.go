@@ -0,0 +1,124 @@
+package monkchain
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func TestBlockNumberIndex(t *testing.T) {
+	initDB()
+	bman, err := newCanonical(10)
+	if err != nil {
+		t.Fatal("Could not make new canonical chain:", err)
+	}
+	bc := bman.bc
+	for b := bc.CurrentBlock(); b != nil; b = bc.GetBlockCanonical(b.PrevHash) {
+		n := b.Number.Uint64()
+		if !bytes.Equal(bc.CanonicalHash(n), b.Hash()) {
+			t.Fatalf("bad index for #%d", n)
+		}
+		if got := bc.GetBlockByNumber(n); got == nil || !bytes.Equal(got.Hash(), b.Hash()) {
+			t.Fatalf("bad block for #%d", n)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	old := bc.CanonicalHash(7)
+
+	// a heavier fork off block 5 takes over
+	setDB(1)
+	bman2, err := newCanonical(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bman2.bc.SetProcessor(bman2)
+	chainB := makeChain(bman2, bman2.bc.CurrentBlock(), 8)
+	setDB(0)
+	chainB = flushChain(chainB)
+	if _, err := bc.TestChain(chainB); err != nil {
+		t.Fatal(err)
+	}
+	bc.InsertChain(chainB)
+
+	if bc.CurrentBlockNumber() != 13 {
+		t.Fatalf("expected the fork to be canonical, head is #%d", bc.CurrentBlockNumber())
+	}
+	for e := chainB.Front(); e != nil; e = e.Next() {
+		b := e.Value.(*link).block
+		if !bytes.Equal(bc.CanonicalHash(b.Number.Uint64()), b.Hash()) {
+			t.Fatalf("index not rewritten for #%d", b.Number)
+		}
+	}
+	if bytes.Equal(bc.CanonicalHash(7), old) {
+		t.Fatal("index still points at the old chain")
+	}
+}
+
+func TestTxIndex(t *testing.T) {
+	initDB()
+	bc := newChainManager(nil, FakeDoug)
+	keys := monkcrypto.GenerateNewKeyPair()
+
+	txs := make(Transactions, 3)
+	receipts := make(Receipts, 3)
+	for i := range txs {
+		txs[i] = NewTransactionMessage(keys.Address(), big.NewInt(int64(i)), big.NewInt(1000), big.NewInt(1), nil)
+		txs[i].Sign(keys.PrivateKey)
+		receipts[i] = &Receipt{txs[i], []byte("root"), big.NewInt(0)}
+	}
+	block := newBlockFromParent(keys.Address(), bc.Genesis())
+	block.SetReceipts(receipts, txs)
+	bc.add(block)
+
+	tx, b, i := bc.GetTransaction(txs[2].Hash())
+	if tx == nil || !bytes.Equal(tx.Hash(), txs[2].Hash()) || !bytes.Equal(b.Hash(), block.Hash()) || i != 2 {
+		t.Fatalf("bad lookup: %v %v %d", tx, b, i)
+	}
+
+	// dropping the block drops its txs
+	batch := monkutil.Config.Db.NewBatch()
+	bc.unindexBlock(batch, block)
+	if tx, _, _ := bc.GetTransaction(txs[0].Hash()); tx == nil {
+		t.Fatal("tx dropped before the batch was written")
+	}
+	batch.Write()
+	if tx, _, _ := bc.GetTransaction(txs[0].Hash()); tx != nil {
+		t.Fatal("tx still indexed")
+	}
+	if bc.CanonicalHash(1) != nil {
+		t.Fatal("number still indexed")
+	}
+}
+
+func TestReindexLostHead(t *testing.T) {
+	initDB()
+	bman, err := newCanonical(5)
+	if err != nil {
+		t.Fatal("Could not make new canonical chain:", err)
+	}
+	bc := bman.bc
+
+	// a reorg that stopped after dropping the old chain's entries
+	batch := monkutil.Config.Db.NewBatch()
+	for b := bc.CurrentBlock(); b.Number.Uint64() > 2; b = bc.GetBlockCanonical(b.PrevHash) {
+		bc.unindexBlock(batch, b)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if bc.CanonicalHash(4) != nil {
+		t.Fatal("entries not dropped")
+	}
+
+	bc.reindex()
+	for b := bc.CurrentBlock(); b.Number.Sign() > 0; b = bc.GetBlockCanonical(b.PrevHash) {
+		if !bytes.Equal(bc.CanonicalHash(b.Number.Uint64()), b.Hash()) {
+			t.Fatalf("#%d not reindexed", b.Number)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package monkchain
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkutil"
+	"github.com/eris-ltd/thelonious/monkvm"
+)
+
+// Process a call to a contract that logs, under doug
+func processLogTx(t *testing.T, doug Protocol) ([]byte, []*TxReceipt) {
+	initDB()
+	bman := &BlockManager{bc: newChainManager(nil, doug), Pow: fakePow{}, th: FakeEth}
+	genesis := bman.bc.CurrentBlock()
+	state := genesis.State().Copy()
+	keys := monkcrypto.GenerateNewKeyPair()
+	state.GetOrNewStateObject(keys.Address()).AddAmount(monkutil.BigPow(10, 18))
+
+	// mem[0] = 0x2a, then LOG2 it with topics 1 and 2
+	addr := monkutil.LeftPadBytes([]byte("logger"), 20)
+	state.GetOrNewStateObject(addr).Code = []byte{
+		byte(monkvm.PUSH1), 0x2a, byte(monkvm.PUSH1), 0, byte(monkvm.MSTORE8),
+		byte(monkvm.PUSH1), 2, byte(monkvm.PUSH1), 1, byte(monkvm.PUSH1), 1, byte(monkvm.PUSH1), 0, byte(monkvm.LOG2),
+		byte(monkvm.STOP),
+	}
+
+	tx := NewTransactionMessage(addr, big.NewInt(0), big.NewInt(10000), big.NewInt(1), nil)
+	tx.Sign(keys.PrivateKey)
+	block := newBlockFromParent(keys.Address(), genesis)
+	coinbase := state.GetOrNewStateObject(block.Coinbase)
+	coinbase.SetGasPool(block.CalcGasLimit(genesis))
+	if _, _, _, err := bman.ProcessTransactions(coinbase, state, block, genesis, Transactions{tx}); err != nil {
+		t.Fatal(err)
+	}
+	return addr, block.TxReceipts()
+}
+
+func TestLogReceipts(t *testing.T) {
+	addr, receipts := processLogTx(t, FakeDoug)
+	if len(receipts) != 1 || receipts[0].Failed() {
+		t.Fatalf("expected one good receipt, got %v", receipts)
+	}
+	logs := receipts[0].Logs
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 log, got %d", len(logs))
+	}
+	log := logs[0]
+	one, two := monkutil.LeftPadBytes([]byte{1}, 32), monkutil.LeftPadBytes([]byte{2}, 32)
+	if !bytes.Equal(log.Address, addr) || len(log.Topics) != 2 || !bytes.Equal(log.Topics[0], one) || !bytes.Equal(log.Topics[1], two) || !bytes.Equal(log.Data, []byte{0x2a}) {
+		t.Fatalf("bad log %v", log)
+	}
+
+	filter := NewFilter(nil)
+	filter.AddTo(addr)
+	filter.SetTopics([][]byte{nil, []byte{2}})
+	if len(filter.FilterLogs(logs)) != 1 {
+		t.Fatal("expected the filter to match on the second topic")
+	}
+	filter.SetTopics([][]byte{[]byte{2}})
+	if len(filter.FilterLogs(logs)) != 0 {
+		t.Fatal("expected no match with the wrong first topic")
+	}
+	filter.SetTopics([][]byte{nil, nil, nil})
+	if len(filter.FilterLogs(logs)) != 0 {
+		t.Fatal("expected no match when asking for more topics than the log has")
+	}
+}
+
+// Before the logs fork LOG2 is still an invalid opcode
+func TestLogsNeedFork(t *testing.T) {
+	_, receipts := processLogTx(t, &bloomDoug{})
+	if len(receipts) != 1 || !receipts[0].Failed() || len(receipts[0].Logs) != 0 {
+		t.Fatalf("expected the tx to fail without logs, got %v", receipts)
+	}
+	_, receipts = processLogTx(t, &bloomDoug{fork: true})
+	if len(receipts) != 1 || receipts[0].Failed() || len(receipts[0].Logs) != 1 {
+		t.Fatalf("expected the log after the fork, got %v", receipts)
+	}
+}
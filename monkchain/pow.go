@@ -0,0 +1,207 @@
+package monkchain
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkreact"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+// Select a proof of work algorithm by name.
+// The empty string is sha3, the original EasyPow
+func NewPoW(name string) (PoW, error) {
+	switch name {
+	case "", "sha3":
+		return &EasyPow{}, nil
+	case "dagger":
+		return &DaggerPow{}, nil
+	case "memhard":
+		return &MemHardPow{}, nil
+	case "none":
+		return &NoPow{}, nil
+	}
+	return nil, fmt.Errorf("Unknown proof of work algorithm %s", name)
+}
+
+// Objective for a nonce: the hash must be less than 2^256 / diff
+func powTarget(diff *big.Int) *big.Int {
+	return new(big.Int).Div(monkutil.BigPow(2, 256), diff)
+}
+
+// For chains that don't need a proof of work.
+// Every nonce is valid
+type NoPow struct{}
+
+func (pow *NoPow) Search(block *Block, reactChan chan monkreact.Event) []byte {
+	return make([]byte, 32)
+}
+
+func (pow *NoPow) Verify(hash []byte, diff *big.Int, nonce []byte) bool {
+	return true
+}
+
+func (pow *NoPow) GetHashrate() int64 {
+	return 0
+}
+
+func (pow *NoPow) Turbo(on bool) {
+}
+
+// Dagger behind the PoW interface. Nonces are 8 bytes
+type DaggerPow struct {
+	HashRate int64
+	turbo    bool
+}
+
+func (pow *DaggerPow) Search(block *Block, reactChan chan monkreact.Event) []byte {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	// Dagger keeps state while evaluating, so don't share it
+	dag := &Dagger{hash: monkutil.BigD(block.HashNoNonce())}
+	obj := powTarget(block.Difficulty)
+	i := int64(0)
+	start := time.Now()
+
+	for {
+		select {
+		case <-reactChan:
+			powlogger.Infoln("Breaking from mining")
+			return nil
+		default:
+			i++
+			pow.HashRate = int64(float64(i) / time.Since(start).Seconds())
+
+			nonce := big.NewInt(r.Int63())
+			if dag.Eval(nonce).Cmp(obj) < 0 {
+				return monkutil.LeftPadBytes(nonce.Bytes(), 8)
+			}
+		}
+
+		if !pow.turbo {
+			time.Sleep(20 * time.Microsecond)
+		}
+	}
+}
+
+func (pow *DaggerPow) Verify(hash []byte, diff *big.Int, nonce []byte) bool {
+	return DaggerVerify(monkutil.BigD(hash), diff, monkutil.BigD(nonce))
+}
+
+func (pow *DaggerPow) GetHashrate() int64 {
+	return pow.HashRate
+}
+
+func (pow *DaggerPow) Turbo(on bool) {
+	pow.turbo = on
+}
+
+// Size of the memory hard table in 32 byte words (4MB)
+// and the number of random reads from it per nonce
+var (
+	MemHardWords = 1 << 17
+	MemHardReads = 64
+)
+
+// A memory hard proof of work. Each block gets a table
+// of chained sha3 hashes seeded by the block's hash.
+// Trying a nonce takes MemHardReads dependent lookups
+// into the table, so it pays to keep the whole thing in memory
+type MemHardPow struct {
+	HashRate int64
+	turbo    bool
+}
+
+func memHardTable(hash []byte) [][]byte {
+	table := make([][]byte, MemHardWords)
+	table[0] = monkcrypto.Sha3Bin(hash)
+	for i := 1; i < len(table); i++ {
+		table[i] = monkcrypto.Sha3Bin(table[i-1])
+	}
+	return table
+}
+
+// Tables for the last few seeds. A block gets verified more than once
+// on its way in, and building a table is MemHardWords hashes
+var memHardTables = struct {
+	sync.Mutex
+	seeds  []string
+	tables map[string][][]byte
+}{tables: make(map[string][][]byte)}
+
+const memHardCached = 4
+
+func cachedMemHardTable(hash []byte) [][]byte {
+	c := &memHardTables
+	c.Lock()
+	defer c.Unlock()
+	seed := string(hash)
+	if table, ok := c.tables[seed]; ok && len(table) == MemHardWords {
+		return table
+	}
+	table := memHardTable(hash)
+	if _, ok := c.tables[seed]; !ok {
+		if len(c.seeds) == memHardCached {
+			delete(c.tables, c.seeds[0])
+			c.seeds = c.seeds[1:]
+		}
+		c.seeds = append(c.seeds, seed)
+	}
+	c.tables[seed] = table
+	return table
+}
+
+func memHardEval(table [][]byte, hash, nonce []byte) []byte {
+	mix := monkcrypto.Sha3Bin(append(append([]byte{}, hash...), nonce...))
+	for i := 0; i < MemHardReads; i++ {
+		j := binary.BigEndian.Uint32(mix[:4]) % uint32(len(table))
+		mix = monkcrypto.Sha3Bin(append(mix, table[j]...))
+	}
+	return mix
+}
+
+func (pow *MemHardPow) Search(block *Block, reactChan chan monkreact.Event) []byte {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	hash := block.HashNoNonce()
+	table := memHardTable(hash)
+	obj := powTarget(block.Difficulty)
+	i := int64(0)
+	start := time.Now()
+
+	for {
+		select {
+		case <-reactChan:
+			powlogger.Infoln("Breaking from mining")
+			return nil
+		default:
+			i++
+			pow.HashRate = int64(float64(i) / time.Since(start).Seconds())
+
+			nonce := monkcrypto.Sha3Bin(big.NewInt(r.Int63()).Bytes())
+			if monkutil.BigD(memHardEval(table, hash, nonce)).Cmp(obj) < 0 {
+				return nonce
+			}
+		}
+
+		if !pow.turbo {
+			time.Sleep(20 * time.Microsecond)
+		}
+	}
+}
+
+func (pow *MemHardPow) Verify(hash []byte, diff *big.Int, nonce []byte) bool {
+	res := memHardEval(cachedMemHardTable(hash), hash, nonce)
+	return monkutil.BigD(res).Cmp(powTarget(diff)) < 0
+}
+
+func (pow *MemHardPow) GetHashrate() int64 {
+	return pow.HashRate
+}
+
+func (pow *MemHardPow) Turbo(on bool) {
+	pow.turbo = on
+}
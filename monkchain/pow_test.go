@@ -0,0 +1,83 @@
+package monkchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkreact"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func testPowBlock() *Block {
+	return CreateBlock(nil, ZeroHash256, []byte("coinbase"), big.NewInt(4), nil, "")
+}
+
+func testPoW(t *testing.T, name string) {
+	pow, err := NewPoW(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pow.Turbo(true)
+
+	block := testPowBlock()
+	nonce := pow.Search(block, make(chan monkreact.Event))
+	if nonce == nil {
+		t.Fatalf("%s: no nonce found", name)
+	}
+	if !pow.Verify(block.HashNoNonce(), block.Difficulty, nonce) {
+		t.Fatalf("%s: nonce %x failed to verify", name, nonce)
+	}
+
+	// with an impossible difficulty the nonce is no good
+	if pow.Verify(block.HashNoNonce(), monkutil.BigPow(2, 255), nonce) {
+		t.Fatalf("%s: nonce %x verified at an impossible difficulty", name, nonce)
+	}
+}
+
+func TestPoWSha3(t *testing.T) {
+	testPoW(t, "sha3")
+}
+
+// dagger takes about a second per hash
+func TestPoWDagger(t *testing.T) {
+	if testing.Short() {
+		t.Skip("dagger is slow")
+	}
+	testPoW(t, "dagger")
+}
+
+func TestPoWMemHard(t *testing.T) {
+	testPoW(t, "memhard")
+}
+
+func TestPoWNone(t *testing.T) {
+	pow, _ := NewPoW("none")
+	block := testPowBlock()
+	nonce := pow.Search(block, make(chan monkreact.Event))
+	if !pow.Verify(block.HashNoNonce(), monkutil.BigPow(2, 255), nonce) {
+		t.Fatal("none should accept any nonce")
+	}
+}
+
+func TestPoWUnknown(t *testing.T) {
+	if _, err := NewPoW("scrypt"); err == nil {
+		t.Fatal("expected an error for an unknown algorithm")
+	}
+}
+
+func TestMemHardTableCache(t *testing.T) {
+	seed := []byte("seed")
+	table := cachedMemHardTable(seed)
+	if &cachedMemHardTable(seed)[0] != &table[0] {
+		t.Fatal("expected the table to be reused")
+	}
+	for i := 0; i < memHardCached; i++ {
+		cachedMemHardTable([]byte{byte(i)})
+	}
+	if len(memHardTables.seeds) != memHardCached {
+		t.Fatalf("expected %d cached tables, got %d", memHardCached, len(memHardTables.seeds))
+	}
+	if &cachedMemHardTable(seed)[0] == &table[0] {
+		t.Fatal("expected the oldest table to be evicted")
+	}
+}
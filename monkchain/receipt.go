@@ -0,0 +1,141 @@
+package monkchain
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+const (
+	TxFailed uint64 = iota
+	TxSucceeded
+)
+
+// What a tx did. Unlike Receipt this isn't part of the block,
+// it's written alongside it when the block is processed
+type TxReceipt struct {
+	TxHash            []byte
+	Status            uint64
+	GasUsed           *big.Int
+	CumulativeGasUsed *big.Int
+	// set if the tx created a contract
+	ContractAddress []byte
+	Output          []byte
+	Logs            monkstate.Logs
+	// the error from TransitionState, if it failed
+	Err string
+
+	// addresses the tx's messages went to or from, for the header bloom
+	addresses [][]byte
+}
+
+func NewTxReceiptFromValue(val *monkutil.Value) *TxReceipt {
+	r := &TxReceipt{}
+	r.TxHash = val.Get(0).Bytes()
+	r.Status = val.Get(1).Uint()
+	r.GasUsed = val.Get(2).BigInt()
+	r.CumulativeGasUsed = val.Get(3).BigInt()
+	r.ContractAddress = val.Get(4).Bytes()
+	r.Output = val.Get(5).Bytes()
+	r.Logs = monkstate.NewLogsFromValue(val.Get(6))
+	r.Err = val.Get(7).Str()
+	return r
+}
+
+func (r *TxReceipt) RlpData() []interface{} {
+	return []interface{}{r.TxHash, r.Status, r.GasUsed, r.CumulativeGasUsed, r.ContractAddress, r.Output, r.Logs.RlpData(), r.Err}
+}
+
+func (r *TxReceipt) Failed() bool {
+	return r.Status == TxFailed
+}
+
+func (r *TxReceipt) String() string {
+	return fmt.Sprintf("TxReceipt{tx: %x status: %d gas: %v cumulative: %v contract: %x output: %x logs: %d err: %q}", r.TxHash, r.Status, r.GasUsed, r.CumulativeGasUsed, r.ContractAddress, r.Output, len(r.Logs), r.Err)
+}
+
+func newTxReceipt(tx *Transaction, st *StateTransition, err error, gasUsed, cumulative *big.Int, logs monkstate.Logs) *TxReceipt {
+	r := &TxReceipt{
+		TxHash:            tx.Hash(),
+		Status:            TxSucceeded,
+		GasUsed:           gasUsed,
+		CumulativeGasUsed: cumulative,
+		Logs:              logs,
+	}
+	if err != nil {
+		r.Status = TxFailed
+		r.Err = err.Error()
+	} else if tx.CreatesContract() {
+		r.ContractAddress = tx.CreationAddress()
+	}
+	if st.msg != nil {
+		r.Output = st.msg.Output
+	}
+	return r
+}
+
+func messageAddresses(msgs monkstate.Messages) [][]byte {
+	addrs := make([][]byte, 0, 2*len(msgs))
+	for _, msg := range msgs {
+		addrs = append(addrs, msg.To, msg.From)
+	}
+	return addrs
+}
+
+// Receipts from the last run of the txs on this block
+func (block *Block) TxReceipts() []*TxReceipt {
+	return block.txReceipts
+}
+
+// All the logs in a block's receipts, in order
+func receiptLogs(receipts []*TxReceipt) monkstate.Logs {
+	var logs monkstate.Logs
+	for _, r := range receipts {
+		logs = append(logs, r.Logs...)
+	}
+	return logs
+}
+
+func txReceiptsKey(hash []byte) []byte {
+	return append(monkutil.CopyBytes(hash), []byte("Receipts")...)
+}
+
+func (sm *BlockManager) writeTxReceipts(block *Block) {
+	if len(block.txReceipts) == 0 {
+		return
+	}
+	data := make([]interface{}, len(block.txReceipts))
+	for i, r := range block.txReceipts {
+		data[i] = r.RlpData()
+	}
+	monkutil.Config.Db.Put(txReceiptsKey(block.Hash()), monkutil.Encode(data))
+}
+
+// Tx receipts of a processed block, in tx order
+func (bc *ChainManager) GetTxReceipts(hash []byte) []*TxReceipt {
+	data, _ := monkutil.Config.Db.Get(txReceiptsKey(hash))
+	if len(data) == 0 {
+		return nil
+	}
+	val := monkutil.NewValueFromBytes(data)
+	receipts := make([]*TxReceipt, val.Len())
+	for i := range receipts {
+		receipts[i] = NewTxReceiptFromValue(val.Get(i))
+	}
+	return receipts
+}
+
+// The receipt of a canonical tx, found through the tx index
+func (bc *ChainManager) GetReceipt(txHash []byte) *TxReceipt {
+	blockHash, i, ok := bc.TxLocation(txHash)
+	if !ok {
+		return nil
+	}
+	receipts := bc.GetTxReceipts(blockHash)
+	if i >= uint64(len(receipts)) {
+		return nil
+	}
+	return receipts[i]
+}
@@ -0,0 +1,57 @@
+package monkchain
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+)
+
+func TestTxReceipts(t *testing.T) {
+	initDB()
+	bc := newChainManager(nil, FakeDoug)
+	bman := &BlockManager{bc: bc}
+	keys := monkcrypto.GenerateNewKeyPair()
+
+	txs := Transactions{
+		NewTransactionMessage(keys.Address(), big.NewInt(1), big.NewInt(1000), big.NewInt(1), nil),
+		NewContractCreationTx(big.NewInt(0), big.NewInt(1000), big.NewInt(1), []byte{0x60, 0x01}),
+	}
+	receipts := make(Receipts, len(txs))
+	for i, tx := range txs {
+		tx.Nonce = uint64(i)
+		tx.Sign(keys.PrivateKey)
+		receipts[i] = &Receipt{tx, []byte("root"), big.NewInt(0)}
+	}
+	block := newBlockFromParent(keys.Address(), bc.Genesis())
+	block.SetReceipts(receipts, txs)
+
+	log := &monkstate.Log{Address: []byte("contract"), Topics: [][]byte{[]byte("a"), []byte("b")}, Data: []byte("data")}
+	block.txReceipts = []*TxReceipt{
+		newTxReceipt(txs[0], &StateTransition{}, NonceError(2, 0), big.NewInt(500), big.NewInt(500), nil),
+		newTxReceipt(txs[1], &StateTransition{msg: &monkstate.Message{Output: []byte("code")}}, nil, big.NewInt(300), big.NewInt(800), monkstate.Logs{log}),
+	}
+	bman.writeTxReceipts(block)
+	bc.add(block)
+
+	failed := bc.GetReceipt(txs[0].Hash())
+	if failed == nil || !failed.Failed() || failed.Err == "" || failed.GasUsed.Cmp(big.NewInt(500)) != 0 || len(failed.ContractAddress) != 0 {
+		t.Fatalf("bad receipt for failed tx: %v", failed)
+	}
+	created := bc.GetReceipt(txs[1].Hash())
+	if created == nil || created.Failed() || created.Err != "" || created.CumulativeGasUsed.Cmp(big.NewInt(800)) != 0 {
+		t.Fatalf("bad receipt for contract creation: %v", created)
+	}
+	if !bytes.Equal(created.ContractAddress, txs[1].CreationAddress()) || !bytes.Equal(created.Output, []byte("code")) {
+		t.Fatalf("bad contract address or output: %v", created)
+	}
+	if len(created.Logs) != 1 || !bytes.Equal(created.Logs[0].Address, log.Address) || len(created.Logs[0].Topics) != 2 || !bytes.Equal(created.Logs[0].Topics[1], []byte("b")) || !bytes.Equal(created.Logs[0].Data, log.Data) {
+		t.Fatalf("bad logs: %v", created.Logs)
+	}
+
+	if bc.GetReceipt([]byte("nothing")) != nil {
+		t.Fatal("expected no receipt for an unknown tx")
+	}
+}
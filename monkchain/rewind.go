@@ -0,0 +1,89 @@
+package monkchain
+
+import (
+	"fmt"
+
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+// Roll the canonical chain back so block n is the head.
+// Everything above n is dropped from the db and the indexes, and the
+// txs in those blocks are returned (oldest first) so they can go back
+// in the pool. Refuses to go below the latest checkpoint or final block
+func (bc *ChainManager) SetHead(n uint64) (Transactions, error) {
+	bc.chainMut.Lock()
+	defer bc.chainMut.Unlock()
+
+	head := bc.CurrentBlock()
+	if n > head.Number.Uint64() {
+		return nil, fmt.Errorf("Can't set head to #%d, we're only at #%d", n, head.Number)
+	}
+	if checkpoint := bc.LatestCheckPointNumber(); n < checkpoint {
+		return nil, &CheckpointErr{Message: fmt.Sprintf("Can't rewind to #%d, below checkpoint #%d", n, checkpoint), Number: checkpoint}
+	}
+	if final := bc.LatestFinalNumber(); n < final {
+		return nil, &FinalityErr{Message: fmt.Sprintf("Can't rewind to #%d, below final block #%d", n, final), Number: final}
+	}
+	target := bc.GetBlockByNumber(n)
+	if target == nil {
+		return nil, fmt.Errorf("Missing canonical block #%d", n)
+	}
+	td := bc.BlockInfo(target).TD
+
+	var (
+		txs   Transactions
+		batch = monkutil.Config.Db.NewBatch()
+	)
+	bc.mut.Lock()
+	for b := head; b.Number.Uint64() > n; b = bc.GetBlockCanonical(b.PrevHash) {
+		txs = append(b.Transactions(), txs...)
+		bc.unindexBlock(batch, b)
+		hash := b.Hash()
+		for _, suffix := range []string{"", "Info", "Receipts", "Hooks"} {
+			batch.Delete(append(monkutil.CopyBytes(hash), []byte(suffix)...))
+		}
+	}
+	batch.Put([]byte("LastBlock"), target.RlpEncode())
+	if err := batch.Write(); err != nil {
+		bc.mut.Unlock()
+		return nil, err
+	}
+
+	bc.currentBlock = target
+	bc.currentBlockHash = target.Hash()
+	bc.currentBlockNumber = n
+	// forks may hang off blocks we just dropped
+	bc.workingTree = make(map[string]*link)
+	bc.mut.Unlock()
+
+	bc.SetTotalDifficulty(td)
+
+	chainlogger.Infof("Rewound chain from #%v to #%d (%x). Dropped %d txs\n", head.Number, n, target.Hash()[:4], len(txs))
+	return txs, nil
+}
+
+// Rewind the chain to block n and reset our states on top of it.
+// Txs from the dropped blocks are queued again
+func (sm *BlockManager) SetHead(n uint64) error {
+	txs, err := sm.bc.SetHead(n)
+	if err != nil {
+		return err
+	}
+	head := sm.bc.CurrentBlock()
+
+	sm.mutex.Lock()
+	sm.state = head.State().Copy()
+	sm.miningState = head.State().Copy()
+	sm.transState = nil
+	sm.lastAttemptedBlock = nil
+	sm.mutex.Unlock()
+
+	// the queue blocks until the pool is running, so don't wait on it
+	go func() {
+		for _, tx := range txs {
+			sm.th.TxPool().QueueTransaction(tx)
+		}
+	}()
+	sm.th.Reactor().Post("chain:rewind", head)
+	return nil
+}
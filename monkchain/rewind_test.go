@@ -0,0 +1,59 @@
+package monkchain
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetHead(t *testing.T) {
+	initDB()
+	emptyDB(1)
+	bman, err := newCanonical(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := bman.bc
+	target := bc.GetBlockByNumber(5)
+	dropped := bc.GetBlockByNumber(8)
+
+	if _, err := bc.SetHead(11); err == nil {
+		t.Fatal("expected error setting head above the chain")
+	}
+
+	txs, err := bc.SetHead(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 0 {
+		t.Fatalf("expected no txs from the empty blocks, got %d", len(txs))
+	}
+	if bc.CurrentBlockNumber() != 5 || !bytes.Equal(bc.CurrentBlockHash(), target.Hash()) {
+		t.Fatalf("expected head at #5 (%x), got #%d (%x)", target.Hash(), bc.CurrentBlockNumber(), bc.CurrentBlockHash())
+	}
+	if bc.TD.Cmp(bc.BlockInfo(target).TD) != 0 {
+		t.Fatalf("expected td %v, got %v", bc.BlockInfo(target).TD, bc.TD)
+	}
+	if bc.CanonicalHash(6) != nil || bc.GetBlockByNumber(7) != nil || bc.HasBlock(dropped.Hash()) {
+		t.Fatal("expected blocks above #5 to be gone")
+	}
+
+	// can build on top again
+	lchain := makeChain(bman, target, 2)
+	if _, err := bc.TestChain(lchain); err != nil {
+		t.Fatal(err)
+	}
+	bc.InsertChain(lchain)
+	if bc.CurrentBlockNumber() != 7 {
+		t.Fatalf("expected head at #7 after rebuilding, got #%d", bc.CurrentBlockNumber())
+	}
+
+	// not below a checkpoint
+	bc.updateCheckpoint(bc.CanonicalHash(3))
+	if _, err := bc.SetHead(2); !IsCheckpointErr(err) {
+		t.Fatalf("expected a checkpoint error, got %v", err)
+	}
+	if bc.CurrentBlockNumber() != 7 {
+		t.Fatalf("expected head still at #7, got #%d", bc.CurrentBlockNumber())
+	}
+	setDB(0)
+}
@@ -12,7 +12,7 @@ import (
 type fDoug struct{}
 
 // Populate the state
-func (d *fDoug) Deploy(block *Block) []byte {
+func (d *fDoug) Deploy(block *Block) ([]byte, error) {
 	for _, acct := range [][]string{
 		[]string{"abc123", "9876"},
 		[]string{"321cba", "1234"},
@@ -23,20 +23,23 @@ func (d *fDoug) Deploy(block *Block) []byte {
 	}
 	block.State().Update()
 	block.State().Sync()
-	return nil
+	return nil, nil
 }
 
 func (d *fDoug) Doug() []byte { return nil }
 func (d *fDoug) ValidateChainID(chainId []byte, genBlock *Block) error {
 	return nil
 }
+func (d *fDoug) PoW() PoW {
+	return fakePow{}
+}
 
 func (d *fDoug) Participate(coinbase []byte, parent *Block) bool                     { return false }
 func (d *fDoug) Difficulty(block, parent *Block) *big.Int                            { return nil }
 func (d *fDoug) ValidatePerm(addr []byte, role string, state *monkstate.State) error { return nil }
 func (d *fDoug) ValidateBlock(block *Block, bc *ChainManager) error                  { return nil }
 func (d *fDoug) ValidateTx(tx *Transaction, state *monkstate.State) error            { return nil }
-func (d *fDoug) CheckPoint(proposed []byte, bc *ChainManager) bool                   { return false }
+func (d *fDoug) CheckPoint(cert *CheckpointCert, bc *ChainManager) bool              { return false }
 
 // Dead simple copy (key, value) pairs of a trie
 // Note this is not enough since some value's might be hashes of other tries
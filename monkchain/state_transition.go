@@ -175,13 +175,14 @@ func (self *StateTransition) TransitionState() (err error) {
 	sender.Nonce += 1
 
 	// Transaction gas
-	if err = self.UseGas(monkvm.GasTx); err != nil {
+	gasPrices := gasSchedule(self.state)
+	if err = self.UseGas(gasPrices.Tx); err != nil {
 		return
 	}
 
 	// Pay data gas
 	dataPrice := big.NewInt(int64(len(self.data)))
-	dataPrice.Mul(dataPrice, monkvm.GasData)
+	dataPrice.Mul(dataPrice, gasPrices.Data)
 	if err = self.UseGas(dataPrice); err != nil {
 		return
 	}
@@ -243,6 +244,13 @@ func (self *StateTransition) TransitionState() (err error) {
 
 		receiver.Code = code
 		msg.Output = code
+	} else if sys, ok := genDoug.(SystemTxs); ok && sys.IsSystemTx(tx, self.state) {
+		if err := sys.ApplySystemTx(tx, self.state, self.block); err != nil {
+			// hand back the value
+			self.transferValue(receiver, sender)
+
+			return fmt.Errorf("Error during system tx %v", err)
+		}
 	} else {
 		if len(receiver.Code) > 0 {
 			ret, err := self.Eval(msg, receiver.Code, receiver, "code")
@@ -286,6 +294,8 @@ func (self *StateTransition) Eval(msg *monkstate.Message, script []byte, context
 	vm := monkvm.New(env)
 	vm.Verbose = true
 	vm.Fn = typ
+	vm.SetGasSchedule(gasSchedule(state))
+	vm.SetLogs(logsEnabled(state))
 
 	ret, _, err = callerClosure.Call(vm, self.tx.Data)
 
@@ -316,3 +326,19 @@ func MakeContract(tx *Transaction, state *monkstate.State) *monkstate.StateObjec
 
 	return nil
 }
+
+// Gas prices for txs on top of state
+func gasSchedule(state *monkstate.State) *monkvm.GasSchedule {
+	if forks, ok := genDoug.(Forks); ok {
+		return forks.GasSchedule(state)
+	}
+	return monkvm.DefaultGasSchedule
+}
+
+// Chains with a fork schedule turn the LOG opcodes on with a fork
+func logsEnabled(state *monkstate.State) bool {
+	if forks, ok := genDoug.(Forks); ok {
+		return forks.Logs(state)
+	}
+	return true
+}
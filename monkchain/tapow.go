@@ -0,0 +1,59 @@
+package monkchain
+
+import (
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+// Transaction proof of work (TaPoW).
+// On chains without fees, a tx pays for its place in the pool and
+// in a block with work: sha3(sha3(hash ++ sender) ++ work) must beat
+// 2^256/difficulty, just like a block nonce. The sender is in there so
+// one solution can't be reused by fresh accounts sending the same body.
+// The signature doesn't cover the work
+
+// Optionally implemented by a Protocol that wants work on txs.
+// Returns nil if no work is required
+type TxWork interface {
+	TxWorkDifficulty(state *monkstate.State) *big.Int
+}
+
+// Difficulty from the genesis setting (a power of two, 0 for none)
+func TaPoWDifficulty(tapow uint64) *big.Int {
+	if tapow == 0 {
+		return nil
+	}
+	return monkutil.BigPow(2, int(tapow))
+}
+
+func (tx *Transaction) VerifyWork(diff *big.Int) bool {
+	if diff == nil || diff.Sign() <= 0 {
+		return true
+	}
+	return (&EasyPow{}).Verify(tx.workHash(tx.Sender()), diff, tx.Work)
+}
+
+func (tx *Transaction) workHash(sender []byte) []byte {
+	return monkcrypto.Sha3Bin(append(monkutil.CopyBytes(tx.Hash()), sender...))
+}
+
+// Find work for the tx from sender. It can be signed before or after
+func (tx *Transaction) DoWork(diff *big.Int, sender []byte) {
+	if diff == nil || diff.Sign() <= 0 {
+		return
+	}
+	pow := &EasyPow{}
+	hash := tx.workHash(sender)
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for {
+		tx.Work = monkcrypto.Sha3Bin(big.NewInt(r.Int63()).Bytes())
+		if pow.Verify(hash, diff, tx.Work) {
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package monkchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+var workDifficulty = big.NewInt(256)
+
+// Wants work on every tx
+type workDoug struct {
+	fakeDoug
+}
+
+func (d *workDoug) TxWorkDifficulty(state *monkstate.State) *big.Int { return workDifficulty }
+
+func (d *workDoug) ValidateTx(tx *Transaction, state *monkstate.State) error {
+	if !tx.VerifyWork(workDifficulty) {
+		return TxWorkError(tx.Hash(), workDifficulty)
+	}
+	return nil
+}
+
+type workEth struct {
+	fakeEth
+	bman *BlockManager
+}
+
+func (e *workEth) BlockManager() *BlockManager { return e.bman }
+func (e *workEth) ChainManager() *ChainManager { return e.bman.bc }
+func (e *workEth) Protocol() Protocol          { return &workDoug{} }
+
+func newWorkTx(keys *monkcrypto.KeyPair, work bool) *Transaction {
+	tx := NewTransactionMessage(ZeroHash160, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil)
+	if work {
+		tx.DoWork(workDifficulty, keys.Address())
+	}
+	tx.Sign(keys.PrivateKey)
+	return tx
+}
+
+func TestTxWork(t *testing.T) {
+	keys := monkcrypto.GenerateNewKeyPair()
+	tx := newWorkTx(keys, true)
+	if !tx.VerifyWork(workDifficulty) {
+		t.Fatal("work didn't verify")
+	}
+	// work survives the wire, and doesn't touch the signature
+	tx = NewTransactionFromBytes(tx.RlpEncode())
+	if !tx.VerifyWork(workDifficulty) || string(tx.Sender()) != string(keys.Address()) {
+		t.Fatal("bad decode of tx with work")
+	}
+	tx.Work = monkutil.CopyBytes(tx.Work)
+	tx.Work[0] ^= 0xff
+	if tx.VerifyWork(big.NewInt(1 << 20)) {
+		t.Fatal("expected tampered work to fail")
+	}
+	if !newWorkTx(keys, false).VerifyWork(nil) {
+		t.Fatal("no difficulty should need no work")
+	}
+
+	// the same body from another account can't reuse the work
+	diff := big.NewInt(1 << 16)
+	tx = NewTransactionMessage(ZeroHash160, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil)
+	tx.DoWork(diff, keys.Address())
+	tx.Sign(keys.PrivateKey)
+	other := NewTransactionMessage(ZeroHash160, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil)
+	other.Work = tx.Work
+	other.Sign(monkcrypto.GenerateNewKeyPair().PrivateKey)
+	if !tx.VerifyWork(diff) || other.VerifyWork(diff) {
+		t.Fatal("expected the work to be bound to its sender")
+	}
+}
+
+func TestTxWorkRejected(t *testing.T) {
+	initDB()
+	doug := &workDoug{}
+	bman := &BlockManager{bc: newChainManager(nil, doug), Pow: fakePow{}}
+	eth := &workEth{bman: bman}
+	bman.th = eth
+	bman.bc.SetProcessor(bman)
+
+	keys := monkcrypto.GenerateNewKeyPair()
+	lazy, worked := newWorkTx(keys, false), newWorkTx(keys, true)
+
+	pool := NewTxPool(eth)
+	if err := pool.ValidateTransaction(lazy); !IsTxWorkErr(err) {
+		t.Fatal("expected the pool to reject a tx without work, got", err)
+	}
+	if err := pool.ValidateTransaction(worked); err != nil {
+		t.Fatal(err)
+	}
+
+	// and blocks can't include it
+	parent := bman.bc.CurrentBlock()
+	block := newBlockFromParent([]byte("coinbase"), parent)
+	state := parent.State().Copy()
+	coinbase := state.GetOrNewStateObject(block.Coinbase)
+	coinbase.SetGasPool(block.CalcGasLimit(parent))
+	_, handled, _, err := bman.ProcessTransactions(coinbase, state, block, parent, Transactions{lazy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handled) != 0 {
+		t.Fatal("expected the tx without work to be left out of the block")
+	}
+}
@@ -2,8 +2,10 @@ package monkchain
 
 import (
 	"bytes"
+	"container/heap"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/eris-ltd/thelonious/monkcrypto"
 	"github.com/eris-ltd/thelonious/monkutil"
@@ -27,6 +29,9 @@ type Transaction struct {
 	v         byte
 	r, s      []byte
 
+	// Proof of work, if the chain wants it (see tapow.go)
+	Work []byte
+
 	// Indicates whether this tx is a contract creation transaction
 	contractCreation bool
 }
@@ -139,7 +144,12 @@ func (tx *Transaction) RlpData() interface{} {
 
 	// TODO Remove prefixing zero's
 
-	return append(data, tx.v, new(big.Int).SetBytes(tx.r).Bytes(), new(big.Int).SetBytes(tx.s).Bytes())
+	data = append(data, tx.v, new(big.Int).SetBytes(tx.r).Bytes(), new(big.Int).SetBytes(tx.s).Bytes())
+	// only txs with work carry it, so the rest encode as before
+	if len(tx.Work) > 0 {
+		data = append(data, tx.Work)
+	}
+	return data
 }
 
 func (tx *Transaction) RlpValue() *monkutil.Value {
@@ -165,6 +175,7 @@ func (tx *Transaction) RlpValueDecode(decoder *monkutil.Value) {
 
 	tx.r = decoder.Get(7).Bytes()
 	tx.s = decoder.Get(8).Bytes()
+	tx.Work = decoder.Get(9).Bytes()
 
 	if IsContractAddr(tx.Recipient) {
 		tx.contractCreation = true
@@ -255,3 +266,69 @@ type TxByNonce struct{ Transactions }
 func (s TxByNonce) Less(i, j int) bool {
 	return s.Transactions[i].Nonce < s.Transactions[j].Nonce
 }
+
+// A sender's txs in nonce order, and the hash of the next one
+type senderTxs struct {
+	txs  Transactions
+	hash []byte
+}
+
+func (s *senderTxs) pop() {
+	s.txs = s.txs[1:]
+	if len(s.txs) > 0 {
+		s.hash = s.txs[0].Hash()
+	}
+}
+
+// Heap of senders by the price of their next tx (hash breaks ties)
+type txHeads []*senderTxs
+
+func (h txHeads) Len() int      { return len(h) }
+func (h txHeads) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h txHeads) Less(i, j int) bool {
+	if cmp := h[i].txs[0].GasPrice.Cmp(h[j].txs[0].GasPrice); cmp != 0 {
+		return cmp > 0
+	}
+	return bytes.Compare(h[i].hash, h[j].hash) < 0
+}
+func (h *txHeads) Push(x interface{}) { *h = append(*h, x.(*senderTxs)) }
+func (h *txHeads) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Order txs by gas price, highest first, without
+// putting any sender's txs out of nonce order.
+// Each sender's next tx competes on its own price
+func SortByPriceAndNonce(txs Transactions) Transactions {
+	bySender := make(map[string]*senderTxs)
+	for _, tx := range txs {
+		sender := string(tx.Sender())
+		if bySender[sender] == nil {
+			bySender[sender] = &senderTxs{}
+		}
+		bySender[sender].txs = append(bySender[sender].txs, tx)
+	}
+	heads := make(txHeads, 0, len(bySender))
+	for _, s := range bySender {
+		sort.Sort(TxByNonce{s.txs})
+		s.hash = s.txs[0].Hash()
+		heads = append(heads, s)
+	}
+	heap.Init(&heads)
+
+	sorted := make(Transactions, 0, len(txs))
+	for heads.Len() > 0 {
+		best := heads[0]
+		sorted = append(sorted, best.txs[0])
+		best.pop()
+		if len(best.txs) > 0 {
+			heap.Fix(&heads, 0)
+		} else {
+			heap.Pop(&heads)
+		}
+	}
+	return sorted
+}
@@ -99,8 +99,15 @@ func (pool *TxPool) ValidateTransaction(tx *Transaction) error {
 		return fmt.Errorf("[TXPL] Invalid recipient. len = %d", len(tx.Recipient))
 	}
 
-	if tx.GasPrice.Cmp(MinGasPrice) < 0 {
-		return fmt.Errorf("Gas price to low. Require %v > Got %v", MinGasPrice, tx.GasPrice)
+	if min := pool.MinGasPrice(); tx.GasPrice.Cmp(min) < 0 {
+		return GasPriceError(tx.GasPrice, min)
+	}
+
+	if work, ok := pool.Thelonious.Protocol().(TxWork); ok {
+		diff := work.TxWorkDifficulty(pool.Thelonious.BlockManager().CurrentState())
+		if !tx.VerifyWork(diff) {
+			return TxWorkError(tx.Hash(), diff)
+		}
 	}
 
 	// Get the sender
@@ -124,6 +131,18 @@ func (pool *TxPool) ValidateTransaction(tx *Transaction) error {
 	return nil
 }
 
+// The fee floor: the chain's min gas price, or our own if it's higher
+func (pool *TxPool) MinGasPrice() *big.Int {
+	min := new(big.Int).Set(MinGasPrice)
+	if fees, ok := pool.Thelonious.Protocol().(FeePolicy); ok {
+		state := pool.Thelonious.BlockManager().CurrentState()
+		if chainMin := fees.MinGasPrice(state); chainMin != nil && chainMin.Cmp(min) > 0 {
+			min.Set(chainMin)
+		}
+	}
+	return min
+}
+
 func (pool *TxPool) queueHandler() {
 out:
 	for {
@@ -1 +1,70 @@
 package monkchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkcrypto"
+)
+
+func TestSortByPriceAndNonce(t *testing.T) {
+	alice, bob := monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair()
+	newTx := func(keys *monkcrypto.KeyPair, nonce uint64, price int64) *Transaction {
+		tx := NewTransactionMessage(ZeroHash160, big.NewInt(0), big.NewInt(100), big.NewInt(price), nil)
+		tx.Nonce = nonce
+		tx.Sign(keys.PrivateKey)
+		return tx
+	}
+	// alice's cheap tx holds up her expensive one
+	txs := Transactions{
+		newTx(alice, 1, 50),
+		newTx(bob, 0, 10),
+		newTx(alice, 0, 1),
+		newTx(bob, 1, 20),
+	}
+	sorted := SortByPriceAndNonce(txs)
+	expected := []struct {
+		sender []byte
+		nonce  uint64
+	}{{bob.Address(), 0}, {bob.Address(), 1}, {alice.Address(), 0}, {alice.Address(), 1}}
+	if len(sorted) != len(expected) {
+		t.Fatalf("expected %d txs, got %d", len(expected), len(sorted))
+	}
+	for i, e := range expected {
+		if string(sorted[i].Sender()) != string(e.sender) || sorted[i].Nonce != e.nonce {
+			t.Fatalf("tx %d is %x #%d, expected %x #%d", i, sorted[i].Sender(), sorted[i].Nonce, e.sender, e.nonce)
+		}
+	}
+}
+
+func TestSortByPriceAndNonceMany(t *testing.T) {
+	txs := Transactions{}
+	for i := 0; i < 8; i++ {
+		keys := monkcrypto.GenerateNewKeyPair()
+		for n := 0; n < 5; n++ {
+			tx := NewTransactionMessage(ZeroHash160, big.NewInt(0), big.NewInt(100), big.NewInt(int64((i*7+n*13)%11)), nil)
+			tx.Nonce = uint64(4 - n)
+			tx.Sign(keys.PrivateKey)
+			txs = append(txs, tx)
+		}
+	}
+	sorted := SortByPriceAndNonce(txs)
+	if len(sorted) != len(txs) {
+		t.Fatalf("expected %d txs, got %d", len(txs), len(sorted))
+	}
+	// every sender's txs in nonce order, and every pick the
+	// best of what was available at the time
+	next := make(map[string]uint64)
+	for i, tx := range sorted {
+		sender := string(tx.Sender())
+		if tx.Nonce != next[sender] {
+			t.Fatalf("tx %d has nonce %d, expected %d", i, tx.Nonce, next[sender])
+		}
+		next[sender]++
+		for _, other := range sorted[i+1:] {
+			if other.Nonce == next[string(other.Sender())] && string(other.Sender()) != sender && other.GasPrice.Cmp(tx.GasPrice) > 0 {
+				t.Fatalf("tx %d (price %v) picked over an available tx with price %v", i, tx.GasPrice, other.GasPrice)
+			}
+		}
+	}
+}
@@ -41,6 +41,10 @@ func (db *LDBDatabase) Delete(key []byte) error {
 	return db.db.Delete(key, nil)
 }
 
+func (db *LDBDatabase) NewBatch() monkutil.Batch {
+	return &ldbBatch{db: db.db, batch: new(leveldb.Batch)}
+}
+
 func (db *LDBDatabase) Db() *leveldb.DB {
 	return db.db
 }
@@ -71,3 +75,22 @@ func (db *LDBDatabase) Print() {
 		fmt.Printf("%v\n", node)
 	}
 }
+
+type ldbBatch struct {
+	db    *leveldb.DB
+	batch *leveldb.Batch
+}
+
+func (b *ldbBatch) Put(key []byte, value []byte) {
+	b.batch.Put(key, value)
+}
+
+func (b *ldbBatch) Delete(key []byte) {
+	b.batch.Delete(key)
+}
+
+func (b *ldbBatch) Write() error {
+	err := b.db.Write(b.batch, nil)
+	b.batch.Reset()
+	return err
+}
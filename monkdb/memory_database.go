@@ -40,6 +40,10 @@ func (db *MemDatabase) Delete(key []byte) error {
 	return nil
 }
 
+func (db *MemDatabase) NewBatch() monkutil.Batch {
+	return monkutil.NewQueuedBatch(db)
+}
+
 func (db *MemDatabase) Print() {
 	for key, val := range db.db {
 		fmt.Printf("%x(%d): ", key, len(key))
@@ -0,0 +1,309 @@
+package monkdoug
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+	"github.com/eris-ltd/thelonious/monkwire"
+	"github.com/obscuren/secp256k1-go"
+)
+
+// The bft model replaces proof of work with tendermint style rounds.
+// Validators are the genesis accounts with the "validate" permission.
+// Each round a proposer broadcasts a block, validators prevote and
+// then precommit on it, and a block with +2/3 precommits is committed
+// and final. Difficulty is constant, so fork choice never matters
+// above the final block and the ChainManager refuses forks below it.
+type BftModel struct {
+	g          *GenesisConfig
+	validators [][]byte
+}
+
+func NewBftModel(g *GenesisConfig) monkchain.Consensus {
+	validators := [][]byte{}
+	for _, acc := range g.Accounts {
+		if acc.Permissions["validate"] != 0 {
+			validators = append(validators, acc.byteAddr)
+		}
+	}
+	return &BftModel{g, validators}
+}
+
+func (m *BftModel) Validators() [][]byte {
+	return m.validators
+}
+
+func (m *BftModel) IsValidator(addr []byte) bool {
+	for _, v := range m.validators {
+		if bytes.Equal(v, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Votes needed for a quorum (more than two thirds)
+func (m *BftModel) Quorum() int {
+	return 2*len(m.validators)/3 + 1
+}
+
+// Proposers take turns by height and round
+func (m *BftModel) Proposer(height, round uint64) []byte {
+	if len(m.validators) == 0 {
+		return nil
+	}
+	return m.validators[(height+round)%uint64(len(m.validators))]
+}
+
+func (m *BftModel) Participate(coinbase []byte, parent *monkchain.Block) bool {
+	return m.IsValidator(coinbase)
+}
+
+func (m *BftModel) Difficulty(block, parent *monkchain.Block) *big.Int {
+	return big.NewInt(1)
+}
+
+// Only validators may produce blocks. Everything else is open
+func (m *BftModel) ValidatePerm(addr []byte, role string, state *monkstate.State) error {
+	switch role {
+	case "mine", "validate":
+		if !m.IsValidator(addr) {
+			return monkchain.InvalidPermError(addr, role)
+		}
+	}
+	return nil
+}
+
+func (m *BftModel) ValidateBlock(block *monkchain.Block, bc *monkchain.ChainManager) error {
+	// we have to verify using the state of the previous block!
+	prevBlock := bc.GetBlock(block.PrevHash)
+
+	// check that signature of block matches proposers coinbase
+	if !bytes.Equal(block.Signer(), block.Coinbase) {
+		return monkchain.InvalidSigError(block.Signer(), block.Coinbase)
+	}
+
+	// check that the proposer is a validator
+	if !m.IsValidator(block.Coinbase) {
+		return monkchain.InvalidPermError(block.Coinbase, "validate")
+	}
+
+	// difficulty is constant
+	newdiff := m.Difficulty(block, prevBlock)
+	if block.Difficulty.Cmp(newdiff) != 0 {
+		return monkchain.InvalidDifficultyError(block.Difficulty, newdiff, block.Coinbase)
+	}
+
+	// check block times
+	if err := m.g.CheckBlockTimes(bc, prevBlock, block); err != nil {
+		return err
+	}
+
+	// blocks only go on committed blocks, so no single
+	// validator can grow a chain the others haven't signed
+	if prevBlock.Number.Sign() > 0 && !bc.HasCommit(prevBlock.Hash()) {
+		return monkchain.ValidationError("Parent %x of block #%v is not committed", prevBlock.Hash()[:4], block.Number)
+	}
+
+	return nil
+}
+
+func (m *BftModel) ValidateTx(tx *monkchain.Transaction, state *monkstate.State) error {
+	if diff := m.TxWorkDifficulty(state); !tx.VerifyWork(diff) {
+		return monkchain.TxWorkError(tx.Hash(), diff)
+	}
+	// Make sure this transaction's nonce is correct
+	sender := state.GetOrNewStateObject(tx.Sender())
+	if sender.Nonce != tx.Nonce {
+		return monkchain.NonceError(tx.Nonce, sender.Nonce)
+	}
+	return nil
+}
+
+func (m *BftModel) TxWorkDifficulty(state *monkstate.State) *big.Int {
+	return genesisTaPoW(m.g)
+}
+
+// Final blocks make for checkpoints, as do blocks
+// certified by a quorum of validators
+func (m *BftModel) CheckPoint(cert *monkchain.CheckpointCert, bc *monkchain.ChainManager) bool {
+	return bc.IsFinal(cert.Hash) || certified(cert, m.Quorum(), m.IsValidator)
+}
+
+// A commit must hold precommits from a quorum of
+// distinct validators, all for the same block and round
+func (m *BftModel) VerifyCommit(data *monkutil.Value, bc *monkchain.ChainManager) ([]byte, uint64, error) {
+	c := NewBftCommitFromValue(data)
+	if len(c.BlockHash) == 0 {
+		return nil, 0, fmt.Errorf("Commit for height %d has no block", c.Height)
+	}
+	signers := make(map[string]bool)
+	for _, vote := range c.Precommits {
+		if vote.Height != c.Height || vote.Round != c.Round || !bytes.Equal(vote.BlockHash, c.BlockHash) {
+			return nil, 0, fmt.Errorf("Commit for %x has a precommit for something else: %v", c.BlockHash, vote)
+		}
+		signer := vote.Signer()
+		if !m.IsValidator(signer) {
+			return nil, 0, fmt.Errorf("Commit for %x has a precommit from non-validator %x", c.BlockHash, signer)
+		}
+		signers[string(signer)] = true
+	}
+	if len(signers) < m.Quorum() {
+		return nil, 0, fmt.Errorf("Commit for %x has %d precommits, need %d", c.BlockHash, len(signers), m.Quorum())
+	}
+	return c.BlockHash, c.Height, nil
+}
+
+/*
+   Consensus messages
+*/
+
+// The +2/3 precommits that committed a block. Gossiped after
+// the block so everyone, not just validators, can finalize it
+type BftCommit struct {
+	Height     uint64
+	Round      uint64
+	BlockHash  []byte
+	Precommits []*BftVote
+}
+
+func NewBftCommit(block *monkchain.Block, precommits []*BftVote) *BftCommit {
+	c := &BftCommit{Height: block.Number.Uint64(), BlockHash: block.Hash(), Precommits: precommits}
+	if len(precommits) > 0 {
+		c.Round = precommits[0].Round
+	}
+	return c
+}
+
+func NewBftCommitFromValue(data *monkutil.Value) *BftCommit {
+	c := &BftCommit{}
+	c.Height = data.Get(0).Uint()
+	c.Round = data.Get(1).Uint()
+	c.BlockHash = data.Get(2).Bytes()
+	votes := data.Get(3)
+	for i := 0; i < votes.Len(); i++ {
+		c.Precommits = append(c.Precommits, NewBftVoteFromValue(monkwire.MsgBftPrecommitTy, votes.Get(i)))
+	}
+	return c
+}
+
+func (c *BftCommit) RlpData() []interface{} {
+	votes := make([]interface{}, len(c.Precommits))
+	for i, vote := range c.Precommits {
+		votes[i] = vote.RlpData()
+	}
+	return []interface{}{c.Height, c.Round, c.BlockHash, votes}
+}
+
+// A signed prevote or precommit for a block hash (nil hash is a vote for nothing)
+type BftVote struct {
+	Type      monkwire.MsgType
+	Height    uint64
+	Round     uint64
+	BlockHash []byte
+
+	v    byte
+	r, s []byte
+}
+
+func NewBftVote(typ monkwire.MsgType, height, round uint64, blockHash []byte) *BftVote {
+	return &BftVote{Type: typ, Height: height, Round: round, BlockHash: blockHash}
+}
+
+func NewBftVoteFromValue(typ monkwire.MsgType, data *monkutil.Value) *BftVote {
+	vote := &BftVote{Type: typ}
+	vote.Height = data.Get(0).Uint()
+	vote.Round = data.Get(1).Uint()
+	vote.BlockHash = data.Get(2).Bytes()
+	vote.v = byte(data.Get(3).Uint())
+	vote.r = data.Get(4).Bytes()
+	vote.s = data.Get(5).Bytes()
+	return vote
+}
+
+func (vote *BftVote) Hash() []byte {
+	data := []interface{}{uint64(vote.Type), vote.Height, vote.Round, vote.BlockHash}
+	return monkcrypto.Sha3Bin(monkutil.NewValue(data).Encode())
+}
+
+func (vote *BftVote) Sign(privk []byte) {
+	vote.r, vote.s, vote.v = bftSign(vote.Hash(), privk)
+}
+
+func (vote *BftVote) Signer() []byte {
+	return bftSigner(vote.Hash(), vote.r, vote.s, vote.v)
+}
+
+func (vote *BftVote) RlpData() []interface{} {
+	return []interface{}{vote.Height, vote.Round, vote.BlockHash, vote.v, vote.r, vote.s}
+}
+
+func (vote *BftVote) String() string {
+	return fmt.Sprintf("%v %d/%d %x", vote.Type, vote.Height, vote.Round, vote.BlockHash)
+}
+
+// A signed block proposal for a height and round
+type BftProposal struct {
+	Height uint64
+	Round  uint64
+	Block  *monkchain.Block
+
+	v    byte
+	r, s []byte
+}
+
+func NewBftProposal(height, round uint64, block *monkchain.Block) *BftProposal {
+	return &BftProposal{Height: height, Round: round, Block: block}
+}
+
+func NewBftProposalFromValue(data *monkutil.Value) *BftProposal {
+	p := &BftProposal{}
+	p.Height = data.Get(0).Uint()
+	p.Round = data.Get(1).Uint()
+	p.Block = monkchain.NewBlockFromRlpValue(data.Get(2))
+	p.v = byte(data.Get(3).Uint())
+	p.r = data.Get(4).Bytes()
+	p.s = data.Get(5).Bytes()
+	return p
+}
+
+func (p *BftProposal) Hash() []byte {
+	data := []interface{}{p.Height, p.Round, p.Block.Hash()}
+	return monkcrypto.Sha3Bin(monkutil.NewValue(data).Encode())
+}
+
+func (p *BftProposal) Sign(privk []byte) {
+	p.r, p.s, p.v = bftSign(p.Hash(), privk)
+}
+
+func (p *BftProposal) Signer() []byte {
+	return bftSigner(p.Hash(), p.r, p.s, p.v)
+}
+
+func (p *BftProposal) RlpData() []interface{} {
+	return []interface{}{p.Height, p.Round, p.Block.Value().Val, p.v, p.r, p.s}
+}
+
+func bftSign(hash, privk []byte) (r, s []byte, v byte) {
+	sig, _ := secp256k1.Sign(hash, privk)
+	return sig[:32], sig[32:64], sig[64] + 27
+}
+
+func bftSigner(hash, r, s []byte, v byte) []byte {
+	if len(r) == 0 || len(s) == 0 {
+		return nil
+	}
+	sig := append(monkutil.LeftPadBytes(r, 32), monkutil.LeftPadBytes(s, 32)...)
+	sig = append(sig, v-27)
+
+	pubkey, _ := secp256k1.RecoverPubkey(hash, sig)
+	if len(pubkey) == 0 || pubkey[0] != 4 {
+		return nil
+	}
+	return monkcrypto.Sha3Bin(pubkey[1:])[12:]
+}
@@ -0,0 +1,474 @@
+package monkdoug
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkutil"
+	"github.com/eris-ltd/thelonious/monkwire"
+)
+
+// Default timeout for each step of a round. Timeouts grow with the round
+var BftTimeout = 3 * time.Second
+
+// The node a BftState drives.
+// Implemented by the bft miner for a running node
+type BftApp interface {
+	// Create and sign a new block on top of the current head
+	Propose(height uint64) *monkchain.Block
+	// Check a proposed block against the current head
+	Validate(block *monkchain.Block) error
+	// Commit a block with +2/3 precommits. It is final
+	Commit(block *monkchain.Block, precommits []*BftVote) error
+	// Send a consensus message to the other validators
+	Broadcast(msgType monkwire.MsgType, data []interface{})
+}
+
+// Steps of a round
+const (
+	BftProposeStep = iota
+	BftPrevoteStep
+	BftPrecommitStep
+)
+
+type bftMsg struct {
+	proposal *BftProposal
+	vote     *BftVote
+}
+
+type bftTimeout struct {
+	height, round uint64
+	step          int
+}
+
+// Votes for a single round, by block hash
+type bftVoteSet struct {
+	votes  map[string]*BftVote // by validator
+	counts map[string]int      // by block hash
+}
+
+func newBftVoteSet() *bftVoteSet {
+	return &bftVoteSet{make(map[string]*BftVote), make(map[string]int)}
+}
+
+// Add a vote. False if the validator already voted this round
+func (vs *bftVoteSet) add(addr []byte, vote *BftVote) bool {
+	if _, ok := vs.votes[string(addr)]; ok {
+		return false
+	}
+	vs.votes[string(addr)] = vote
+	vs.counts[string(vote.BlockHash)]++
+	return true
+}
+
+// The block hash (possibly nil) with at least n votes
+func (vs *bftVoteSet) majority(n int) ([]byte, bool) {
+	for hash, count := range vs.counts {
+		if count >= n {
+			return []byte(hash), true
+		}
+	}
+	return nil, false
+}
+
+func (vs *bftVoteSet) forHash(hash []byte) []*BftVote {
+	votes := []*BftVote{}
+	for _, vote := range vs.votes {
+		if bytes.Equal(vote.BlockHash, hash) {
+			votes = append(votes, vote)
+		}
+	}
+	return votes
+}
+
+// Tendermint style round state for one validator.
+// All state is owned by the loop goroutine. Messages from peers
+// come in through Receive, our own go through the internal queue
+type BftState struct {
+	model *BftModel
+	app   BftApp
+	keys  *monkcrypto.KeyPair
+
+	Timeout time.Duration
+
+	height uint64
+	round  uint64
+	step   int
+
+	// proposed blocks for this height, by hash and by round
+	blocks    map[string]*monkchain.Block
+	proposals map[uint64]*monkchain.Block
+
+	// once we precommit a block we are locked on it and prevote
+	// it in later rounds, until we see +2/3 prevote for something else
+	lockedRound int64
+	lockedBlock *monkchain.Block
+
+	// the latest block we saw +2/3 prevotes for.
+	// We propose it again so locked validators can make progress
+	validRound int64
+	validBlock *monkchain.Block
+
+	prevotes   map[uint64]*bftVoteSet
+	precommits map[uint64]*bftVoteSet
+
+	// messages for later heights
+	future []*bftMsg
+	// our own messages
+	queue []*bftMsg
+
+	msgs     chan *bftMsg
+	timeouts chan bftTimeout
+	quit     chan bool
+}
+
+func NewBftState(model *BftModel, app BftApp, keys *monkcrypto.KeyPair, height uint64) *BftState {
+	s := &BftState{
+		model:    model,
+		app:      app,
+		keys:     keys,
+		Timeout:  BftTimeout,
+		height:   height,
+		msgs:     make(chan *bftMsg, 256),
+		timeouts: make(chan bftTimeout, 16),
+		quit:     make(chan bool),
+	}
+	s.resetHeight()
+	return s
+}
+
+func (s *BftState) Start() {
+	s.enterNewRound(0)
+	go s.loop()
+}
+
+func (s *BftState) Stop() {
+	close(s.quit)
+}
+
+// Hand a consensus message from a peer to the state machine
+func (s *BftState) Receive(msgType monkwire.MsgType, data *monkutil.Value) {
+	var m *bftMsg
+	switch msgType {
+	case monkwire.MsgBftProposalTy:
+		m = &bftMsg{proposal: NewBftProposalFromValue(data)}
+	case monkwire.MsgBftPrevoteTy, monkwire.MsgBftPrecommitTy:
+		m = &bftMsg{vote: NewBftVoteFromValue(msgType, data)}
+	default:
+		return
+	}
+	select {
+	case s.msgs <- m:
+	case <-s.quit:
+	}
+}
+
+func (s *BftState) loop() {
+	for {
+		for len(s.queue) > 0 {
+			m := s.queue[0]
+			s.queue = s.queue[1:]
+			s.handle(m)
+		}
+		select {
+		case m := <-s.msgs:
+			s.handle(m)
+		case t := <-s.timeouts:
+			s.handleTimeout(t)
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *BftState) resetHeight() {
+	s.round = 0
+	s.step = BftProposeStep
+	s.blocks = make(map[string]*monkchain.Block)
+	s.proposals = make(map[uint64]*monkchain.Block)
+	s.lockedRound = -1
+	s.lockedBlock = nil
+	s.validRound = -1
+	s.validBlock = nil
+	s.prevotes = make(map[uint64]*bftVoteSet)
+	s.precommits = make(map[uint64]*bftVoteSet)
+}
+
+func (s *BftState) scheduleTimeout(step int) {
+	t := bftTimeout{s.height, s.round, step}
+	d := s.Timeout * time.Duration(s.round+1)
+	time.AfterFunc(d, func() {
+		select {
+		case s.timeouts <- t:
+		case <-s.quit:
+		}
+	})
+}
+
+func (s *BftState) handleTimeout(t bftTimeout) {
+	if t.height != s.height || t.round != s.round || t.step != s.step {
+		return
+	}
+	switch t.step {
+	case BftProposeStep:
+		s.enterPrevote()
+	case BftPrevoteStep:
+		s.enterPrecommit()
+	case BftPrecommitStep:
+		s.enterNewRound(s.round + 1)
+	}
+}
+
+func (s *BftState) handle(m *bftMsg) {
+	var height uint64
+	if m.proposal != nil {
+		height = m.proposal.Height
+	} else {
+		height = m.vote.Height
+	}
+	switch {
+	case height > s.height:
+		s.future = append(s.future, m)
+		return
+	case height < s.height:
+		return
+	}
+
+	if m.proposal != nil {
+		s.handleProposal(m.proposal)
+	} else {
+		s.handleVote(m.vote)
+	}
+}
+
+func (s *BftState) enterNewRound(round uint64) {
+	s.round = round
+	s.step = BftProposeStep
+	douglogger.Debugf("BFT: new round %d/%d\n", s.height, s.round)
+
+	if bytes.Equal(s.model.Proposer(s.height, s.round), s.keys.Address()) {
+		block := s.validBlock
+		if block == nil {
+			block = s.app.Propose(s.height)
+		}
+		if block != nil {
+			p := NewBftProposal(s.height, s.round, block)
+			p.Sign(s.keys.PrivateKey)
+			s.app.Broadcast(monkwire.MsgBftProposalTy, p.RlpData())
+			s.queue = append(s.queue, &bftMsg{proposal: p})
+		}
+	}
+	s.scheduleTimeout(BftProposeStep)
+
+	// the proposal or votes for this round may have beaten us here
+	if s.proposals[s.round] != nil {
+		s.enterPrevote()
+	}
+	s.checkPrecommits()
+}
+
+func (s *BftState) handleProposal(p *BftProposal) {
+	if _, ok := s.proposals[p.Round]; ok {
+		return
+	}
+	proposer := s.model.Proposer(p.Height, p.Round)
+	if !bytes.Equal(p.Signer(), proposer) {
+		douglogger.Debugf("BFT: proposal %d/%d not signed by proposer %x\n", p.Height, p.Round, proposer)
+		return
+	}
+	if p.Block.Number == nil || p.Block.Number.Uint64() != p.Height {
+		return
+	}
+	s.proposals[p.Round] = p.Block
+	s.blocks[string(p.Block.Hash())] = p.Block
+
+	if p.Round == s.round && s.step == BftProposeStep {
+		s.enterPrevote()
+	}
+	// we may have been waiting on the block
+	s.checkPolka(p.Round)
+	s.checkPrecommits()
+}
+
+func (s *BftState) enterPrevote() {
+	s.step = BftPrevoteStep
+
+	var hash []byte
+	if block := s.proposals[s.round]; block != nil {
+		if s.lockedBlock != nil && !bytes.Equal(s.lockedBlock.Hash(), block.Hash()) && !s.hasPolkaSince(block.Hash(), s.lockedRound) {
+			// stick with our lock
+			hash = s.lockedBlock.Hash()
+		} else if err := s.app.Validate(block); err != nil {
+			douglogger.Infoln("BFT: invalid proposal:", err)
+		} else {
+			hash = block.Hash()
+		}
+	} else if s.lockedBlock != nil {
+		hash = s.lockedBlock.Hash()
+	}
+	s.vote(monkwire.MsgBftPrevoteTy, hash)
+	s.scheduleTimeout(BftPrevoteStep)
+
+	// prevotes may already be in
+	s.checkPrevotes()
+}
+
+func (s *BftState) enterPrecommit() {
+	s.step = BftPrecommitStep
+
+	var hash []byte
+	if h, ok := s.votes(s.prevotes, s.round).majority(s.model.Quorum()); ok {
+		if len(h) == 0 {
+			// +2/3 prevoted nil. Unlock
+			s.lockedRound = -1
+			s.lockedBlock = nil
+		} else if block, ok := s.blocks[string(h)]; ok {
+			// +2/3 prevoted a block we have. Lock on it
+			s.lockedRound = int64(s.round)
+			s.lockedBlock = block
+			hash = h
+		}
+	}
+	s.vote(monkwire.MsgBftPrecommitTy, hash)
+	s.scheduleTimeout(BftPrecommitStep)
+}
+
+// Is there a round after the given one where +2/3 prevoted this block
+func (s *BftState) hasPolkaSince(hash []byte, round int64) bool {
+	for r, vs := range s.prevotes {
+		if int64(r) <= round {
+			continue
+		}
+		if h, ok := vs.majority(s.model.Quorum()); ok && bytes.Equal(h, hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// Track the latest block with +2/3 prevotes
+func (s *BftState) checkPolka(round uint64) {
+	if int64(round) <= s.validRound {
+		return
+	}
+	hash, ok := s.votes(s.prevotes, round).majority(s.model.Quorum())
+	if !ok || len(hash) == 0 {
+		return
+	}
+	if block, ok := s.blocks[string(hash)]; ok {
+		s.validRound = int64(round)
+		s.validBlock = block
+	}
+}
+
+func (s *BftState) vote(typ monkwire.MsgType, hash []byte) {
+	vote := NewBftVote(typ, s.height, s.round, hash)
+	vote.Sign(s.keys.PrivateKey)
+	s.app.Broadcast(typ, vote.RlpData())
+	s.queue = append(s.queue, &bftMsg{vote: vote})
+}
+
+func (s *BftState) votes(sets map[uint64]*bftVoteSet, round uint64) *bftVoteSet {
+	vs, ok := sets[round]
+	if !ok {
+		vs = newBftVoteSet()
+		sets[round] = vs
+	}
+	return vs
+}
+
+func (s *BftState) handleVote(vote *BftVote) {
+	signer := vote.Signer()
+	if !s.model.IsValidator(signer) {
+		douglogger.Debugf("BFT: vote from non-validator %x\n", signer)
+		return
+	}
+
+	switch vote.Type {
+	case monkwire.MsgBftPrevoteTy:
+		if !s.votes(s.prevotes, vote.Round).add(signer, vote) {
+			return
+		}
+		s.checkPolka(vote.Round)
+		if vote.Round == s.round {
+			s.checkPrevotes()
+		}
+	case monkwire.MsgBftPrecommitTy:
+		if !s.votes(s.precommits, vote.Round).add(signer, vote) {
+			return
+		}
+		s.checkPrecommits()
+	default:
+		return
+	}
+
+	// if more than a third of the validators are in a later round,
+	// we are behind and should skip ahead
+	if vote.Round > s.round && s.votersIn(vote.Round) > len(s.model.Validators())/3 {
+		s.enterNewRound(vote.Round)
+	}
+}
+
+// Number of validators with a vote in the round
+func (s *BftState) votersIn(round uint64) int {
+	voters := make(map[string]bool)
+	for addr := range s.votes(s.prevotes, round).votes {
+		voters[addr] = true
+	}
+	for addr := range s.votes(s.precommits, round).votes {
+		voters[addr] = true
+	}
+	return len(voters)
+}
+
+func (s *BftState) checkPrevotes() {
+	if s.step != BftPrevoteStep {
+		return
+	}
+	if _, ok := s.votes(s.prevotes, s.round).majority(s.model.Quorum()); ok {
+		s.enterPrecommit()
+	}
+}
+
+func (s *BftState) checkPrecommits() {
+	// a block with +2/3 precommits in any round is committed
+	for round, vs := range s.precommits {
+		hash, ok := vs.majority(s.model.Quorum())
+		if !ok || len(hash) == 0 {
+			continue
+		}
+		if block, ok := s.blocks[string(hash)]; ok {
+			s.commit(block, vs.forHash(hash))
+			return
+		}
+		douglogger.Debugf("BFT: block %x committed in round %d but we don't have it\n", hash, round)
+	}
+	// +2/3 precommitted nil, move on
+	if s.step == BftPrecommitStep {
+		if hash, ok := s.votes(s.precommits, s.round).majority(s.model.Quorum()); ok && len(hash) == 0 {
+			s.enterNewRound(s.round + 1)
+		}
+	}
+}
+
+func (s *BftState) commit(block *monkchain.Block, precommits []*BftVote) {
+	douglogger.Infof("BFT: committing block %x at height %d\n", block.Hash(), s.height)
+	if err := s.app.Commit(block, precommits); err != nil {
+		douglogger.Errorln("BFT: commit failed:", err)
+	}
+
+	s.height++
+	s.resetHeight()
+	s.queue = nil
+
+	// replay messages for the new height
+	future := s.future
+	s.future = nil
+	for _, m := range future {
+		s.queue = append(s.queue, m)
+	}
+
+	s.enterNewRound(0)
+}
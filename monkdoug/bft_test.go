@@ -0,0 +1,267 @@
+package monkdoug
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkdb"
+	"github.com/eris-ltd/thelonious/monkutil"
+	"github.com/eris-ltd/thelonious/monkwire"
+)
+
+func init() {
+	monkutil.ReadConfig(".ethtest", "/tmp/ethtest", "")
+	db, _ := monkdb.NewMemDatabase()
+	monkutil.Config.Db = db
+}
+
+// An in-process validator. Commits go into a simple list
+type testBftNode struct {
+	keys  *monkcrypto.KeyPair
+	net   *testBftNet
+	state *BftState
+
+	mut        sync.Mutex
+	chain      []*monkchain.Block
+	precommits [][]*BftVote
+}
+
+func (n *testBftNode) head() []byte {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	if len(n.chain) == 0 {
+		return monkchain.ZeroHash256
+	}
+	return n.chain[len(n.chain)-1].Hash()
+}
+
+func (n *testBftNode) height() int {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	return len(n.chain)
+}
+
+func (n *testBftNode) committed(i int) (*monkchain.Block, []*BftVote) {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	return n.chain[i], n.precommits[i]
+}
+
+func (n *testBftNode) Propose(height uint64) *monkchain.Block {
+	block := monkchain.CreateBlock(nil, n.head(), n.keys.Address(), big.NewInt(1), nil, "")
+	block.Number = new(big.Int).SetUint64(height)
+	block.Sign(n.keys.PrivateKey)
+	return block
+}
+
+func (n *testBftNode) Validate(block *monkchain.Block) error {
+	if !bytes.Equal(block.PrevHash, n.head()) {
+		return fmt.Errorf("block does not extend head")
+	}
+	return nil
+}
+
+func (n *testBftNode) Commit(block *monkchain.Block, precommits []*BftVote) error {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	n.chain = append(n.chain, block)
+	n.precommits = append(n.precommits, precommits)
+	return nil
+}
+
+// Messages go over the "wire" to every other live node
+func (n *testBftNode) Broadcast(msgType monkwire.MsgType, data []interface{}) {
+	n.net.broadcast(n, msgType, monkutil.Encode(data))
+}
+
+type testBftNet struct {
+	model *BftModel
+	nodes []*testBftNode
+	live  []*testBftNode
+}
+
+func (net *testBftNet) broadcast(from *testBftNode, msgType monkwire.MsgType, data []byte) {
+	for _, n := range net.live {
+		if n != from {
+			go n.state.Receive(msgType, monkutil.NewValueFromBytes(data))
+		}
+	}
+}
+
+// Start n validators, of which the last silent ones never say anything
+func newTestBftNet(n, silent int) *testBftNet {
+	g := &GenesisConfig{ModelName: "bft"}
+	keys := make([]*monkcrypto.KeyPair, n)
+	for i := 0; i < n; i++ {
+		keys[i] = monkcrypto.GenerateNewKeyPair()
+		g.Accounts = append(g.Accounts, &Account{
+			Address:     monkutil.Bytes2Hex(keys[i].Address()),
+			byteAddr:    keys[i].Address(),
+			Permissions: map[string]int{"validate": 1},
+		})
+	}
+	g.Init()
+
+	net := &testBftNet{model: g.Model().(*Protocol).Consensus().(*BftModel)}
+	for i := 0; i < n; i++ {
+		node := &testBftNode{keys: keys[i], net: net}
+		node.state = NewBftState(net.model, node, keys[i], 1)
+		node.state.Timeout = 100 * time.Millisecond
+		net.nodes = append(net.nodes, node)
+		if i < n-silent {
+			net.live = append(net.live, node)
+		}
+	}
+	for _, node := range net.live {
+		node.state.Start()
+	}
+	return net
+}
+
+func (net *testBftNet) stop() {
+	for _, node := range net.live {
+		node.state.Stop()
+	}
+}
+
+// Wait for every live node to commit some blocks
+func (net *testBftNet) waitHeight(height int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		done := true
+		for _, node := range net.live {
+			if node.height() < height {
+				done = false
+			}
+		}
+		if done {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
+
+// Every live node must commit the same block at every height,
+// each with a quorum of precommits from distinct validators
+func (net *testBftNet) checkSafety(t *testing.T, height int) {
+	for h := 0; h < height; h++ {
+		first, _ := net.live[0].committed(h)
+		hash := first.Hash()
+		for i, node := range net.live {
+			block, precommits := node.committed(h)
+
+			if !bytes.Equal(block.Hash(), hash) {
+				t.Fatalf("node %d committed %x at height %d, node 0 committed %x", i, block.Hash(), h+1, hash)
+			}
+			if block.Number.Uint64() != uint64(h+1) {
+				t.Fatalf("node %d committed block number %v at height %d", i, block.Number, h+1)
+			}
+			signers := make(map[string]bool)
+			for _, vote := range precommits {
+				if vote.Type != monkwire.MsgBftPrecommitTy || !bytes.Equal(vote.BlockHash, hash) {
+					t.Fatalf("node %d has a bad precommit for height %d: %v", i, h+1, vote)
+				}
+				signer := vote.Signer()
+				if !net.model.IsValidator(signer) {
+					t.Fatalf("precommit signed by non-validator %x", signer)
+				}
+				signers[string(signer)] = true
+			}
+			if len(signers) < net.model.Quorum() {
+				t.Fatalf("node %d committed height %d with %d precommits, need %d", i, h+1, len(signers), net.model.Quorum())
+			}
+		}
+	}
+}
+
+func TestBftAllValidators(t *testing.T) {
+	net := newTestBftNet(4, 0)
+	defer net.stop()
+
+	if !net.waitHeight(5, 10*time.Second) {
+		t.Fatal("validators failed to commit 5 blocks")
+	}
+	net.checkSafety(t, 5)
+}
+
+// One of four validators is silent, including when it should propose
+func TestBftOneSilent(t *testing.T) {
+	net := newTestBftNet(4, 1)
+	defer net.stop()
+
+	if !net.waitHeight(6, 20*time.Second) {
+		t.Fatal("validators failed to commit 6 blocks with one silent")
+	}
+	net.checkSafety(t, 6)
+}
+
+func TestBftTwoOfSevenSilent(t *testing.T) {
+	net := newTestBftNet(7, 2)
+	defer net.stop()
+
+	if !net.waitHeight(7, 30*time.Second) {
+		t.Fatal("validators failed to commit 7 blocks with two silent")
+	}
+	net.checkSafety(t, 7)
+}
+
+// Without a quorum nothing is ever committed
+func TestBftNoQuorum(t *testing.T) {
+	net := newTestBftNet(4, 2)
+	defer net.stop()
+
+	if net.waitHeight(1, time.Second) {
+		t.Fatal("committed a block without a quorum")
+	}
+	for _, node := range net.live {
+		if node.height() != 0 {
+			t.Fatal("committed a block without a quorum")
+		}
+	}
+}
+
+// What non-validators check before they finalize a block
+func TestBftCommit(t *testing.T) {
+	net := newTestBftNet(4, 0)
+	if !net.waitHeight(1, 10*time.Second) {
+		net.stop()
+		t.Fatal("validators failed to commit a block")
+	}
+	net.stop()
+	block, precommits := net.live[0].committed(0)
+
+	verify := func(votes []*BftVote) ([]byte, uint64, error) {
+		data := monkutil.Encode(NewBftCommit(block, votes).RlpData())
+		return net.model.VerifyCommit(monkutil.NewValueFromBytes(data), nil)
+	}
+	hash, number, err := verify(precommits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hash, block.Hash()) || number != 1 {
+		t.Fatalf("commit is for #%d %x, expected #1 %x", number, hash, block.Hash())
+	}
+
+	quorum := net.model.Quorum()
+	if _, _, err := verify(precommits[:quorum-1]); err == nil {
+		t.Fatal("expected an error for a commit without a quorum")
+	}
+	// the same validator twice doesn't make a quorum
+	dup := append(append([]*BftVote{}, precommits[:quorum-1]...), precommits[0])
+	if _, _, err := verify(dup); err == nil {
+		t.Fatal("expected an error for a commit with a repeated validator")
+	}
+	// nor does someone who isn't a validator
+	outsider := NewBftVote(monkwire.MsgBftPrecommitTy, 1, precommits[0].Round, block.Hash())
+	outsider.Sign(monkcrypto.GenerateNewKeyPair().PrivateKey)
+	if _, _, err := verify(append(append([]*BftVote{}, precommits[:quorum-1]...), outsider)); err == nil {
+		t.Fatal("expected an error for a precommit from a non-validator")
+	}
+}
@@ -7,16 +7,25 @@ import (
 	"github.com/eris-ltd/thelonious/monkstate"
 	"github.com/eris-ltd/thelonious/monkutil"
 	"math/big"
+	"sort"
+	"time"
 )
 
+// Forks override the gendoug singles
 func (m *StdLibModel) consensus(state *monkstate.State) string {
-	consensusBytes := vars.GetSingle(m.doug, "consensus", state)
+	if r := ActiveRules(state); r.Consensus != "" {
+		return r.Consensus
+	}
+	consensusBytes := getSingle(m.doug, "consensus", state)
 	consensus := string(consensusBytes)
 	return consensus
 }
 
 func (m *StdLibModel) blocktime(state *monkstate.State) int64 {
-	blockTimeBytes := vars.GetSingle(m.doug, "blocktime", state)
+	if r := ActiveRules(state); r.BlockTime != 0 {
+		return int64(r.BlockTime)
+	}
+	blockTimeBytes := getSingle(m.doug, "blocktime", state)
 	blockTime := monkutil.BigD(blockTimeBytes).Int64()
 	return blockTime
 }
@@ -44,6 +53,21 @@ func (m *StdLibModel) nextCoinbase(prevblock *monkchain.Block) []byte {
 	return nextcoinbase
 }
 
+// How many places coinbase is behind the next coinbase in the seq:name list.
+// Returns 0 for the in-turn miner, and puts anyone not in the list last
+func (m *StdLibModel) turnDistance(coinbase []byte, parent *monkchain.Block) int {
+	state := parent.State()
+	next := m.nextCoinbase(parent)
+	nMiners := vars.GetLinkedListLength(m.doug, "seq:name", state)
+	for i := 0; i < nMiners; i++ {
+		if bytes.Equal(next, coinbase) {
+			return i
+		}
+		next, _ = vars.GetNextLinkedListElement(m.doug, "seq:name", string(next), state)
+	}
+	return nMiners
+}
+
 // TODO !
 func (m *StdLibModel) CheckUncles(prevBlock, block *monkchain.Block) error {
 	// Check each uncle's previous hash. In order for it to be valid
@@ -72,3 +96,90 @@ func CheckBlockTimes(prevBlock, block *monkchain.Block) error {
 	*/
 	return nil
 }
+
+// The clock blocks are checked against. Tests can set their own
+var Clock = time.Now
+
+// Seconds a block may be ahead of our clock, if the genesis doesn't say
+var DefaultMaxFutureDrift = 15
+
+// Seconds ahead of our clock past which a block isn't
+// worth waiting for. It's invalid instead
+var FutureBlockHorizon int64 = 600
+
+// Median time of parent and up to n-1 of its ancestors
+func MedianTimePast(bc *monkchain.ChainManager, parent *monkchain.Block, n int) int64 {
+	times := []int64{}
+	for b := parent; b != nil && len(times) < n; b = bc.GetBlock(b.PrevHash) {
+		times = append(times, b.Time)
+		if bytes.Equal(b.PrevHash, monkchain.ZeroHash256) {
+			break
+		}
+	}
+	return medianTime(times)
+}
+
+func medianTime(times []int64) int64 {
+	sorted := append(int64s{}, times...)
+	sort.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
+
+type int64s []int64
+
+func (a int64s) Len() int           { return len(a) }
+func (a int64s) Less(i, j int) bool { return a[i] < a[j] }
+func (a int64s) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// A block can't be too far ahead of our clock
+func CheckFutureDrift(block *monkchain.Block, drift int64) error {
+	now := Clock().Unix()
+	if block.Time > now+drift+FutureBlockHorizon {
+		return monkchain.ValidationError("Block #%d is too far in the future (%v > %v)", block.Number, block.Time, now+drift+FutureBlockHorizon)
+	}
+	if block.Time > now+drift {
+		return monkchain.FutureBlockError(block.Number.Uint64(), block.Time, now)
+	}
+	return nil
+}
+
+// From gendoug if we have one, or straight from the genesis
+func (g *GenesisConfig) medianTimeBlocks(state *monkstate.State) int {
+	if g.NoGenDoug {
+		return g.MedianTimeBlocks
+	}
+	return int(singleUint(g.byteAddr, "mediantimeblocks", state, uint64(g.MedianTimeBlocks)))
+}
+
+// Earliest time that passes CheckBlockTimes, or our clock if it's later
+func (g *GenesisConfig) NextBlockTime(parent *monkchain.Block, bc *monkchain.ChainManager) int64 {
+	t := Clock().Unix()
+	if t < parent.Time {
+		t = parent.Time
+	}
+	if n := g.medianTimeBlocks(parent.State()); n > 0 {
+		if median := MedianTimePast(bc, parent, n); t <= median {
+			t = median + 1
+		}
+	}
+	return t
+}
+
+// All the timestamp rules from the genesis:
+// no older than the parent, newer than the median time past
+// (if median-time-blocks is set) and not too far in the future
+func (g *GenesisConfig) CheckBlockTimes(bc *monkchain.ChainManager, prevBlock, block *monkchain.Block) error {
+	if err := CheckBlockTimes(prevBlock, block); err != nil {
+		return err
+	}
+	if n := g.medianTimeBlocks(prevBlock.State()); n > 0 {
+		if median := MedianTimePast(bc, prevBlock, n); block.Time <= median {
+			return monkchain.ValidationError("Block timestamp not after the median of the last %d blocks (%v <= %v)", n, block.Time, median)
+		}
+	}
+	drift := g.MaxFutureDrift
+	if drift == 0 {
+		drift = DefaultMaxFutureDrift
+	}
+	return CheckFutureDrift(block, int64(drift))
+}
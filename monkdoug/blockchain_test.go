@@ -0,0 +1,163 @@
+package monkdoug
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func blockAtTime(n, t int64) *monkchain.Block {
+	block := blockAt(n)
+	block.Time = t
+	return block
+}
+
+func TestMedianTime(t *testing.T) {
+	if m := medianTime([]int64{5, 1, 9, 3, 7}); m != 5 {
+		t.Fatalf("expected 5, got %d", m)
+	}
+	// a single skewed clock can't drag the median
+	if m := medianTime([]int64{100, 101, 102, 5000, 103}); m != 102 {
+		t.Fatalf("expected 102, got %d", m)
+	}
+}
+
+func TestFutureDrift(t *testing.T) {
+	now := time.Unix(1000, 0)
+	Clock = func() time.Time { return now }
+	defer func() { Clock = time.Now }()
+
+	g := &GenesisConfig{MaxFutureDrift: 10}
+	parent := blockAtTime(1, 990)
+	if err := g.CheckBlockTimes(nil, parent, blockAtTime(2, 1010)); err != nil {
+		t.Fatal(err)
+	}
+	err := g.CheckBlockTimes(nil, parent, blockAtTime(2, 1011))
+	ferr, ok := err.(*monkchain.FutureBlockErr)
+	if !ok || ferr.Number != 2 || ferr.Time != 1011 {
+		t.Fatal("expected a future block error, got", err)
+	}
+	// until the clock catches up
+	now = time.Unix(1001, 0)
+	if err := g.CheckBlockTimes(nil, parent, blockAtTime(2, 1011)); err != nil {
+		t.Fatal(err)
+	}
+	// older than the parent is just invalid
+	if err := g.CheckBlockTimes(nil, parent, blockAtTime(2, 980)); err == nil || monkchain.IsFutureBlockErr(err) {
+		t.Fatal("expected a validation error, got", err)
+	}
+
+	// the default drift
+	g.MaxFutureDrift = 0
+	if err := g.CheckBlockTimes(nil, parent, blockAtTime(2, now.Unix()+int64(DefaultMaxFutureDrift)+1)); !monkchain.IsFutureBlockErr(err) {
+		t.Fatal("expected a future block error, got", err)
+	}
+	// past the horizon it's not worth the wait
+	far := now.Unix() + int64(DefaultMaxFutureDrift) + FutureBlockHorizon + 1
+	if err := g.CheckBlockTimes(nil, parent, blockAtTime(2, far)); err == nil || monkchain.IsFutureBlockErr(err) {
+		t.Fatal("expected a validation error, got", err)
+	}
+}
+
+func TestMedianTimePastChain(t *testing.T) {
+	times := []int64{100, 110, 105, 5000, 120, 130}
+	var chain []*monkchain.Block
+	prev := monkchain.ZeroHash256
+	for i, tm := range times {
+		b := monkchain.CreateBlock(nil, prev, nil, big.NewInt(1), nil, "")
+		b.Number = big.NewInt(int64(i))
+		b.Time = tm
+		monkutil.Config.Db.Put(b.Hash(), b.RlpEncode())
+		chain = append(chain, b)
+		prev = b.Hash()
+	}
+	bc := &monkchain.ChainManager{}
+	head := chain[len(chain)-1]
+	// 130, 120, 5000 -> 130
+	if m := MedianTimePast(bc, head, 3); m != 130 {
+		t.Fatalf("expected 130, got %d", m)
+	}
+	// 130, 120, 5000, 105, 110 -> 120
+	if m := MedianTimePast(bc, head, 5); m != 120 {
+		t.Fatalf("expected 120, got %d", m)
+	}
+	// stops at genesis
+	if m := MedianTimePast(bc, head, 100); m != 120 {
+		t.Fatalf("expected 120 over the whole chain, got %d", m)
+	}
+}
+
+func TestBlockTimePastMedian(t *testing.T) {
+	now := time.Unix(200, 0)
+	Clock = func() time.Time { return now }
+	defer func() { Clock = time.Now }()
+
+	var parent *monkchain.Block
+	prev := monkchain.ZeroHash256
+	for i, tm := range []int64{200, 210, 205} {
+		parent = monkchain.CreateBlock(nil, prev, nil, big.NewInt(1), nil, "")
+		parent.Number = big.NewInt(int64(i))
+		parent.Time = tm
+		monkutil.Config.Db.Put(parent.Hash(), parent.RlpEncode())
+		prev = parent.Hash()
+	}
+	bc := &monkchain.ChainManager{}
+
+	// our clock is a little behind, so stamp past the median
+	g := &GenesisConfig{NoGenDoug: true, MedianTimeBlocks: 3}
+	block := blockAtTime(3, g.NextBlockTime(parent, bc))
+	if block.Time != 206 {
+		t.Fatalf("expected 206, got %d", block.Time)
+	}
+	if err := g.CheckBlockTimes(bc, parent, block); err != nil {
+		t.Fatal(err)
+	}
+	// without the median rule, no older than the parent
+	g.MedianTimeBlocks = 0
+	if bt := g.NextBlockTime(parent, bc); bt != 205 {
+		t.Fatalf("expected the parent's time, got %d", bt)
+	}
+	// and the clock when it's ahead
+	now = time.Unix(300, 0)
+	g.MedianTimeBlocks = 3
+	if bt := g.NextBlockTime(parent, bc); bt != 300 {
+		t.Fatalf("expected the clock, got %d", bt)
+	}
+}
+
+// Never finds the nonce valid
+type badPow struct {
+	monkchain.EasyPow
+}
+
+func (*badPow) Verify(hash []byte, diff *big.Int, nonce []byte) bool { return false }
+
+func TestFutureBlockNeedsWork(t *testing.T) {
+	now := time.Unix(1000, 0)
+	Clock = func() time.Time { return now }
+	defer func() { Clock = time.Now }()
+
+	m, _ := newStakeTestModel()
+	m.pow = &monkchain.EasyPow{}
+	keys := monkcrypto.GenerateNewKeyPair()
+	parent := storedParent(func(state *monkstate.State) {
+		GrantPerm(m.doug, keys.Address(), "mine", NoExpiry, state)
+	})
+	block := sealedBlock(m, keys, parent, nil)
+	block.Time = now.Unix() + 100
+	block.Sign(keys.PrivateKey)
+	bc := &monkchain.ChainManager{}
+	if err := m.ValidateBlock(block, bc); !monkchain.IsFutureBlockErr(err) {
+		t.Fatal("expected a future block error, got", err)
+	}
+	// without the work it's just invalid
+	m.pow = &badPow{}
+	if err := m.ValidateBlock(block, bc); err == nil || monkchain.IsFutureBlockErr(err) {
+		t.Fatal("expected a nonce error, got", err)
+	}
+}
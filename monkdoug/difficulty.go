@@ -13,7 +13,7 @@ import (
 // Base difficulty of the chain is 2^($difficulty), with $difficulty
 // stored in GenDoug
 func (m *StdLibModel) baseDifficulty(state *monkstate.State) *big.Int {
-	difv := vars.GetSingle(m.doug, "difficulty", state)
+	difv := getSingle(m.doug, "difficulty", state)
 	return monkutil.BigPow(2, int(monkutil.ReadVarInt(difv)))
 }
 
@@ -36,8 +36,7 @@ func (m *StdLibModel) RoundRobinDifficulty(block, parent *monkchain.Block) *big.
 	// get base difficulty
 	newdiff := m.baseDifficulty(state)
 	// get target block time
-	blockTimeBytes := vars.GetSingle(m.doug, "blocktime", parent.State())
-	blockTime := monkutil.BigD(blockTimeBytes).Int64()
+	blockTime := m.blocktime(parent.State())
 	// adjust difficulty in pursuit of holy target block time
 	newdiff = adjustDifficulty(newdiff, parent.Time, block.Time, blockTime)
 	// find relative position of coinbase in the linked list (i)
@@ -52,13 +51,29 @@ func (m *StdLibModel) RoundRobinDifficulty(block, parent *monkchain.Block) *big.
 		}
 		next, _ = vars.GetNextLinkedListElement(m.doug, "seq:name", string(next), state)
 	}
-	newdiff = big.NewInt(0).Mul(monkutil.BigPow(2, i), newdiff)
-	return newdiff
+	return robinDifficulty(newdiff, i)
 }
 
+// Each place a miner is behind the proper next coinbase doubles its difficulty
+func robinDifficulty(base *big.Int, i int) *big.Int {
+	return big.NewInt(0).Mul(monkutil.BigPow(2, i), base)
+}
+
+// Difficulty for signers in authority mode. It doesn't protect anything,
+// it only makes the chain of in-turn blocks the heaviest
+func (m *StdLibModel) AuthorityDifficulty(block, parent *monkchain.Block) *big.Int {
+	if m.turnDistance(block.Coinbase, parent) == 0 {
+		return big.NewInt(2)
+	}
+	return big.NewInt(1)
+}
+
+// Difficulty for miners weighted by their share of the bonded stake
 func (m *StdLibModel) StakeDifficulty(block, parent *monkchain.Block) *big.Int {
-	//TODO
-	return nil
+	state := parent.State()
+	stake := GetStake(m.doug, block.Coinbase, state)
+	total := GetTotalStake(m.doug, state)
+	return stakeDifficulty(m.baseDifficulty(state), stake, total)
 }
 
 // difficulty targets a specific block time
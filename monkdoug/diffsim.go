@@ -0,0 +1,264 @@
+package monkdoug
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+)
+
+/*
+   Difficulty simulator.
+   Runs the difficulty functions against synthetic miners,
+   so blocktime and difficulty can be tuned before a chain is deployed.
+   There is no networking and no real proof of work: a miner with
+   hashrate h finds a block at difficulty d after an exponentially
+   distributed time with mean d/h seconds
+*/
+
+type SimConfig struct {
+	// robin, stake-weight, constant, or eth
+	Consensus string `json:"consensus"`
+	// Base difficulty is 2^difficulty (the starting difficulty for eth)
+	Difficulty int `json:"difficulty"`
+	// Target block time (seconds)
+	BlockTime int64 `json:"blocktime"`
+	// Number of blocks to mine
+	Blocks int `json:"blocks"`
+	// Seed for the random number generator
+	Seed int64 `json:"seed"`
+	// Block timestamps are taken when a miner starts on a block ("start"),
+	// which is what the miner does, or when it finds it ("found")
+	Timestamp string `json:"timestamp"`
+
+	Miners []*SimMiner `json:"miners"`
+}
+
+type SimMiner struct {
+	Name string `json:"name"`
+	// Hashes per second
+	HashRate float64 `json:"hashrate"`
+	// Bonded stake (stake-weight)
+	Stake int64 `json:"stake"`
+	// Seconds this miner's clock is ahead (or behind, if negative)
+	Skew int64 `json:"skew"`
+	// Seconds into the simulation the miner joins and leaves (0 for never)
+	Join  float64 `json:"join"`
+	Leave float64 `json:"leave"`
+}
+
+func (m *SimMiner) active(t float64) bool {
+	return t >= m.Join && (m.Leave == 0 || t < m.Leave)
+}
+
+// One row of the time series
+type SimBlock struct {
+	Number int `json:"number"`
+	// Seconds since the start of the simulation
+	Time float64 `json:"time"`
+	// Seconds since the last block
+	Interval   float64  `json:"interval"`
+	Difficulty *big.Int `json:"difficulty"`
+	Miner      string   `json:"miner"`
+	// Fraction of the blocks so far mined by each miner
+	Shares map[string]float64 `json:"shares"`
+}
+
+func (c *SimConfig) Validate() error {
+	switch c.Consensus {
+	case "robin", "stake-weight", "constant", "eth":
+	default:
+		return fmt.Errorf("Can not simulate consensus %q (use robin, stake-weight, constant, or eth)", c.Consensus)
+	}
+	if len(c.Miners) == 0 {
+		return fmt.Errorf("No miners")
+	}
+	if c.Consensus == "eth" && c.BlockTime <= 0 {
+		return fmt.Errorf("Eth difficulty needs a blocktime")
+	}
+	switch c.Timestamp {
+	case "", "start", "found":
+	default:
+		return fmt.Errorf("Unknown timestamp %q (use start or found)", c.Timestamp)
+	}
+	names := make(map[string]bool)
+	for i, m := range c.Miners {
+		if m.Name == "" {
+			m.Name = fmt.Sprintf("miner%d", i)
+		}
+		if names[m.Name] {
+			return fmt.Errorf("Two miners named %s", m.Name)
+		}
+		names[m.Name] = true
+		if m.HashRate <= 0 {
+			return fmt.Errorf("Miner %s needs a positive hashrate", m.Name)
+		}
+		// no stake is an impossible difficulty
+		if c.Consensus == "stake-weight" && m.Stake <= 0 {
+			return fmt.Errorf("Miner %s needs a positive stake", m.Name)
+		}
+	}
+	return nil
+}
+
+type simulator struct {
+	c    *SimConfig
+	r    *rand.Rand
+	base *big.Int
+	// miners in the order they joined (the round robin order)
+	order []*SimMiner
+}
+
+// Run the simulation and return a row for each block
+func Simulate(c *SimConfig) ([]*SimBlock, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	s := &simulator{
+		c:    c,
+		r:    rand.New(rand.NewSource(c.Seed)),
+		base: big.NewInt(0).Lsh(big.NewInt(1), uint(c.Difficulty)),
+	}
+
+	parent := &monkchain.Block{Number: big.NewInt(0), Difficulty: s.base}
+	var (
+		now, last   float64
+		parentMiner *SimMiner
+		wins        = make(map[string]int)
+		blocks      []*SimBlock
+	)
+	for len(blocks) < c.Blocks {
+		s.updateOrder(now)
+		if len(s.order) == 0 {
+			next, ok := s.nextEvent(now)
+			if !ok {
+				break
+			}
+			now = next
+			continue
+		}
+
+		// every active miner races on the parent from now,
+		// unless someone joins or leaves first
+		var (
+			winner  *SimMiner
+			block   *monkchain.Block
+			found   = math.Inf(1)
+			started = now
+		)
+		for _, m := range s.order {
+			b := &monkchain.Block{Number: new(big.Int).Add(parent.Number, big.NewInt(1)), Coinbase: []byte(m.Name)}
+			b.Time = int64(started) + m.Skew
+			diff := s.difficulty(b, parent, parentMiner, m)
+			t := started + s.r.ExpFloat64()*bigFloat(diff)/m.HashRate
+			if t < found {
+				found, winner, block = t, m, b
+				block.Difficulty = diff
+			}
+		}
+		if next, ok := s.nextEvent(now); ok && next < found {
+			now = next
+			continue
+		}
+
+		if c.Timestamp == "found" {
+			block.Time = int64(found) + winner.Skew
+		}
+		now = found
+		wins[winner.Name]++
+		row := &SimBlock{
+			Number:     len(blocks) + 1,
+			Time:       now,
+			Interval:   now - last,
+			Difficulty: block.Difficulty,
+			Miner:      winner.Name,
+			Shares:     make(map[string]float64),
+		}
+		for _, m := range c.Miners {
+			row.Shares[m.Name] = float64(wins[m.Name]) / float64(row.Number)
+		}
+		blocks = append(blocks, row)
+		last = now
+		parent, parentMiner = block, winner
+	}
+	return blocks, nil
+}
+
+// Keep the join order, drop those who left
+func (s *simulator) updateOrder(t float64) {
+	order := []*SimMiner{}
+	for _, m := range s.order {
+		if m.active(t) {
+			order = append(order, m)
+		}
+	}
+	for _, m := range s.c.Miners {
+		if !m.active(t) {
+			continue
+		}
+		known := false
+		for _, o := range order {
+			known = known || o == m
+		}
+		if !known {
+			order = append(order, m)
+		}
+	}
+	s.order = order
+}
+
+// Time of the next join or leave after t
+func (s *simulator) nextEvent(t float64) (float64, bool) {
+	next, ok := math.Inf(1), false
+	for _, m := range s.c.Miners {
+		for _, e := range []float64{m.Join, m.Leave} {
+			if e > t && e < next {
+				next, ok = e, true
+			}
+		}
+	}
+	return next, ok
+}
+
+// The same functions the StdLibModel uses, with the miners
+// standing in for what would be read from gendoug
+func (s *simulator) difficulty(block, parent *monkchain.Block, parentMiner, m *SimMiner) *big.Int {
+	switch s.c.Consensus {
+	case "robin":
+		diff := adjustDifficulty(s.base, parent.Time, block.Time, s.c.BlockTime)
+		return robinDifficulty(diff, s.robinPosition(parentMiner, m))
+	case "stake-weight":
+		total := int64(0)
+		for _, o := range s.order {
+			total += o.Stake
+		}
+		return stakeDifficulty(s.base, big.NewInt(m.Stake), big.NewInt(total))
+	case "constant":
+		return s.base
+	default:
+		return EthDifficulty(s.c.BlockTime, block, parent)
+	}
+}
+
+// How many places m is behind the miner after parentMiner
+func (s *simulator) robinPosition(parentMiner, m *SimMiner) int {
+	next := 0
+	for i, o := range s.order {
+		if o == parentMiner {
+			next = (i + 1) % len(s.order)
+		}
+	}
+	for i := 0; i < len(s.order); i++ {
+		if s.order[(next+i)%len(s.order)] == m {
+			return i
+		}
+	}
+	return 0
+}
+
+func bigFloat(b *big.Int) float64 {
+	f, _ := new(big.Rat).SetInt(b).Float64()
+	return f
+}
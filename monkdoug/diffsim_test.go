@@ -0,0 +1,83 @@
+package monkdoug
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimulateConstant(t *testing.T) {
+	c := &SimConfig{
+		Consensus:  "constant",
+		Difficulty: 10,
+		Blocks:     2000,
+		Seed:       1,
+		Miners: []*SimMiner{
+			{Name: "a", HashRate: 1024},
+			{Name: "b", HashRate: 3 * 1024},
+		},
+	}
+	rows, err := Simulate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != c.Blocks {
+		t.Fatalf("expected %d blocks, got %d", c.Blocks, len(rows))
+	}
+	// 4096 hashes/s on difficulty 1024 is a block every 1/4s,
+	// with b finding 3/4 of them
+	last := rows[len(rows)-1]
+	if mean := last.Time / float64(len(rows)); math.Abs(mean-0.25) > 0.03 {
+		t.Errorf("mean interval %.3f, expected 0.25", mean)
+	}
+	if share := last.Shares["b"]; math.Abs(share-0.75) > 0.05 {
+		t.Errorf("b has share %.3f, expected 0.75", share)
+	}
+}
+
+func TestSimulateJoinLeave(t *testing.T) {
+	c := &SimConfig{
+		Consensus:  "robin",
+		Difficulty: 4,
+		BlockTime:  1,
+		Blocks:     500,
+		Miners: []*SimMiner{
+			{Name: "a", HashRate: 16},
+			{Name: "b", HashRate: 16, Join: 50, Leave: 100},
+		},
+	}
+	rows, err := Simulate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rows {
+		if r.Miner == "b" && (r.Time < 50 || r.Time >= 100) {
+			t.Fatalf("b mined block %d at %.2f, outside of when it was around", r.Number, r.Time)
+		}
+	}
+	if rows[len(rows)-1].Shares["b"] == 0 {
+		t.Fatal("b never mined")
+	}
+
+	c.Consensus = "magic"
+	if _, err := Simulate(c); err == nil {
+		t.Fatal("expected an error for an unknown consensus")
+	}
+}
+
+func TestSimValidate(t *testing.T) {
+	c := &SimConfig{
+		Consensus: "stake-weight",
+		Miners:    []*SimMiner{{Name: "a", HashRate: 16, Stake: 10}, {Name: "b", HashRate: 16}},
+	}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected an error for a stake-weight miner without stake")
+	}
+	c.Miners[1].Stake = 5
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	c.Timestamp = "whenever"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected an error for an unknown timestamp")
+	}
+}
@@ -0,0 +1,85 @@
+package monkdoug
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+/*
+   Punishing equivocation for the StdLibModel.
+
+   Nodes that catch a miner signing two blocks at the same height
+   submit the evidence (see monkchain.Evidence) as the data of a
+   system tx to EvidenceAddr. When it's included, the offender is
+   slashed: it loses the "mine" permission for good, however it
+   held it, and its bonded and unbonding stake is burned.
+
+   Records live in GenDoug's storage with the roles:
+       sha3(rlp("perm:slashed", addr))   - addr was caught equivocating
+       sha3(rlp("evidence", hash))       - evidence that was already used
+*/
+
+// Receives the evidence system txs
+var EvidenceAddr = monkutil.LeftPadBytes([]byte("evidence"), 20)
+
+func IsSlashed(doug, addr []byte, state *monkstate.State) bool {
+	return getRoleValue(doug, roleKey("perm:slashed", addr), state).Uint() != 0
+}
+
+// Take away addr's mining rights and burn its stake
+func Slash(doug, addr []byte, state *monkstate.State) {
+	setRoleValue(doug, roleKey("perm:slashed", addr), uint64(1), state)
+
+	stake := GetStake(doug, addr, state)
+	AddStake(doug, addr, new(big.Int).Neg(stake), state)
+	unbonding, _ := GetUnbonding(doug, addr, state)
+	setStakeValue(doug, "stake:unbonding", addr, new(big.Int), state)
+	setStakeValue(doug, "stake:release", addr, new(big.Int), state)
+	state.GetOrNewStateObject(StakeAddr).SubAmount(stake.Add(stake, unbonding))
+}
+
+// Build the tx that gets evidence into a block
+func (m *StdLibModel) EvidenceTx(ev *monkchain.Evidence, state *monkstate.State) *monkchain.Transaction {
+	data := monkutil.Encode(ev.RlpData())
+	schedule := gasSchedule(state)
+	gas := new(big.Int).Mul(schedule.Data, big.NewInt(int64(len(data))))
+	gas.Add(gas, schedule.Tx)
+	return monkchain.NewTransactionMessage(EvidenceAddr, new(big.Int), gas, new(big.Int), data)
+}
+
+func (m *StdLibModel) applyEvidenceTx(tx *monkchain.Transaction, state *monkstate.State, block *monkchain.Block) error {
+	if tx.Value.Sign() != 0 {
+		return fmt.Errorf("Evidence txs do not take a value")
+	}
+	ev := monkchain.NewEvidenceFromValue(monkutil.NewValueFromBytes(tx.Data))
+	if ev == nil {
+		return fmt.Errorf("Malformed evidence")
+	}
+	if err := ev.Verify(); err != nil {
+		return err
+	}
+	key := roleKey("evidence", ev.Hash())
+	if getRoleValue(m.doug, key, state).Uint() != 0 {
+		return fmt.Errorf("Evidence %x was already used", ev.Hash())
+	}
+	offender := ev.Offender()
+	if IsSlashed(m.doug, offender, state) {
+		return fmt.Errorf("%x was already slashed", offender)
+	}
+	setRoleValue(m.doug, key, uint64(1), state)
+	Slash(m.doug, offender, state)
+	douglogger.Infof("Slashed %x for equivocating at block #%d\n", offender, ev.Number())
+	return nil
+}
+
+// Pass through to the consensus, if it punishes equivocation
+func (p *Protocol) EvidenceTx(ev *monkchain.Evidence, state *monkstate.State) *monkchain.Transaction {
+	if eq, ok := p.model(state).(monkchain.Equivocation); ok {
+		return eq.EvidenceTx(ev, state)
+	}
+	return nil
+}
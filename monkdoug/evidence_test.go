@@ -0,0 +1,66 @@
+package monkdoug
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+)
+
+func equivocate(keys *monkcrypto.KeyPair, number int64) *monkchain.Evidence {
+	blocks := make([]*monkchain.Block, 2)
+	for i, extra := range []string{"a", "b"} {
+		blocks[i] = monkchain.CreateBlock(nil, monkchain.ZeroHash256, keys.Address(), big.NewInt(1), nil, extra)
+		blocks[i].Number = big.NewInt(number)
+		blocks[i].Sign(keys.PrivateKey)
+	}
+	return monkchain.NewEvidence(blocks[0], blocks[1])
+}
+
+func TestEquivocationSlashes(t *testing.T) {
+	m, state := newStakeTestModel()
+	miner, reporter := monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair()
+	state.GetOrNewStateObject(miner.Address()).SetBalance(big.NewInt(1000))
+	GrantPerm(m.doug, miner.Address(), "mine", NoExpiry, state)
+	if err := applySystemTx(m, state, miner, systemTx(StakeAddr, 300, "bond"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := applySystemTx(m, state, miner, systemTx(StakeAddr, 0, "unbond", "0x64"), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	ev := equivocate(miner, 5)
+	if err := applySystemTx(m, state, reporter, m.EvidenceTx(ev, state), 10); err != nil {
+		t.Fatal(err)
+	}
+	if m.HasPermission(miner.Address(), "mine", state) {
+		t.Fatal("expected the miner to lose the mine permission")
+	}
+	if stake, _ := GetUnbonding(m.doug, miner.Address(), state); stake.Sign() != 0 || GetStake(m.doug, miner.Address(), state).Sign() != 0 {
+		t.Fatal("expected the stake to be slashed")
+	}
+	if bal := state.GetStateObject(StakeAddr).Balance; bal.Sign() != 0 {
+		t.Fatalf("expected the stake to be burned, StakeAddr has %v", bal)
+	}
+	// granting it back doesn't help
+	GrantPerm(m.doug, miner.Address(), "mine", NoExpiry, state)
+	if m.HasPermission(miner.Address(), "mine", state) {
+		t.Fatal("slashed miner got the mine permission back")
+	}
+
+	// evidence only counts once
+	if err := applySystemTx(m, state, reporter, m.EvidenceTx(ev, state), 10); err == nil {
+		t.Fatal("expected an error for reused evidence")
+	}
+	// and bad evidence not at all
+	honest := monkcrypto.GenerateNewKeyPair()
+	bad := equivocate(honest, 6)
+	bad.B.Number = big.NewInt(7)
+	if err := applySystemTx(m, state, reporter, m.EvidenceTx(bad, state), 10); err == nil {
+		t.Fatal("expected an error for bad evidence")
+	}
+	if IsSlashed(m.doug, honest.Address(), state) {
+		t.Fatal("slashed on bad evidence")
+	}
+}
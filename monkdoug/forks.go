@@ -0,0 +1,355 @@
+package monkdoug
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+	"github.com/eris-ltd/thelonious/monkvm"
+)
+
+/*
+   Scheduled rule changes (forks).
+
+   The schedule starts in genesis.json and is kept in the storage
+   of ForkAddr, where GenDoug governs it: accounts with the "fork"
+   permission can schedule more with a system tx to ForkAddr
+       schedule number [rule value]...
+   where the rules are consensus, blocktime, maxgastx, gas-schedule, model, bloom and logs.
+   A bloom fork makes blocks carry a header bloom from then on, and a logs fork
+   turns on the LOG0-LOG4 opcodes (invalid before it). Chains from before them
+   schedule one; new chains can put one at block 1 in genesis.json
+
+   Storage:
+       sha3("fork:count")    - number of forks scheduled
+       sha3("fork:<i>")      - rlp of the ith fork
+       sha3("fork:height")   - block number of the latest active fork
+
+   A fork at block N is activated at the end of block N-1,
+   so the rules for a block are always the ones in its parent's state
+*/
+
+// Holds the fork schedule and receives the system txs
+var ForkAddr = monkutil.LeftPadBytes([]byte("forks"), 20)
+
+// Rule changes that take effect at a block number.
+// Empty fields leave the rule as it was
+type Fork struct {
+	Number      uint64 `json:"number"`
+	Consensus   string `json:"consensus"`
+	BlockTime   int    `json:"blocktime"`
+	MaxGasTx    string `json:"maxgastx"`
+	GasSchedule string `json:"gas-schedule"`
+	ModelName   string `json:"model"`
+	HeaderBloom bool   `json:"bloom"`
+	Logs        bool   `json:"logs"`
+}
+
+func NewForkFromValue(val *monkutil.Value) *Fork {
+	return &Fork{
+		Number:      val.Get(0).Uint(),
+		Consensus:   val.Get(1).Str(),
+		BlockTime:   int(val.Get(2).Uint()),
+		MaxGasTx:    val.Get(3).Str(),
+		GasSchedule: val.Get(4).Str(),
+		ModelName:   val.Get(5).Str(),
+		HeaderBloom: val.Get(6).Uint() != 0,
+		Logs:        val.Get(7).Uint() != 0,
+	}
+}
+
+func (f *Fork) RlpData() []interface{} {
+	data := []interface{}{f.Number, f.Consensus, uint64(f.BlockTime), f.MaxGasTx, f.GasSchedule, f.ModelName}
+	// only when set, so forks from before blooms and logs keep their encoding
+	if f.HeaderBloom || f.Logs {
+		bloom := uint64(0)
+		if f.HeaderBloom {
+			bloom = 1
+		}
+		data = append(data, bloom)
+	}
+	if f.Logs {
+		data = append(data, uint64(1))
+	}
+	return data
+}
+
+// The overrides from all the active forks, folded in order
+type Rules struct {
+	Consensus   string
+	BlockTime   int
+	MaxGasTx    string
+	GasSchedule string
+	ModelName   string
+	HeaderBloom bool
+	Logs        bool
+}
+
+func (r *Rules) apply(f *Fork) {
+	if f.Consensus != "" {
+		r.Consensus = f.Consensus
+	}
+	if f.BlockTime != 0 {
+		r.BlockTime = f.BlockTime
+	}
+	if f.MaxGasTx != "" {
+		r.MaxGasTx = f.MaxGasTx
+	}
+	if f.GasSchedule != "" {
+		r.GasSchedule = f.GasSchedule
+	}
+	if f.ModelName != "" {
+		r.ModelName = f.ModelName
+	}
+	if f.HeaderBloom {
+		r.HeaderBloom = true
+	}
+	if f.Logs {
+		r.Logs = true
+	}
+}
+
+// Consensus types the StdLibModel knows
+var consensusNames = []string{"robin", "authority", "stake-weight", "constant", "eth"}
+
+// Can this node run with the rules
+func (r *Rules) supported() error {
+	if r.ModelName != "" {
+		if _, err := lookupModel(r.ModelName); err != nil {
+			return err
+		}
+	}
+	if _, ok := monkvm.GetGasSchedule(r.GasSchedule); !ok {
+		return fmt.Errorf("Unknown gas schedule %q", r.GasSchedule)
+	}
+	if r.Consensus != "" {
+		known := false
+		for _, name := range consensusNames {
+			known = known || name == r.Consensus
+		}
+		if !known {
+			return fmt.Errorf("Unknown consensus %q", r.Consensus)
+		}
+	}
+	return nil
+}
+
+func forkKey(name string) *big.Int {
+	return monkutil.BigD(monkcrypto.Sha3Bin([]byte(name)))
+}
+
+// The scheduled forks, in the order they were added
+func GetForks(state *monkstate.State) []*Fork {
+	obj := state.GetStateObject(ForkAddr)
+	if obj == nil {
+		return nil
+	}
+	n := obj.GetStorage(forkKey("fork:count")).Uint()
+	forks := make([]*Fork, n)
+	for i := range forks {
+		data := obj.GetStorage(forkKey("fork:" + strconv.Itoa(i))).Bytes()
+		forks[i] = NewForkFromValue(monkutil.NewValueFromBytes(data))
+	}
+	return forks
+}
+
+func addFork(fork *Fork, state *monkstate.State) {
+	obj := state.GetOrNewStateObject(ForkAddr)
+	n := obj.GetStorage(forkKey("fork:count")).Uint()
+	obj.SetStorage(forkKey("fork:"+strconv.Itoa(int(n))), monkutil.NewValue(monkutil.Encode(fork.RlpData())))
+	obj.SetStorage(forkKey("fork:count"), monkutil.NewValue(new(big.Int).SetUint64(n+1).Bytes()))
+}
+
+// Block number of the latest active fork (0 if none)
+func activeForkHeight(state *monkstate.State) uint64 {
+	obj := state.GetStateObject(ForkAddr)
+	if obj == nil {
+		return 0
+	}
+	return obj.GetStorage(forkKey("fork:height")).Uint()
+}
+
+type forksByNumber []*Fork
+
+func (f forksByNumber) Len() int           { return len(f) }
+func (f forksByNumber) Less(i, j int) bool { return f[i].Number < f[j].Number }
+func (f forksByNumber) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+
+// The rules for blocks on top of state
+func ActiveRules(state *monkstate.State) *Rules {
+	rules := &Rules{}
+	height := activeForkHeight(state)
+	if height == 0 {
+		return rules
+	}
+	// apply in order of activation
+	forks := GetForks(state)
+	sort.Stable(forksByNumber(forks))
+	for _, f := range forks {
+		if f.Number > height {
+			break
+		}
+		rules.apply(f)
+	}
+	return rules
+}
+
+// Activate forks starting at the block after number
+func activateForks(state *monkstate.State, number uint64) {
+	for _, f := range GetForks(state) {
+		if f.Number == number+1 {
+			state.GetOrNewStateObject(ForkAddr).SetStorage(forkKey("fork:height"), monkutil.NewValue(new(big.Int).SetUint64(f.Number).Bytes()))
+			douglogger.Infof("Fork activated for block #%d\n", f.Number)
+			return
+		}
+	}
+}
+
+// Parse a "schedule" system tx into a fork
+func forkFromTx(tx *monkchain.Transaction) (*Fork, error) {
+	data := tx.Data
+	if len(data) < 64 || len(data)%32 != 0 {
+		return nil, fmt.Errorf("Fork tx needs a command and a block number")
+	}
+	word := func(i int) []byte { return data[32*i : 32*(i+1)] }
+	str := func(i int) string { return string(bytes.TrimLeft(word(i), "\x00")) }
+
+	if cmd := str(0); cmd != "schedule" {
+		return nil, fmt.Errorf("Unknown fork command %s", cmd)
+	}
+	fork := &Fork{Number: monkutil.BigD(word(1)).Uint64()}
+	for i := 2; i+1 < len(data)/32; i += 2 {
+		switch rule := str(i); rule {
+		case "consensus":
+			fork.Consensus = str(i + 1)
+		case "blocktime":
+			fork.BlockTime = int(monkutil.BigD(word(i + 1)).Int64())
+		case "maxgastx":
+			fork.MaxGasTx = monkutil.BigD(word(i + 1)).String()
+		case "gas-schedule":
+			fork.GasSchedule = str(i + 1)
+		case "model":
+			fork.ModelName = str(i + 1)
+		case "bloom":
+			fork.HeaderBloom = monkutil.BigD(word(i+1)).Sign() != 0
+		case "logs":
+			fork.Logs = monkutil.BigD(word(i+1)).Sign() != 0
+		default:
+			return nil, fmt.Errorf("Unknown fork rule %s", rule)
+		}
+	}
+	return fork, nil
+}
+
+/*
+   Protocol side
+*/
+
+// The consensus model for blocks on top of state
+func (p *Protocol) model(state *monkstate.State) monkchain.Consensus {
+	name := ActiveRules(state).ModelName
+	if name == "" {
+		return p.consensus
+	}
+
+	p.modelMut.Lock()
+	defer p.modelMut.Unlock()
+	if model, ok := p.forkModels[name]; ok {
+		return model
+	}
+	ctor, err := lookupModel(name)
+	if err != nil {
+		// CheckFork won't let us get here
+		douglogger.Warnln(err)
+		return p.consensus
+	}
+	model := ctor(p.g)
+	p.forkModels[name] = model
+	return model
+}
+
+func (p *Protocol) CheckFork(state *monkstate.State) error {
+	return ActiveRules(state).supported()
+}
+
+func (p *Protocol) GasSchedule(state *monkstate.State) *monkvm.GasSchedule {
+	return gasSchedule(state)
+}
+
+func gasSchedule(state *monkstate.State) *monkvm.GasSchedule {
+	if schedule, ok := monkvm.GetGasSchedule(ActiveRules(state).GasSchedule); ok {
+		return schedule
+	}
+	return monkvm.DefaultGasSchedule
+}
+
+func (p *Protocol) HeaderBloom(state *monkstate.State) bool {
+	return ActiveRules(state).HeaderBloom
+}
+
+func (p *Protocol) Logs(state *monkstate.State) bool {
+	return ActiveRules(state).Logs
+}
+
+func (p *Protocol) ActivateForks(state *monkstate.State, block *monkchain.Block) {
+	activateForks(state, block.Number.Uint64())
+}
+
+// Schedule a fork. Needs the "fork" permission,
+// and the fork must be after this block
+func (p *Protocol) scheduleFork(tx *monkchain.Transaction, state *monkstate.State, block *monkchain.Block) error {
+	if tx.Value.Sign() != 0 {
+		return fmt.Errorf("Fork txs do not take a value")
+	}
+	if err := p.model(state).ValidatePerm(tx.Sender(), "fork", state); err != nil {
+		return err
+	}
+	fork, err := forkFromTx(tx)
+	if err != nil {
+		return err
+	}
+	if fork.Number <= block.Number.Uint64() {
+		return fmt.Errorf("Can not schedule a fork for block #%d at block #%d", fork.Number, block.Number)
+	}
+	addFork(fork, state)
+	douglogger.Infof("Fork scheduled for block #%d by %x\n", fork.Number, tx.Sender())
+	return nil
+}
+
+/*
+   Genesis side
+*/
+
+// Write the schedule and activate any fork for block 1
+func (g *GenesisConfig) deployForks(block *monkchain.Block) {
+	for _, f := range g.Forks {
+		addFork(f, block.State())
+	}
+	activateForks(block.State(), block.Number.Uint64())
+}
+
+func (g *GenesisConfig) validateForks() []error {
+	errs := []error{}
+	seen := make(map[uint64]bool)
+	for _, f := range g.Forks {
+		if f.Number < 1 {
+			errs = append(errs, fmt.Errorf("Fork number must be at least 1"))
+		}
+		if seen[f.Number] {
+			errs = append(errs, fmt.Errorf("Two forks scheduled for block #%d", f.Number))
+		}
+		seen[f.Number] = true
+		rules := &Rules{}
+		rules.apply(f)
+		if err := rules.supported(); err != nil {
+			errs = append(errs, fmt.Errorf("Fork at block #%d: %s", f.Number, err.Error()))
+		}
+	}
+	return errs
+}
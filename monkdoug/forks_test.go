@@ -0,0 +1,214 @@
+package monkdoug
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monktrie"
+	"github.com/eris-ltd/thelonious/monkutil"
+	"github.com/eris-ltd/thelonious/monkvm"
+)
+
+func newForkTestProtocol(model string) (*Protocol, *monkstate.State) {
+	g := &GenesisConfig{ModelName: model}
+	consensus, _ := NewPermModel(g)
+	p := &Protocol{g: g, consensus: consensus, forkModels: make(map[string]monkchain.Consensus)}
+	state := monkstate.New(monktrie.New(monkutil.Config.Db, ""))
+	return p, state
+}
+
+func TestForkActivation(t *testing.T) {
+	p, state := newForkTestProtocol("yes")
+	addFork(&Fork{Number: 3, BlockTime: 10, MaxGasTx: "5000"}, state)
+	addFork(&Fork{Number: 5, BlockTime: 20, ModelName: "no"}, state)
+
+	forks := GetForks(state)
+	if len(forks) != 2 || forks[1].Number != 5 || forks[1].ModelName != "no" || forks[0].MaxGasTx != "5000" {
+		t.Fatalf("bad round trip of the schedule: %v", forks)
+	}
+
+	// nothing until the end of block 2
+	for n := uint64(0); n < 2; n++ {
+		activateForks(state, n)
+	}
+	if r := ActiveRules(state); r.BlockTime != 0 {
+		t.Fatalf("fork active too early: %v", r)
+	}
+	activateForks(state, 2)
+	if r := ActiveRules(state); r.BlockTime != 10 || r.MaxGasTx != "5000" {
+		t.Fatalf("expected the first fork to be active, got %v", r)
+	}
+	if _, ok := p.model(state).(*YesModel); !ok {
+		t.Fatal("model should not have changed yet")
+	}
+
+	activateForks(state, 4)
+	r := ActiveRules(state)
+	if r.BlockTime != 20 || r.MaxGasTx != "5000" || r.ModelName != "no" {
+		t.Fatalf("expected the forks to fold, got %v", r)
+	}
+	if _, ok := p.model(state).(*NoModel); !ok {
+		t.Fatalf("expected the forked model, got %T", p.model(state))
+	}
+	if err := p.CheckFork(state); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnsupportedFork(t *testing.T) {
+	p, state := newForkTestProtocol("yes")
+	addFork(&Fork{Number: 1, ModelName: "from-the-future"}, state)
+	activateForks(state, 0)
+	if err := p.CheckFork(state); err == nil {
+		t.Fatal("expected an error for an unknown model")
+	}
+
+	p, state = newForkTestProtocol("yes")
+	addFork(&Fork{Number: 1, GasSchedule: "from-the-future"}, state)
+	activateForks(state, 0)
+	if err := p.CheckFork(state); err == nil {
+		t.Fatal("expected an error for an unknown gas schedule")
+	}
+
+	g := &GenesisConfig{ModelName: "yes", NoGenDoug: true, Forks: []*Fork{{Number: 0}, {Number: 2, Consensus: "magic"}}}
+	if errs := g.Validate(); len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+}
+
+func TestScheduleForkTx(t *testing.T) {
+	keys := monkcrypto.GenerateNewKeyPair()
+
+	p, state := newForkTestProtocol("yes")
+	if err := applySystemTx(p, state, keys, systemTx(ForkAddr, 0, "schedule", "0x5", "blocktime", "0x1e"), 5); err == nil {
+		t.Fatal("expected error scheduling a fork for the current block")
+	}
+	if err := applySystemTx(p, state, keys, systemTx(ForkAddr, 0, "schedule", "0xa", "blocktime", "0x1e", "model", "no"), 5); err != nil {
+		t.Fatal(err)
+	}
+	forks := GetForks(state)
+	if len(forks) != 1 || forks[0].Number != 10 || forks[0].BlockTime != 30 || forks[0].ModelName != "no" {
+		t.Fatalf("bad fork from tx: %v", forks)
+	}
+	if err := applySystemTx(p, state, keys, systemTx(ForkAddr, 0, "schedule", "0xa", "colour", "blue"), 5); err == nil {
+		t.Fatal("expected error for unknown rule")
+	}
+
+	// no "fork" permission
+	p, state = newForkTestProtocol("no")
+	if err := applySystemTx(p, state, keys, systemTx(ForkAddr, 0, "schedule", "0xa", "blocktime", "0x1e"), 5); err == nil {
+		t.Fatal("expected a permission error")
+	}
+}
+
+func TestForksSurviveSync(t *testing.T) {
+	_, state := newForkTestProtocol("yes")
+	addFork(&Fork{Number: 1, BlockTime: 10}, state)
+	addFork(&Fork{Number: 2, BlockTime: 20}, state)
+	activateForks(state, 0)
+
+	state.Update()
+	state.Sync()
+	if n := len(GetForks(state)); n != 2 {
+		t.Fatalf("expected 2 forks after a sync, got %d", n)
+	}
+	if r := ActiveRules(state); r.BlockTime != 10 {
+		t.Fatalf("expected the first fork to still be active, got %v", r)
+	}
+}
+
+func TestForkOrderAndModels(t *testing.T) {
+	p, state := newForkTestProtocol("yes")
+	// scheduled out of order
+	addFork(&Fork{Number: 4, BlockTime: 40, ModelName: "bft"}, state)
+	addFork(&Fork{Number: 2, BlockTime: 20, ModelName: "std"}, state)
+
+	activateForks(state, 1)
+	if r := ActiveRules(state); r.BlockTime != 20 || r.ModelName != "std" {
+		t.Fatalf("expected only the fork at 2, got %v", r)
+	}
+	// system txs are the forked model's
+	tx := monkchain.NewTransactionMessage(StakeAddr, big.NewInt(0), big.NewInt(10000), big.NewInt(1), nil)
+	if !p.IsSystemTx(tx, state) {
+		t.Fatal("expected the std model's system txs after the fork")
+	}
+
+	activateForks(state, 3)
+	if r := ActiveRules(state); r.BlockTime != 40 || r.ModelName != "bft" {
+		t.Fatalf("expected the later fork to win, got %v", r)
+	}
+	if _, ok := p.model(state).(*BftModel); !ok {
+		t.Fatalf("expected the bft model, got %T", p.model(state))
+	}
+	if p.g.NoGenDoug {
+		t.Fatal("forking to bft shouldn't turn off the genesis gendoug")
+	}
+	if p.IsSystemTx(tx, state) {
+		t.Fatal("bft has no stake txs")
+	}
+}
+
+// A schedule from before the LOG opcodes gets the default log prices
+func TestPartialGasSchedule(t *testing.T) {
+	monkvm.RegisterGasSchedule("cheap-sstore", &monkvm.GasSchedule{SStore: big.NewInt(1)})
+	p, state := newForkTestProtocol("yes")
+	addFork(&Fork{Number: 1, GasSchedule: "cheap-sstore"}, state)
+	activateForks(state, 0)
+	schedule := p.GasSchedule(state)
+	if schedule.SStore.Int64() != 1 {
+		t.Fatalf("expected the schedule's sstore, got %v", schedule.SStore)
+	}
+	if schedule.Log == nil || schedule.Log.Cmp(monkvm.GasLog) != 0 || schedule.Topic == nil || schedule.LogData == nil {
+		t.Fatal("expected the missing prices to be the defaults")
+	}
+}
+
+func TestBloomFork(t *testing.T) {
+	keys := monkcrypto.GenerateNewKeyPair()
+	p, state := newForkTestProtocol("yes")
+
+	// forks without a bloom keep their old encoding
+	old := &Fork{Number: 2, BlockTime: 10}
+	if n := len(old.RlpData()); n != 6 {
+		t.Fatalf("expected 6 fields for a fork without a bloom, got %d", n)
+	}
+	addFork(old, state)
+	if err := applySystemTx(p, state, keys, systemTx(ForkAddr, 0, "schedule", "0x4", "bloom", "0x1"), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	activateForks(state, 1)
+	if p.HeaderBloom(state) {
+		t.Fatal("bloom required before its fork")
+	}
+	activateForks(state, 3)
+	if !p.HeaderBloom(state) || ActiveRules(state).BlockTime != 10 {
+		t.Fatalf("expected the bloom fork to fold in, got %v", ActiveRules(state))
+	}
+}
+
+func TestLogsFork(t *testing.T) {
+	keys := monkcrypto.GenerateNewKeyPair()
+	p, state := newForkTestProtocol("yes")
+
+	// logs without a bloom still round trip
+	f := NewForkFromValue(monkutil.NewValueFromBytes(monkutil.Encode((&Fork{Number: 3, Logs: true}).RlpData())))
+	if !f.Logs || f.HeaderBloom {
+		t.Fatalf("bad logs fork after decoding %v", f)
+	}
+	if err := applySystemTx(p, state, keys, systemTx(ForkAddr, 0, "schedule", "0x4", "logs", "0x1"), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	activateForks(state, 1)
+	if p.Logs(state) {
+		t.Fatal("logs on before their fork")
+	}
+	activateForks(state, 3)
+	if !p.Logs(state) || p.HeaderBloom(state) {
+		t.Fatalf("expected just the logs fork to fold in, got %v", ActiveRules(state))
+	}
+}
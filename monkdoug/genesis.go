@@ -28,6 +28,10 @@ type Account struct {
 	Balance     string         `json:"balance"`
 	Permissions map[string]int `json:"permissions"`
 	Stake       int            `json:"stake"`
+	// Roles and direct grants (like call:<addr>),
+	// each with the block they expire at (0 for never)
+	Roles  map[string]int `json:"roles"`
+	Grants map[string]int `json:"grants"`
 }
 
 type GenesisConfig struct {
@@ -44,7 +48,7 @@ type GenesisConfig struct {
 	Unique bool `json:"unique"`
 	// A private key to seed uniqueness (otherwise is random)
 	PrivateKey string `json:"private-key"`
-	// Name of the gendoug access model (yes, no, std, vm, eth)
+	// Name of the gendoug access model (yes, no, std, vm, eth, bft, or see RegisterModel)
 	ModelName string `json:"model"`
 	// Turn off gendoug
 	NoGenDoug bool `json:"no-gendoug"`
@@ -52,8 +56,10 @@ type GenesisConfig struct {
 	/*
 	   Global GenDoug Singles
 	*/
-	// Consensus/difficulty mechanism (stake, robin, constant, eth)
+	// Consensus/difficulty mechanism (stake-weight, robin, constant, authority, eth)
 	Consensus string `json:"consensus"`
+	// Proof of work algorithm (sha3, dagger, memhard, none)
+	Pow string `json:"pow"`
 	// Starting difficulty level
 	Difficulty int `json:"difficulty"`
 	// Allow anyone to mine
@@ -64,10 +70,33 @@ type GenesisConfig struct {
 	PublicTx int `json:"public:tx"`
 	// Max gas per tx
 	MaxGasTx string `json:"maxgastx"`
+	// Min gas price per tx
+	MinGasPrice string `json:"mingasprice"`
 	// Proof of work difficulty for transactions/
 	TaPoW int `json:"tapow"`
 	// Target block time (shaky...)
 	BlockTime int `json:"blocktime"`
+	// Blocks to wait between unbonding stake and withdrawing it
+	UnbondDelay int `json:"unbond-delay"`
+	// Blocks must be newer than the median time of this many ancestors (0 for just the parent)
+	MedianTimeBlocks int `json:"median-time-blocks"`
+	// How many seconds ahead of our clock a block may be (0 for the default)
+	MaxFutureDrift int `json:"max-future-drift"`
+	// Signatures needed on a checkpoint certificate
+	CheckpointSigs int `json:"checkpoint-sigs"`
+	// Admin votes needed to halt or resume the chain
+	HaltThreshold int `json:"halt-threshold"`
+
+	// Rule changes scheduled by block number
+	Forks []*Fork `json:"forks"`
+
+	// Block reward policy (default is ethereum's)
+	Reward *RewardConfig `json:"reward"`
+
+	// Roles, by the permissions they include
+	Roles map[string][]string `json:"roles"`
+	// Contracts that need a call:<addr> permission to call
+	Restricted []string `json:"restricted"`
 
 	// Paths to lll consensus contracts (if ModelName = vm)
 	Vm *VmConsensus `json:"vm"`
@@ -84,6 +113,7 @@ type GenesisConfig struct {
 	// for verifying blocks/txs
 	protocol  monkchain.Protocol
 	consensus monkchain.Consensus
+	pow       monkchain.PoW
 
 	// Signed genesis block (hex)
 	chainId string
@@ -151,14 +181,14 @@ func LoadGenesis(file string) *GenesisConfig {
 	b, err := ioutil.ReadFile(file)
 	if err != nil {
 		fmt.Println("err reading genesis.json", err)
-		os.Exit(0)
+		os.Exit(1)
 	}
 
 	g := new(GenesisConfig)
 	err = json.Unmarshal(b, g)
 	if err != nil {
 		fmt.Println("error unmarshalling genesis.json", err)
-		os.Exit(0)
+		os.Exit(1)
 	}
 
 	// move address into accounts, in bytes
@@ -200,8 +230,11 @@ func (g *GenesisConfig) Deploy(block *monkchain.Block) ([]byte, error) {
 	if g.NoGenDoug {
 		// simple bankroll accounts
 		g.bankRoll(block)
-		chainId := g.chainIdFromBlock(block, keys)
+		g.deployForks(block)
+		g.deployRewards(block)
+		// update first, so we sign the final state root
 		block.State().Update()
+		chainId := g.chainIdFromBlock(block, keys)
 		block.State().Sync()
 		return chainId, nil
 	}
@@ -229,8 +262,12 @@ func (g *GenesisConfig) Deploy(block *monkchain.Block) ([]byte, error) {
 		g.hookVmDeploy(keys, block)
 	}
 
-	chainId := g.chainIdFromBlock(block, keys)
+	// schedule rule changes
+	g.deployForks(block)
+	g.deployRewards(block)
+
 	block.State().Update()
+	chainId := g.chainIdFromBlock(block, keys)
 	block.State().Sync()
 
 	return chainId, nil
@@ -262,20 +299,44 @@ func (g *GenesisConfig) bankRoll(block *monkchain.Block) {
 
 // Bank roll accounts and add permissions and stake
 func (g *GenesisConfig) bankRollAndPerms(keys *monkcrypto.KeyPair, block *monkchain.Block) {
+	if g.protocol != nil {
+		g.deployRoles(block)
+	}
 	for _, account := range g.Accounts {
 		// direct state modification to create accounts and balances
 		AddAccount(account.byteAddr, account.Balance, block)
 		if g.protocol != nil {
 			// issue txs to set perms according to the model
 			SetPermissions(g.byteAddr, account.byteAddr, account.Permissions, block, keys)
+			g.deployAccountRoles(account, block)
 			if account.Permissions["mine"] != 0 {
 				SetValue(g.byteAddr, []string{"addminer", account.Name, "0x" + account.Address, "0x" + strconv.Itoa(account.Stake)}, keys, block)
 			}
+			if account.Stake != 0 && g.stakeWeighted() {
+				// genesis stake comes already bonded
+				stake := big.NewInt(int64(account.Stake))
+				block.State().GetOrNewStateObject(StakeAddr).AddAmount(stake)
+				AddStake(g.byteAddr, account.byteAddr, stake, block.State())
+			}
 			douglogger.Debugln("Setting permissions for ", account.Address)
 		}
 	}
 }
 
+// Does the chain ever weigh by stake. Other chains leave
+// genesis stakes unbonded, so their state root stays the same
+func (g *GenesisConfig) stakeWeighted() bool {
+	if g.Consensus == "stake-weight" {
+		return true
+	}
+	for _, f := range g.Forks {
+		if f.Consensus == "stake-weight" {
+			return true
+		}
+	}
+	return false
+}
+
 // XXX: Must be unique for production use!
 func (g *GenesisConfig) selectKeyPair() (*monkcrypto.KeyPair, error) {
 	var keys *monkcrypto.KeyPair
@@ -301,15 +362,29 @@ func (g *GenesisConfig) selectKeyPair() (*monkcrypto.KeyPair, error) {
 	return keys, nil
 }
 
+// The global singles set in gendoug, as name, value pairs
+func (g *GenesisConfig) singles() [][2]string {
+	return [][2]string{
+		{"consensus", g.Consensus},
+		{"difficulty", "0x" + monkutil.Bytes2Hex(big.NewInt(int64(g.Difficulty)).Bytes())},
+		{"public:mine", "0x" + strconv.Itoa(g.PublicMine)},
+		{"public:create", "0x" + strconv.Itoa(g.PublicCreate)},
+		{"public:tx", "0x" + strconv.Itoa(g.PublicTx)},
+		{"maxgastx", g.MaxGasTx},
+		{"mingasprice", g.MinGasPrice},
+		{"tapow", "0x" + monkutil.Bytes2Hex(big.NewInt(int64(g.TaPoW)).Bytes())},
+		{"blocktime", "0x" + strconv.Itoa(g.BlockTime)},
+		{"haltthreshold", "0x" + monkutil.Bytes2Hex(big.NewInt(int64(g.HaltThreshold)).Bytes())},
+		{"unbonddelay", "0x" + monkutil.Bytes2Hex(big.NewInt(int64(g.UnbondDelay)).Bytes())},
+		{"mediantimeblocks", "0x" + monkutil.Bytes2Hex(big.NewInt(int64(g.MedianTimeBlocks)).Bytes())},
+	}
+}
+
 // Set some global values in gendoug
 func (g *GenesisConfig) setValues(keys *monkcrypto.KeyPair, block *monkchain.Block) {
-	SetValue(g.byteAddr, []string{"initvar", "consensus", "single", g.Consensus}, keys, block)
-	SetValue(g.byteAddr, []string{"initvar", "difficulty", "single", "0x" + monkutil.Bytes2Hex(big.NewInt(int64(g.Difficulty)).Bytes())}, keys, block)
-	SetValue(g.byteAddr, []string{"initvar", "public:mine", "single", "0x" + strconv.Itoa(g.PublicMine)}, keys, block)
-	SetValue(g.byteAddr, []string{"initvar", "public:create", "single", "0x" + strconv.Itoa(g.PublicCreate)}, keys, block)
-	SetValue(g.byteAddr, []string{"initvar", "public:tx", "single", "0x" + strconv.Itoa(g.PublicTx)}, keys, block)
-	SetValue(g.byteAddr, []string{"initvar", "maxgastx", "single", g.MaxGasTx}, keys, block)
-	SetValue(g.byteAddr, []string{"initvar", "blocktime", "single", "0x" + strconv.Itoa(g.BlockTime)}, keys, block)
+	for _, single := range g.singles() {
+		SetValue(g.byteAddr, []string{"initvar", single[0], "single", single[1]}, keys, block)
+	}
 }
 
 // Options for hooking consensus to the vm
@@ -325,7 +400,27 @@ const (
 // Addresses are stored at standard locations in gendoug
 func (g *GenesisConfig) hookVmDeploy(keys *monkcrypto.KeyPair, block *monkchain.Block) {
 	// TODO: add some logs!
+	m := g.protocol.(*Protocol).consensus.(*VmModel)
+	for _, sc := range g.vmSysCalls() {
+		tag, codePath := sc[0], sc[1]
+		absCodePath := path.Join(g.contractPath, codePath)
+		tx, _, err := MakeApplyTx(absCodePath, nil, nil, keys, block)
+		if err == nil {
+			s := SysCall{
+				byteAddr: tx.CreationAddress(),
+				CodePath: absCodePath,
+			}
+			m.contract[tag] = s
+			douglogger.Infof("Setting contract address in GENDOUG for %s (%s) : %x\n", tag, codePath, s.byteAddr)
+			SetValue(g.byteAddr, []string{"initvar", tag, "single", "0x" + monkutil.Bytes2Hex(s.byteAddr)}, keys, block)
+		}
+	}
+	//TODO handle final element in Vm struct (list of SysCalls)
+}
 
+// The syscalls to deploy for "vm" consensus, as tag, code path pairs.
+// Only those with a contract (VmScriptTy) are included
+func (g *GenesisConfig) vmSysCalls() [][2]string {
 	// grab the suite, if any
 	suite := suites["default"]
 	if s, ok := suites[g.Vm.SuiteName]; ok {
@@ -333,41 +428,22 @@ func (g *GenesisConfig) hookVmDeploy(keys *monkcrypto.KeyPair, block *monkchain.
 	}
 
 	// loop through g.Vm fields
-	// deploy the non-nil ones
 	// fall back order: g.Vm > suite > defaults
-	m := g.protocol.(*Protocol).consensus.(*VmModel)
+	sysCalls := [][2]string{}
 	gvm := reflect.ValueOf(g.Vm).Elem()
 	svm := reflect.ValueOf(suite).Elem()
 	// Skip first and last (suite name, others)
 	for i := 1; i < gvm.NumField()-1; i++ {
-		// default mode (if a contract is provided)
-		mode := VmDefTy
 		// grab fields from struct
 		_, tag, codePath := nameTagPath(gvm, i)
-		if codePath != "" {
-			mode = VmScriptTy
-		} else if suite != nil {
+		if codePath == "" && suite != nil {
 			_, _, codePath = nameTagPath(svm, i)
-			if codePath != "" {
-				mode = VmScriptTy
-			}
 		}
-
-		if mode > 0 {
-			absCodePath := path.Join(g.contractPath, codePath)
-			tx, _, err := MakeApplyTx(absCodePath, nil, nil, keys, block)
-			if err == nil {
-				s := SysCall{
-					byteAddr: tx.CreationAddress(),
-					CodePath: absCodePath,
-				}
-				m.contract[tag] = s
-				douglogger.Infof("Setting contract address in GENDOUG for %s (%s) : %x\n", tag, codePath, s.byteAddr)
-				SetValue(g.byteAddr, []string{"initvar", tag, "single", "0x" + monkutil.Bytes2Hex(s.byteAddr)}, keys, block)
-			}
+		if codePath != "" {
+			sysCalls = append(sysCalls, [2]string{tag, codePath})
 		}
 	}
-	//TODO handle final element in Vm struct (list of SysCalls)
+	return sysCalls
 }
 
 // return field name, tag, and codepath
@@ -408,48 +484,44 @@ func AddAccount(addr []byte, balance string, block *monkchain.Block) {
 
 //
 func NewProtocol(g *GenesisConfig) monkchain.Protocol {
-	consensus := NewPermModel(g)
-	p := &Protocol{g: g, consensus: consensus}
+	// the hashing rules are set once and for all here
+	pow, err := monkchain.NewPoW(g.Pow)
+	if err != nil {
+		douglogger.Fatalln(err)
+	}
+	g.pow = pow
+
+	consensus, err := NewPermModel(g)
+	if err != nil {
+		douglogger.Fatalln(err)
+	}
+	p := &Protocol{g: g, consensus: consensus, forkModels: make(map[string]monkchain.Consensus)}
 	return p
 }
 
-// Return a new permissions model
+// Return a new permissions model from the registry
 // Only "std" and "vm" care about gendoug
 // NoGendoug defaults to the "yes" model
-func NewPermModel(g *GenesisConfig) (model monkchain.Consensus) {
+func NewPermModel(g *GenesisConfig) (monkchain.Consensus, error) {
 	modelName := g.ModelName
 	if g.NoGenDoug {
-		modelName = "yes"
+		modelName = DefaultModel
+	}
+	ctor, err := lookupModel(modelName)
+	if err != nil {
+		return nil, err
 	}
-	switch modelName {
-	case "std":
-		// gendoug-v2
-		// uses eris-std-lib/gotests/vars for reading
-		// from gendoug
-		model = NewStdLibModel(g)
-	case "vm":
-		// run processing through the vm
-		model = NewVmModel(g)
-	case "yes":
-		// everyone allowed everything
-		model = NewYesModel(g)
-	case "no":
-		// noone allowed anything
-		model = NewNoModel(g)
-	case "eth":
-		// ethereum
+	// only the genesis model decides this, never a forked one
+	if noGenDougModel(modelName) {
 		g.NoGenDoug = true
-		model = NewEthModel(g)
-	default:
-		// default to yes
-		model = NewYesModel(g)
 	}
-	return
+	return ctor(g), nil
 }
 
 // A default genesis.json
 // TODO: make a lookup-able suite of these
-var DefaultGenesis = defaultGenesis()
+// (set in init, once the models are registered)
+var DefaultGenesis *GenesisConfig
 
 func defaultGenesis() *GenesisConfig {
 	g := &GenesisConfig{
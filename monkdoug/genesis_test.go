@@ -0,0 +1,57 @@
+package monkdoug
+
+import (
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func TestValidateChainID(t *testing.T) {
+	g := &GenesisConfig{
+		NoGenDoug: true,
+		ModelName: "yes",
+		Accounts: []*Account{
+			{byteAddr: monkcrypto.GenerateNewKeyPair().Address(), Balance: "1000"},
+		},
+	}
+	g.Init()
+	block := monkchain.NewBlockFromBytes(monkutil.Encode(monkchain.Genesis))
+	chainId, err := g.Deploy(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// as loaded from the db
+	block = monkchain.NewBlockFromBytes(block.RlpEncode())
+	p := g.Model()
+	if err := p.ValidateChainID(chainId, block); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ValidateChainID([]byte("01234567890123456789"), block); !monkchain.IsGenesisCertErr(err) {
+		t.Fatal("expected an error for the wrong chainId, got", err)
+	}
+
+	// the certificate goes over the wire
+	cert := monkchain.NewGenesisCert(block)
+	cert = monkchain.NewGenesisCertFromValue(monkutil.NewValueFromBytes(monkutil.Encode(cert.RlpData())))
+	if err := cert.Verify(chainId); err != nil {
+		t.Fatal(err)
+	}
+	cert.PubKey = monkcrypto.GenerateNewKeyPair().PublicKey
+	if err := cert.Verify(chainId); err == nil {
+		t.Fatal("expected an error for the wrong pubkey")
+	}
+
+	defer func() { TrustedSigners = nil }()
+	TrustedSigners = [][]byte{monkcrypto.GenerateNewKeyPair().Address()}
+	if err := p.ValidateChainID(chainId, block); !monkchain.IsGenesisCertErr(err) {
+		t.Fatal("expected an error for an untrusted signer, got", err)
+	}
+	keys, _ := g.selectKeyPair()
+	TrustedSigners = append(TrustedSigners, keys.Address())
+	if err := p.ValidateChainID(chainId, block); err != nil {
+		t.Fatal(err)
+	}
+}
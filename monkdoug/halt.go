@@ -0,0 +1,114 @@
+package monkdoug
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+/*
+   Emergency halt for the StdLibModel.
+
+   Admins vote with system txs to HaltAddr, the command being
+   "halt" or "resume". Once halt-threshold different admins have
+   voted for the change it happens, and the votes start over.
+   Admin can't be delegated (see roles.go), so the votes are from
+   separate genesis keys.
+   While the chain is halted only admins can transact, so miners
+   are left making empty heartbeat blocks until it resumes.
+
+   Records live in GenDoug's storage with the roles:
+       sha3(rlp("halted"))                   - 1 while halted
+       sha3(rlp("halt:round"))               - bumped every time the flag flips
+       sha3(rlp("halt:votes", round))        - votes so far this round
+       sha3(rlp("halt:vote", round, addr))   - addr voted this round
+*/
+
+// Receives the halt and resume votes
+var HaltAddr = monkutil.LeftPadBytes([]byte("halt"), 20)
+
+func IsHalted(doug []byte, state *monkstate.State) bool {
+	return getRoleValue(doug, roleKey("halted"), state).Uint() != 0
+}
+
+func setHalted(doug []byte, halted bool, state *monkstate.State) {
+	v := uint64(0)
+	if halted {
+		v = 1
+	}
+	setRoleValue(doug, roleKey("halted"), v, state)
+	round := getRoleValue(doug, roleKey("halt:round"), state).Uint()
+	setRoleValue(doug, roleKey("halt:round"), round+1, state)
+}
+
+func (m *StdLibModel) haltThreshold(state *monkstate.State) uint64 {
+	def := uint64(1)
+	if m.g.HaltThreshold > 0 {
+		def = uint64(m.g.HaltThreshold)
+	}
+	return singleUint(m.doug, "haltthreshold", state, def)
+}
+
+func (m *StdLibModel) Halted(state *monkstate.State) bool {
+	return IsHalted(m.doug, state)
+}
+
+// Count an admin's vote to halt or resume
+func (m *StdLibModel) applyHaltTx(tx *monkchain.Transaction, state *monkstate.State, block *monkchain.Block) error {
+	if tx.Value.Sign() != 0 {
+		return fmt.Errorf("Halt txs do not take a value")
+	}
+	if len(tx.Data) < 32 {
+		return fmt.Errorf("Halt tx missing command")
+	}
+	sender := tx.Sender()
+	if m.PermExpiry(sender, "admin", state) == 0 {
+		return monkchain.InvalidPermError(sender, "admin")
+	}
+
+	halted := IsHalted(m.doug, state)
+	switch cmd := string(bytes.TrimLeft(tx.Data[:32], "\x00")); cmd {
+	case "halt":
+		if halted {
+			return fmt.Errorf("Chain is already halted")
+		}
+	case "resume":
+		if !halted {
+			return fmt.Errorf("Chain is not halted")
+		}
+	default:
+		return fmt.Errorf("Unknown halt command %s", cmd)
+	}
+
+	round := getRoleValue(m.doug, roleKey("halt:round"), state).Uint()
+	voteKey := roleKey("halt:vote", round, sender)
+	if getRoleValue(m.doug, voteKey, state).Uint() != 0 {
+		return fmt.Errorf("%x already voted", sender)
+	}
+	setRoleValue(m.doug, voteKey, uint64(1), state)
+	votes := getRoleValue(m.doug, roleKey("halt:votes", round), state).Uint() + 1
+	setRoleValue(m.doug, roleKey("halt:votes", round), votes, state)
+
+	if threshold := m.haltThreshold(state); votes < threshold {
+		douglogger.Infof("Vote by %x to change the halt (%d of %d)\n", sender, votes, threshold)
+		return nil
+	}
+	setHalted(m.doug, !halted, state)
+	if halted {
+		douglogger.Infof("Chain resumed at block #%d\n", block.Number)
+	} else {
+		douglogger.Infof("Chain halted at block #%d\n", block.Number)
+	}
+	return nil
+}
+
+// Pass through to the consensus, if it can be halted
+func (p *Protocol) Halted(state *monkstate.State) bool {
+	if h, ok := p.model(state).(monkchain.Halter); ok {
+		return h.Halted(state)
+	}
+	return false
+}
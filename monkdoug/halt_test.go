@@ -0,0 +1,112 @@
+package monkdoug
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func TestHaltThreshold(t *testing.T) {
+	m, state := newStakeTestModel()
+	m.g.HaltThreshold = 2
+	admins := []*monkcrypto.KeyPair{monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair()}
+	user := monkcrypto.GenerateNewKeyPair()
+	for _, keys := range append(admins, user) {
+		GrantPerm(m.doug, keys.Address(), "transact", NoExpiry, state)
+	}
+	for _, keys := range admins {
+		GrantPerm(m.doug, keys.Address(), "admin", NoExpiry, state)
+	}
+
+	if err := applySystemTx(m, state, user, systemTx(HaltAddr, 0, "halt"), 5); err == nil {
+		t.Fatal("expected a permission error")
+	}
+	// one admin can't make another to reach the threshold alone
+	hexUser := "0x" + monkutil.Bytes2Hex(user.Address())
+	if err := applySystemTx(m, state, admins[0], systemTx(PermsAddr, 0, "grant", hexUser, "admin"), 5); err == nil {
+		t.Fatal("expected admin to be undelegable")
+	}
+	if err := applySystemTx(m, state, admins[0], systemTx(PermsAddr, 0, "define-role", "sockpuppet", "admin"), 5); err == nil {
+		t.Fatal("expected a role with admin to be refused")
+	}
+	DefineRole(m.doug, "genesis-admin", []string{"admin"}, state)
+	if err := applySystemTx(m, state, admins[0], systemTx(PermsAddr, 0, "grant-role", hexUser, "genesis-admin"), 5); err == nil {
+		t.Fatal("expected a role with admin to be undelegable")
+	}
+	if err := applySystemTx(m, state, admins[0], systemTx(HaltAddr, 0, "halt"), 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := applySystemTx(m, state, admins[0], systemTx(HaltAddr, 0, "halt"), 5); err == nil {
+		t.Fatal("expected an error for voting twice")
+	}
+	if m.Halted(state) {
+		t.Fatal("halted on one vote")
+	}
+	if err := applySystemTx(m, state, admins[1], systemTx(HaltAddr, 0, "halt"), 5); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Halted(state) {
+		t.Fatal("expected the chain to be halted")
+	}
+
+	// only admins get through
+	tx := monkchain.NewTransactionMessage(user.Address(), big.NewInt(0), big.NewInt(1000), big.NewInt(0), nil)
+	tx.Sign(user.PrivateKey)
+	if err := m.ValidateTx(tx, state); !monkchain.IsHaltedErr(err) {
+		t.Fatal("expected a halted error, got", err)
+	}
+	tx = monkchain.NewTransactionMessage(user.Address(), big.NewInt(0), big.NewInt(1000), big.NewInt(0), nil)
+	tx.Sign(admins[0].PrivateKey)
+	if err := m.ValidateTx(tx, state); err != nil {
+		t.Fatal(err)
+	}
+
+	// the votes start over for resuming
+	if err := applySystemTx(m, state, admins[0], systemTx(HaltAddr, 0, "resume"), 5); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Halted(state) {
+		t.Fatal("resumed on one vote")
+	}
+	if err := applySystemTx(m, state, admins[1], systemTx(HaltAddr, 0, "resume"), 5); err != nil {
+		t.Fatal(err)
+	}
+	if m.Halted(state) {
+		t.Fatal("expected the chain to resume")
+	}
+}
+
+func TestHaltedBlocks(t *testing.T) {
+	m, _ := newStakeTestModel()
+	m.pow = &monkchain.EasyPow{}
+	miner, admin, user := monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair()
+	parent := storedParent(func(state *monkstate.State) {
+		GrantPerm(m.doug, miner.Address(), "mine", NoExpiry, state)
+		GrantPerm(m.doug, admin.Address(), "admin", NoExpiry, state)
+		setHalted(m.doug, true, state)
+	})
+	bc := &monkchain.ChainManager{}
+	withTx := func(keys *monkcrypto.KeyPair) *monkchain.Block {
+		tx := monkchain.NewTransactionMessage(HaltAddr, big.NewInt(0), big.NewInt(10000), big.NewInt(1), monkutil.PackTxDataArgs2("resume"))
+		tx.Sign(keys.PrivateKey)
+		block := sealedBlock(m, miner, parent, nil)
+		block.SetReceipts(nil, monkchain.Transactions{tx})
+		block.Sign(miner.PrivateKey)
+		return block
+	}
+
+	// heartbeats and admin txs are fine
+	if err := m.ValidateBlock(sealedBlock(m, miner, parent, nil), bc); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ValidateBlock(withTx(admin), bc); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ValidateBlock(withTx(user), bc); err == nil {
+		t.Fatal("expected a block with a user tx to be invalid while halted")
+	}
+}
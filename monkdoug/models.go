@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"math/big"
+	"sync"
 	"time"
 	//"log"
 	vars "github.com/eris-ltd/eris-std-lib/go-tests"
@@ -14,9 +15,23 @@ import (
 
 var Adversary = 0
 
+// Read a gendoug single. Tests swap it out,
+// since the std lib's storage layout isn't ours
+var getSingle = func(doug []byte, name string, state *monkstate.State) []byte {
+	return vars.GetSingle(doug, name, state)
+}
+
+// Addresses trusted to sign genesis blocks.
+// If empty, any correctly signed genesis is accepted
+var TrustedSigners [][]byte
+
 type Protocol struct {
 	g         *GenesisConfig
 	consensus monkchain.Consensus
+
+	// models switched to by forks
+	forkModels map[string]monkchain.Consensus
+	modelMut   sync.Mutex
 }
 
 func (p *Protocol) Doug() []byte {
@@ -28,33 +43,140 @@ func (p *Protocol) Deploy(block *monkchain.Block) ([]byte, error) {
 	return p.g.Deployer(block)
 }
 
+func (p *Protocol) Consensus() monkchain.Consensus {
+	return p.consensus
+}
+
+func (p *Protocol) PoW() monkchain.PoW {
+	return p.g.pow
+}
+
+// The genesis signature must give the chainId,
+// and the signer must be trusted
 func (p *Protocol) ValidateChainID(chainId []byte, genesisBlock *monkchain.Block) error {
-	return nil
+	cert := monkchain.NewGenesisCert(genesisBlock)
+	if err := cert.Verify(chainId); err != nil {
+		return err
+	}
+	if len(TrustedSigners) == 0 {
+		return nil
+	}
+	signer := cert.Signer()
+	for _, trusted := range TrustedSigners {
+		if bytes.Equal(trusted, signer) {
+			return nil
+		}
+	}
+	return monkchain.GenesisCertError("Genesis signer %x is not trusted", signer)
 }
 
 // Determine whether to accept a new checkpoint
 func (p *Protocol) Participate(coinbase []byte, parent *monkchain.Block) bool {
-	return p.consensus.Participate(coinbase, parent)
+	return p.model(parent.State()).Participate(coinbase, parent)
 }
 
 func (p *Protocol) Difficulty(block, parent *monkchain.Block) *big.Int {
-	return p.consensus.Difficulty(block, parent)
+	return p.model(parent.State()).Difficulty(block, parent)
+}
+
+// The time rules are in the genesis, whatever the model
+func (p *Protocol) NextBlockTime(parent *monkchain.Block, bc *monkchain.ChainManager) int64 {
+	return p.g.NextBlockTime(parent, bc)
 }
 
 func (p *Protocol) ValidatePerm(addr []byte, role string, state *monkstate.State) error {
-	return p.consensus.ValidatePerm(addr, role, state)
+	return p.model(state).ValidatePerm(addr, role, state)
 }
 
 func (p *Protocol) ValidateBlock(block *monkchain.Block, bc *monkchain.ChainManager) error {
-	return p.consensus.ValidateBlock(block, bc)
+	parent := bc.GetBlock(block.PrevHash)
+	if parent == nil {
+		parent = bc.CurrentBlock()
+	}
+	return p.model(parent.State()).ValidateBlock(block, bc)
 }
 
 func (p *Protocol) ValidateTx(tx *monkchain.Transaction, state *monkstate.State) error {
-	return p.consensus.ValidateTx(tx, state)
+	return p.model(state).ValidateTx(tx, state)
 }
 
-func (p *Protocol) CheckPoint(proposed []byte, bc *monkchain.ChainManager) bool {
-	return p.consensus.CheckPoint(proposed, bc)
+// Pass through to the consensus, if it has a fee floor
+func (p *Protocol) MinGasPrice(state *monkstate.State) *big.Int {
+	if fees, ok := p.model(state).(monkchain.FeePolicy); ok {
+		return fees.MinGasPrice(state)
+	}
+	return new(big.Int)
+}
+
+// Pass through to the consensus, if it wants work on txs
+func (p *Protocol) TxWorkDifficulty(state *monkstate.State) *big.Int {
+	if work, ok := p.model(state).(monkchain.TxWork); ok {
+		return work.TxWorkDifficulty(state)
+	}
+	return nil
+}
+
+func (p *Protocol) CheckPoint(cert *monkchain.CheckpointCert, bc *monkchain.ChainManager) bool {
+	return p.model(bc.CurrentBlock().State()).CheckPoint(cert, bc)
+}
+
+// Pass through to the consensus, if it commits blocks
+func (p *Protocol) VerifyCommit(commit *monkutil.Value, bc *monkchain.ChainManager) ([]byte, uint64, error) {
+	if committer, ok := p.model(bc.CurrentBlock().State()).(monkchain.Committer); ok {
+		return committer.VerifyCommit(commit, bc)
+	}
+	return nil, 0, fmt.Errorf("Consensus does not commit blocks")
+}
+
+// Fork txs, or pass through to the consensus, if it has system txs
+func (p *Protocol) IsSystemTx(tx *monkchain.Transaction, state *monkstate.State) bool {
+	if bytes.Equal(tx.Recipient, ForkAddr) {
+		return true
+	}
+	if sys, ok := p.model(state).(monkchain.SystemTxs); ok {
+		return sys.IsSystemTx(tx, state)
+	}
+	return false
+}
+
+func (p *Protocol) ApplySystemTx(tx *monkchain.Transaction, state *monkstate.State, block *monkchain.Block) error {
+	if bytes.Equal(tx.Recipient, ForkAddr) {
+		return p.scheduleFork(tx, state, block)
+	}
+	if sys, ok := p.model(state).(monkchain.SystemTxs); ok {
+		return sys.ApplySystemTx(tx, state, block)
+	}
+	return fmt.Errorf("No system txs")
+}
+
+// Pass through to the consensus, if it has block hooks
+func (p *Protocol) PreBlock(state *monkstate.State, block *monkchain.Block) *monkchain.HookReceipt {
+	if hooks, ok := p.model(state).(monkchain.BlockHooks); ok {
+		return hooks.PreBlock(state, block)
+	}
+	return nil
+}
+
+func (p *Protocol) PostBlock(state *monkstate.State, block *monkchain.Block) *monkchain.HookReceipt {
+	if hooks, ok := p.model(state).(monkchain.BlockHooks); ok {
+		return hooks.PostBlock(state, block)
+	}
+	return nil
+}
+
+func (p *Protocol) HasHook(name string, state *monkstate.State) bool {
+	if hooks, ok := p.model(state).(monkchain.BlockHooks); ok {
+		return hooks.HasHook(name, state)
+	}
+	return false
+}
+
+// Pass through to the consensus, if it seals by signature
+func (p *Protocol) SealTime(coinbase []byte, parent *monkchain.Block) (time.Time, bool) {
+	if sealer, ok := p.model(parent.State()).(monkchain.Sealer); ok {
+		return sealer.SealTime(coinbase, parent)
+	}
+	return time.Time{}, false
 }
 
 // The yes model grants all permissions
@@ -83,11 +205,20 @@ func (m *YesModel) ValidateBlock(block *monkchain.Block, bc *monkchain.ChainMana
 }
 
 func (m *YesModel) ValidateTx(tx *monkchain.Transaction, state *monkstate.State) error {
+	if diff := m.TxWorkDifficulty(state); !tx.VerifyWork(diff) {
+		return monkchain.TxWorkError(tx.Hash(), diff)
+	}
 	return nil
 }
 
-func (m *YesModel) CheckPoint(proposed []byte, bc *monkchain.ChainManager) bool {
-	return true
+func (m *YesModel) TxWorkDifficulty(state *monkstate.State) *big.Int {
+	return genesisTaPoW(m.g)
+}
+
+// Only bare proposals from our own config. Anyone can sign,
+// so a signed cert off the wire proves nothing here
+func (m *YesModel) CheckPoint(cert *monkchain.CheckpointCert, bc *monkchain.ChainManager) bool {
+	return len(cert.Sigs) == 0
 }
 
 // The no model grants no permissions
@@ -121,7 +252,7 @@ func (m *NoModel) ValidateTx(tx *monkchain.Transaction, state *monkstate.State)
 	return fmt.Errorf("No!")
 }
 
-func (m *NoModel) CheckPoint(proposed []byte, bc *monkchain.ChainManager) bool {
+func (m *NoModel) CheckPoint(cert *monkchain.CheckpointCert, bc *monkchain.ChainManager) bool {
 	return false
 }
 
@@ -266,6 +397,12 @@ func (m *VmModel) ValidateBlock(block *monkchain.Block, bc *monkchain.ChainManag
 }
 
 func (m *VmModel) ValidateTx(tx *monkchain.Transaction, state *monkstate.State) error {
+	if min := m.MinGasPrice(state); tx.GasPrice.Cmp(min) < 0 {
+		return monkchain.GasPriceError(tx.GasPrice, min)
+	}
+	if diff := m.TxWorkDifficulty(state); !tx.VerifyWork(diff) {
+		return monkchain.TxWorkError(tx.Hash(), diff)
+	}
 	if scall, ok := m.getSysCall("tx-verify", state); ok {
 		addr := scall.byteAddr
 		obj, code := m.pickCallObjAndCode(addr, state)
@@ -289,9 +426,76 @@ func (m *VmModel) ValidateTx(tx *monkchain.Transaction, state *monkstate.State)
 	return m.ValidatePerm(tx.Sender(), perm, state)
 }
 
-func (m *VmModel) CheckPoint(proposed []byte, bc *monkchain.ChainManager) bool {
-	// TODO: checkpoint validation contract
-	return true
+func (m *VmModel) TxWorkDifficulty(state *monkstate.State) *big.Int {
+	return tapowDifficulty(m.doug, state)
+}
+
+func (m *VmModel) MinGasPrice(state *monkstate.State) *big.Int {
+	return minGasPrice(m.doug, state)
+}
+
+// Run the precall contract before the block's txs
+func (m *VmModel) PreBlock(state *monkstate.State, block *monkchain.Block) *monkchain.HookReceipt {
+	return m.blockHook("precall", state, block)
+}
+
+// Run the postcall contract after the block's txs
+func (m *VmModel) PostBlock(state *monkstate.State, block *monkchain.Block) *monkchain.HookReceipt {
+	return m.blockHook("postcall", state, block)
+}
+
+func (m *VmModel) HasHook(name string, state *monkstate.State) bool {
+	switch name {
+	case "pre":
+		return m.hookObj("precall", state) != nil
+	case "post":
+		return m.hookObj("postcall", state) != nil
+	}
+	return false
+}
+
+// The contract behind a hook, if there is one
+func (m *VmModel) hookObj(name string, state *monkstate.State) *monkstate.StateObject {
+	scall, ok := m.getSysCall(name, state)
+	if !ok {
+		return nil
+	}
+	obj := state.GetStateObject(scall.byteAddr)
+	if obj == nil || len(obj.Code) == 0 {
+		return nil
+	}
+	return obj
+}
+
+// Hook contracts get the block's number, coinbase, time and difficulty
+// as 32 byte words, and BlockHookGas to do their thing with
+func (m *VmModel) blockHook(name string, state *monkstate.State, block *monkchain.Block) *monkchain.HookReceipt {
+	obj := m.hookObj(name, state)
+	if obj == nil {
+		return nil
+	}
+
+	data := monkutil.LeftPadBytes(block.Number.Bytes(), 32)
+	data = append(data, monkutil.LeftPadBytes(block.Coinbase, 32)...)
+	data = append(data, monkutil.LeftPadBytes(big.NewInt(block.Time).Bytes(), 32)...)
+	data = append(data, monkutil.LeftPadBytes(block.Difficulty.Bytes(), 32)...)
+
+	douglogger.Debugf("Calling %s contract for block #%d\n", name, block.Number)
+	ret, gasUsed, err := m.hookCall(obj, data, state, block)
+	receipt := &monkchain.HookReceipt{Name: name, GasUsed: gasUsed, Output: ret}
+	if err != nil {
+		receipt.Err = err.Error()
+	}
+	return receipt
+}
+
+// Checkpoints need enough signers with the "checkpoint" permission
+// TODO: checkpoint validation contract
+func (m *VmModel) CheckPoint(cert *monkchain.CheckpointCert, bc *monkchain.ChainManager) bool {
+	state := bc.CurrentBlock().State()
+	return certified(cert, m.g.CheckpointSigs, func(addr []byte) bool {
+		return m.ValidatePerm(addr, "checkpoint", state) == nil
+	})
 }
 
 // The stdlib model grants permissions based on the state of the gendoug
@@ -308,12 +512,12 @@ func NewStdLibModel(g *GenesisConfig) monkchain.Consensus {
 		base: new(big.Int),
 		doug: g.byteAddr,
 		g:    g,
-		pow:  &monkchain.EasyPow{},
+		pow:  g.pow,
 	}
 }
 
 func (m *StdLibModel) GetPermission(addr []byte, perm string, state *monkstate.State) *monkutil.Value {
-	public := vars.GetSingle(m.doug, "public:"+perm, state)
+	public := getSingle(m.doug, "public:"+perm, state)
 	// A stand-in for a one day more sophisticated system
 	if len(public) > 0 {
 		return monkutil.NewValue(1)
@@ -325,7 +529,12 @@ func (m *StdLibModel) GetPermission(addr []byte, perm string, state *monkstate.S
 	return monkutil.NewValue(permStr)
 }
 
+// Flat, granted, or through a role (see roles.go)
 func (m *StdLibModel) HasPermission(addr []byte, perm string, state *monkstate.State) bool {
+	return m.PermExpiry(addr, perm, state) > 0
+}
+
+func (m *StdLibModel) hasFlatPermission(addr []byte, perm string, state *monkstate.State) bool {
 	permBig := m.GetPermission(addr, perm, state).BigInt()
 	return permBig.Int64() > 0
 }
@@ -338,6 +547,10 @@ func (m *StdLibModel) Participate(coinbase []byte, parent *monkchain.Block) bool
 	}
 
 	consensus := m.consensus(parent.State())
+	// no point mining without stake
+	if consensus == "stake-weight" {
+		return GetStake(m.doug, coinbase, parent.State()).Sign() > 0
+	}
 	// if we're not in a round robin, always mine
 	if consensus != "robin" {
 		return true
@@ -359,7 +572,7 @@ func (m *StdLibModel) Participate(coinbase []byte, parent *monkchain.Block) bool
 	// if we're more than halfway, but enough time has gone by, we should mine
 	mDiff := i - int(nMiners/2)
 	t := parent.Time
-	cur := time.Now().Unix()
+	cur := Clock().Unix()
 	blocktime := m.blocktime(parent.State())
 	tDiff := (cur - t) / blocktime
 	if tDiff > int64(mDiff) {
@@ -379,6 +592,8 @@ func (m *StdLibModel) Difficulty(block, parent *monkchain.Block) *big.Int {
 	switch consensus {
 	case "robin":
 		b = m.RoundRobinDifficulty(block, parent)
+	case "authority":
+		b = m.AuthorityDifficulty(block, parent)
 	case "stake-weight":
 		b = m.StakeDifficulty(block, parent)
 	case "constant":
@@ -418,6 +633,22 @@ func (m *StdLibModel) ValidateBlock(block *monkchain.Block, bc *monkchain.ChainM
 		return monkchain.InvalidSigError(block.Signer(), block.Coinbase)
 	}
 
+	// while halted, blocks are heartbeats with admin txs at most
+	if IsHalted(m.doug, prevBlock.State()) {
+		for _, tx := range block.Transactions() {
+			if !m.HasPermission(tx.Sender(), "admin", prevBlock.State()) {
+				return monkchain.ValidationError("Block #%v has a tx from %x while the chain is halted", block.Number, tx.Sender())
+			}
+		}
+	}
+
+	// miners must have stake in the game
+	if m.consensus(prevBlock.State()) == "stake-weight" {
+		if GetStake(m.doug, block.Coinbase, prevBlock.State()).Sign() <= 0 {
+			return monkchain.ValidationError("Miner %x has no bonded stake", block.Coinbase)
+		}
+	}
+
 	// check if the block difficulty is correct
 	// it must be specified exactly
 	newdiff := m.Difficulty(block, prevBlock)
@@ -430,19 +661,28 @@ func (m *StdLibModel) ValidateBlock(block *monkchain.Block, bc *monkchain.ChainM
 	// Do we even budget for lists of signers/forgers and all
 	// that nutty PoS stuff?
 
-	// check block times
-	if err := CheckBlockTimes(prevBlock, block); err != nil {
-		return err
-	}
-
-	// Verify the nonce of the block. Return an error if it's not valid
+	// Verify the nonce of the block. Return an error if it's not valid.
+	// In authority mode the signature is the seal and there is no nonce.
+	// This goes before the times, so only sealed blocks wait in the future queue
 	// TODO: for now we leave pow on everything
 	// soon we will want to generalize/relieve
 	// also, variable hashing algos
-	if !m.pow.Verify(block.HashNoNonce(), block.Difficulty, block.Nonce) {
+	sealTime, signed := m.SealTime(block.Coinbase, prevBlock)
+	if !signed && !m.pow.Verify(block.HashNoNonce(), block.Difficulty, block.Nonce) {
 		return monkchain.ValidationError("Block's nonce is invalid (= %v)", monkutil.Bytes2Hex(block.Nonce))
 	}
 
+	// check block times
+	if err := m.g.CheckBlockTimes(bc, prevBlock, block); err != nil {
+		return err
+	}
+
+	// signers must wait out the block time, and out of turn
+	// signers a block time more for every place they're behind
+	if signed && block.Time < sealTime.Unix() {
+		return monkchain.ValidationError("Block sealed too early (%v < %v)", block.Time, sealTime.Unix())
+	}
+
 	return nil
 }
 
@@ -451,6 +691,11 @@ func (m *StdLibModel) ValidateTx(tx *monkchain.Transaction, state *monkstate.Sta
 		return nil
 	}
 
+	// only admins get through while the chain is halted
+	if IsHalted(m.doug, state) && !m.HasPermission(tx.Sender(), "admin", state) {
+		return monkchain.HaltedError(tx.Sender())
+	}
+
 	// check that sender has permission to transact or create
 	var perm string
 	if tx.IsContract() {
@@ -461,13 +706,30 @@ func (m *StdLibModel) ValidateTx(tx *monkchain.Transaction, state *monkstate.Sta
 	if !m.HasPermission(tx.Sender(), perm, state) {
 		return monkchain.InvalidPermError(tx.Sender(), perm)
 	}
+	// and to call the contract, if it's restricted
+	if !tx.IsContract() && IsRestricted(m.doug, tx.Recipient, state) {
+		if perm := CallPerm(tx.Recipient); !m.HasPermission(tx.Sender(), perm, state) {
+			return monkchain.InvalidPermError(tx.Sender(), perm)
+		}
+	}
 	// check that tx uses less than maxgas
 	gas := tx.GasValue()
-	max := vars.GetSingle(m.doug, "maxgastx", state)
+	max := getSingle(m.doug, "maxgastx", state)
 	maxBig := monkutil.BigD(max)
+	if r := ActiveRules(state); r.MaxGasTx != "" {
+		max, maxBig = []byte(r.MaxGasTx), monkutil.Big(r.MaxGasTx)
+	}
 	if max != nil && gas.Cmp(maxBig) > 0 {
 		return monkchain.GasLimitTxError(gas, maxBig)
 	}
+	// and pays at least the min gas price
+	if min := m.MinGasPrice(state); tx.GasPrice.Cmp(min) < 0 {
+		return monkchain.GasPriceError(tx.GasPrice, min)
+	}
+	// and has done its work
+	if diff := m.TxWorkDifficulty(state); !tx.VerifyWork(diff) {
+		return monkchain.TxWorkError(tx.Hash(), diff)
+	}
 	// Make sure this transaction's nonce is correct
 	sender := state.GetOrNewStateObject(tx.Sender())
 	if sender.Nonce != tx.Nonce {
@@ -476,9 +738,59 @@ func (m *StdLibModel) ValidateTx(tx *monkchain.Transaction, state *monkstate.Sta
 	return nil
 }
 
-func (m *StdLibModel) CheckPoint(proposed []byte, bc *monkchain.ChainManager) bool {
-	// TODO: something reasonable
-	return true
+func (m *StdLibModel) MinGasPrice(state *monkstate.State) *big.Int {
+	return minGasPrice(m.doug, state)
+}
+
+func (m *StdLibModel) TxWorkDifficulty(state *monkstate.State) *big.Int {
+	return tapowDifficulty(m.doug, state)
+}
+
+// A number single from gendoug, or def if it isn't set
+func singleUint(doug []byte, name string, state *monkstate.State, def uint64) uint64 {
+	if v := monkutil.BigD(getSingle(doug, name, state)).Uint64(); v > 0 {
+		return v
+	}
+	return def
+}
+
+// The gendoug fee floor
+func minGasPrice(doug []byte, state *monkstate.State) *big.Int {
+	return monkutil.BigD(getSingle(doug, "mingasprice", state))
+}
+
+// Work required on txs, from the gendoug tapow single
+func tapowDifficulty(doug []byte, state *monkstate.State) *big.Int {
+	return monkchain.TaPoWDifficulty(monkutil.BigD(getSingle(doug, "tapow", state)).Uint64())
+}
+
+// Work required on txs for models without a gendoug
+// to read it from, so it's fixed at genesis
+func genesisTaPoW(g *GenesisConfig) *big.Int {
+	return monkchain.TaPoWDifficulty(uint64(g.TaPoW))
+}
+
+// Checkpoints need enough signers with the "checkpoint" permission
+func (m *StdLibModel) CheckPoint(cert *monkchain.CheckpointCert, bc *monkchain.ChainManager) bool {
+	if Adversary != 0 {
+		return true
+	}
+	state := bc.CurrentBlock().State()
+	return certified(cert, m.g.CheckpointSigs, func(addr []byte) bool {
+		return m.HasPermission(addr, "checkpoint", state)
+	})
+}
+
+// In authority mode, the in-turn signer seals one blocktime after the parent.
+// Everyone else waits an extra blocktime for each place they are behind it,
+// so an out-of-turn block only shows up when the in-turn signer is missing
+func (m *StdLibModel) SealTime(coinbase []byte, parent *monkchain.Block) (time.Time, bool) {
+	if m.consensus(parent.State()) != "authority" {
+		return time.Time{}, false
+	}
+	blocktime := m.blocktime(parent.State())
+	delay := blocktime * int64(1+m.turnDistance(coinbase, parent))
+	return time.Unix(parent.Time+delay, 0), true
 }
 
 type EthModel struct {
@@ -487,7 +799,7 @@ type EthModel struct {
 }
 
 func NewEthModel(g *GenesisConfig) monkchain.Consensus {
-	return &EthModel{&monkchain.EasyPow{}, g}
+	return &EthModel{g.pow, g}
 }
 
 func (m *EthModel) Participate(coinbase []byte, parent *monkchain.Block) bool {
@@ -518,20 +830,23 @@ func (m *EthModel) ValidateBlock(block *monkchain.Block, bc *monkchain.ChainMana
 		return monkchain.InvalidDifficultyError(block.Difficulty, newdiff, block.Coinbase)
 	}
 
-	// check block times
-	if err := CheckBlockTimes(prevBlock, block); err != nil {
-		return err
-	}
-
 	// Verify the nonce of the block. Return an error if it's not valid
 	if !m.pow.Verify(block.HashNoNonce(), block.Difficulty, block.Nonce) {
 		return monkchain.ValidationError("Block's nonce is invalid (= %v)", monkutil.Bytes2Hex(block.Nonce))
 	}
 
+	// check block times
+	if err := m.g.CheckBlockTimes(bc, prevBlock, block); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (m *EthModel) ValidateTx(tx *monkchain.Transaction, state *monkstate.State) error {
+	if diff := m.TxWorkDifficulty(state); !tx.VerifyWork(diff) {
+		return monkchain.TxWorkError(tx.Hash(), diff)
+	}
 	// Make sure this transaction's nonce is correct
 	sender := state.GetOrNewStateObject(tx.Sender())
 	if sender.Nonce != tx.Nonce {
@@ -540,7 +855,11 @@ func (m *EthModel) ValidateTx(tx *monkchain.Transaction, state *monkstate.State)
 	return nil
 }
 
-func (m *EthModel) CheckPoint(proposed []byte, bc *monkchain.ChainManager) bool {
+func (m *EthModel) TxWorkDifficulty(state *monkstate.State) *big.Int {
+	return genesisTaPoW(m.g)
+}
+
+func (m *EthModel) CheckPoint(cert *monkchain.CheckpointCert, bc *monkchain.ChainManager) bool {
 	// TODO: can we authenticate eth checkpoints?
 	//   or just do something reasonable
 	return false
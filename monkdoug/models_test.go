@@ -0,0 +1,96 @@
+package monkdoug
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monktrie"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+// Fake gendoug singles for the duration of a test
+func withSingles(singles map[string][]byte) func() {
+	old := getSingle
+	getSingle = func(doug []byte, name string, state *monkstate.State) []byte {
+		return singles[name]
+	}
+	return func() { getSingle = old }
+}
+
+func workTx(keys *monkcrypto.KeyPair) *monkchain.Transaction {
+	tx := monkchain.NewTransactionMessage(monkchain.ZeroHash160, big.NewInt(0), big.NewInt(100), big.NewInt(1), nil)
+	tx.Sign(keys.PrivateKey)
+	return tx
+}
+
+func TestStdLibTaPoW(t *testing.T) {
+	defer withSingles(map[string][]byte{"tapow": {8}})()
+	m, state := newStakeTestModel()
+	keys := monkcrypto.GenerateNewKeyPair()
+	GrantPerm(m.doug, keys.Address(), "transact", NoExpiry, state)
+
+	tx := workTx(keys)
+	if err := m.ValidateTx(tx, state); !monkchain.IsTxWorkErr(err) {
+		t.Fatal("expected a work error, got", err)
+	}
+	tx.DoWork(m.TxWorkDifficulty(state), keys.Address())
+	if err := m.ValidateTx(tx, state); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenesisTaPoW(t *testing.T) {
+	g := &GenesisConfig{TaPoW: 8}
+	keys := monkcrypto.GenerateNewKeyPair()
+	state := monkstate.New(monktrie.New(monkutil.Config.Db, ""))
+	for _, model := range []monkchain.Consensus{NewYesModel(g), NewEthModel(g), NewBftModel(g)} {
+		tx := workTx(keys)
+		if err := model.ValidateTx(tx, state); !monkchain.IsTxWorkErr(err) {
+			t.Fatalf("%T: expected a work error, got %v", model, err)
+		}
+		tx.DoWork(model.(monkchain.TxWork).TxWorkDifficulty(state), keys.Address())
+		if err := model.ValidateTx(tx, state); err != nil {
+			t.Fatalf("%T: %v", model, err)
+		}
+	}
+}
+
+func TestGenDougSingles(t *testing.T) {
+	m, state := newStakeTestModel()
+	m.g.HaltThreshold = 2
+	m.g.MedianTimeBlocks = 11
+	// the genesis values until gendoug says otherwise
+	if m.haltThreshold(state) != 2 || m.unbondDelay(state) != 10 || m.g.medianTimeBlocks(state) != 11 {
+		t.Fatal("expected the genesis values")
+	}
+
+	defer withSingles(map[string][]byte{"haltthreshold": {3}, "unbonddelay": {7}, "mediantimeblocks": {5}})()
+	if n := m.haltThreshold(state); n != 3 {
+		t.Fatalf("expected the halt threshold from gendoug, got %d", n)
+	}
+	if n := m.unbondDelay(state); n != 7 {
+		t.Fatalf("expected the unbond delay from gendoug, got %d", n)
+	}
+	if n := m.g.medianTimeBlocks(state); n != 5 {
+		t.Fatalf("expected the median time blocks from gendoug, got %d", n)
+	}
+	m.g.NoGenDoug = true
+	if n := m.g.medianTimeBlocks(state); n != 11 {
+		t.Fatalf("expected the genesis value without gendoug, got %d", n)
+	}
+}
+
+func TestYesCheckpoint(t *testing.T) {
+	m := NewYesModel(&GenesisConfig{})
+	cert := monkchain.NewCheckpointCert([]byte("hash"), 5)
+	if !m.CheckPoint(cert, nil) {
+		t.Fatal("expected a bare proposal to be accepted")
+	}
+	cert.Sign(monkcrypto.GenerateNewKeyPair().PrivateKey)
+	if m.CheckPoint(cert, nil) {
+		t.Fatal("expected a signed cert to be refused")
+	}
+}
@@ -0,0 +1,176 @@
+package monkdoug
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkdb"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+/*
+   Dry run a genesis deploy.
+   Everything is deployed into a throw away MemDatabase,
+   so we can see what a genesis.json produces without
+   touching the real db
+*/
+
+// What a genesis deploy would produce
+type GenesisPlan struct {
+	ChainId   []byte
+	StateRoot []byte
+	Accounts  []*PlanAccount
+	// GenDoug singles as name, value pairs
+	Singles [][2]string
+}
+
+type PlanAccount struct {
+	Address []byte
+	Name    string
+	Balance *big.Int
+	Stake   *big.Int
+	// Permissions from the config, and whether the model grants them
+	Permissions map[string]bool
+}
+
+// Check a config for problems that would otherwise only show up
+// (or be silently ignored) during deploy.
+// Returns every problem found
+func (g *GenesisConfig) Validate() []error {
+	errs := []error{}
+
+	if _, err := lookupModel(g.ModelName); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := monkchain.NewPoW(g.Pow); err != nil {
+		errs = append(errs, err)
+	}
+
+	for i, acc := range g.Accounts {
+		addr := strings.TrimPrefix(acc.Address, "0x")
+		if b, err := hex.DecodeString(addr); err != nil || len(b) != 20 {
+			errs = append(errs, fmt.Errorf("Malformed address for account %d (%s): %s", i, acc.Name, acc.Address))
+		}
+		// deploy reads balances with monkutil.Big, so hex is fine too
+		if _, ok := new(big.Int).SetString(acc.Balance, 0); !ok {
+			errs = append(errs, fmt.Errorf("Malformed balance for account %d (%s): %s", i, acc.Name, acc.Balance))
+		}
+	}
+
+	errs = append(errs, g.validateForks()...)
+	if g.Reward != nil {
+		errs = append(errs, g.Reward.validate(g)...)
+	}
+	errs = append(errs, g.validateRoles()...)
+
+	// some models never deploy a gendoug (eth, bft)
+	noGenDoug := g.NoGenDoug || noGenDougModel(g.ModelName)
+	if noGenDoug {
+		return errs
+	}
+
+	if len(g.Address) != 20 {
+		errs = append(errs, fmt.Errorf("GenDoug address must be 20 bytes, got %d (%s)", len(g.Address), g.Address))
+	}
+	if g.DougPath == "" {
+		errs = append(errs, fmt.Errorf("Missing path to doug contract"))
+	} else if err := fileExists(path.Join(g.contractPath, g.DougPath)); err != nil {
+		errs = append(errs, err)
+	}
+
+	if g.ModelName == "vm" {
+		if g.Vm == nil {
+			errs = append(errs, fmt.Errorf("Model=vm requires non-nil VmConsensus obj"))
+		} else {
+			for _, sc := range g.vmSysCalls() {
+				if err := fileExists(path.Join(g.contractPath, sc[1])); err != nil {
+					errs = append(errs, fmt.Errorf("Syscall %s: %s", sc[0], err.Error()))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func fileExists(file string) error {
+	if _, err := os.Stat(file); err != nil {
+		return fmt.Errorf("Missing contract file %s", file)
+	}
+	return nil
+}
+
+// Deploy the genesis block into a MemDatabase and report the results.
+// The global db is swapped out for the duration
+func (g *GenesisConfig) Plan() (*GenesisPlan, error) {
+	if g.protocol == nil {
+		g.Init()
+	}
+
+	db, err := monkdb.NewMemDatabase()
+	if err != nil {
+		return nil, err
+	}
+	oldConfig := monkutil.Config
+	if oldConfig != nil {
+		config := *oldConfig
+		config.Db = db
+		monkutil.Config = &config
+	} else {
+		monkutil.Config = &monkutil.ConfigManager{Db: db, Debug: true, Paranoia: true}
+	}
+	defer func() { monkutil.Config = oldConfig }()
+
+	block := monkchain.NewBlockFromBytes(monkutil.Encode(monkchain.Genesis))
+	chainId, err := g.Deploy(block)
+	if err != nil {
+		return nil, err
+	}
+	state := block.State()
+
+	plan := &GenesisPlan{ChainId: chainId}
+	switch r := state.Root().(type) {
+	case []byte:
+		plan.StateRoot = monkutil.CopyBytes(r)
+	case string:
+		plan.StateRoot = []byte(r)
+	}
+
+	for _, acc := range g.Accounts {
+		pacc := &PlanAccount{
+			Address:     acc.byteAddr,
+			Name:        acc.Name,
+			Balance:     new(big.Int),
+			Stake:       GetStake(g.byteAddr, acc.byteAddr, state),
+			Permissions: make(map[string]bool),
+		}
+		if obj := state.GetStateObject(acc.byteAddr); obj != nil {
+			pacc.Balance.Set(obj.Balance)
+		}
+		for perm := range acc.Permissions {
+			pacc.Permissions[perm] = g.protocol.ValidatePerm(acc.byteAddr, perm, state) == nil
+		}
+		plan.Accounts = append(plan.Accounts, pacc)
+	}
+
+	if !g.NoGenDoug {
+		names := []string{}
+		for _, single := range g.singles() {
+			names = append(names, single[0])
+		}
+		if g.ModelName == "vm" && g.Vm != nil {
+			for _, sc := range g.vmSysCalls() {
+				names = append(names, sc[0])
+			}
+		}
+		for _, name := range names {
+			value := getSingle(g.byteAddr, name, state)
+			plan.Singles = append(plan.Singles, [2]string{name, monkutil.Bytes2Hex(value)})
+		}
+	}
+	return plan, nil
+}
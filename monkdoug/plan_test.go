@@ -0,0 +1,88 @@
+package monkdoug
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func TestGenesisValidate(t *testing.T) {
+	g := &GenesisConfig{
+		Address:   "0000000000THISISDOUG",
+		DougPath:  "gendoug-v2.lll",
+		ModelName: "std",
+		Accounts: []*Account{
+			{Address: "0xbbbd0256041f7aed3ce278c56ee61492de96d001", Balance: "0x3e8"},
+		},
+	}
+	g.contractPath = "testdata"
+
+	// the doug contract doesn't exist here
+	if errs := g.Validate(); len(errs) != 1 {
+		t.Fatalf("expected one error for the missing doug, got %v", errs)
+	}
+
+	g.DougPath = ""
+	g.ModelName = "vm"
+	g.Accounts = append(g.Accounts, &Account{Address: "0xbbbd", Balance: "1000"})
+	g.Accounts = append(g.Accounts, &Account{Address: "bbbd0256041f7aed3ce278c56ee61492de96d001", Balance: "lots"})
+	// missing doug, vm without a Vm, short address, bad balance
+	if errs := g.Validate(); len(errs) != 4 {
+		t.Fatalf("expected 4 errors, got %v", errs)
+	}
+
+	g.ModelName = "proof-of-luck"
+	g.NoGenDoug = true
+	g.Accounts = g.Accounts[:1]
+	if errs := g.Validate(); len(errs) != 1 {
+		t.Fatalf("expected an unknown model error, got %v", errs)
+	}
+
+	g.ModelName = "eth"
+	if errs := g.Validate(); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+
+	g.Pow = "scrypt"
+	if errs := g.Validate(); len(errs) != 1 {
+		t.Fatalf("expected an unknown pow error, got %v", errs)
+	}
+}
+
+func TestGenesisPlan(t *testing.T) {
+	addr := "bbbd0256041f7aed3ce278c56ee61492de96d001"
+	g := &GenesisConfig{
+		ModelName: "eth",
+		NoGenDoug: true,
+		Accounts:  []*Account{{Address: addr, Name: "bob", Balance: "0x3e8"}},
+	}
+	for _, acc := range g.Accounts {
+		acc.byteAddr = monkutil.UserHex2Bytes(acc.Address)
+	}
+	g.byteAddr = []byte(g.Address)
+
+	db := monkutil.Config.Db
+	plan, err := g.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if monkutil.Config.Db != db {
+		t.Fatal("expected the db to be put back")
+	}
+	if len(plan.ChainId) != 20 || len(plan.StateRoot) == 0 {
+		t.Fatalf("bad plan: chainId %x root %x", plan.ChainId, plan.StateRoot)
+	}
+	if len(plan.Accounts) != 1 || !bytes.Equal(plan.Accounts[0].Address, monkutil.Hex2Bytes(addr)) || plan.Accounts[0].Balance.Int64() != 1000 {
+		t.Fatalf("bad accounts in plan: %v", plan.Accounts)
+	}
+
+	// the same config always plans the same chain
+	again, err := g.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plan.ChainId, again.ChainId) || !bytes.Equal(plan.StateRoot, again.StateRoot) {
+		t.Fatal("expected planning to be deterministic")
+	}
+}
@@ -0,0 +1,102 @@
+package monkdoug
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+)
+
+// Consensus models by name, for GenesisConfig.ModelName.
+// Downstream packages can add their own with RegisterModel
+var (
+	models   = make(map[string]*registeredModel)
+	modelMut sync.RWMutex
+)
+
+type registeredModel struct {
+	ctor func(*GenesisConfig) monkchain.Consensus
+	// never deploys a gendoug
+	noGenDoug bool
+}
+
+// Model used when ModelName is empty or gendoug is turned off
+const DefaultModel = "yes"
+
+func init() {
+	// gendoug-v2
+	// uses eris-std-lib/gotests/vars for reading
+	// from gendoug
+	RegisterModel("std", func(g *GenesisConfig) monkchain.Consensus {
+		return NewStdLibModel(g)
+	}, false)
+	// run processing through the vm
+	RegisterModel("vm", func(g *GenesisConfig) monkchain.Consensus {
+		return NewVmModel(g)
+	}, false)
+	// everyone allowed everything
+	RegisterModel("yes", func(g *GenesisConfig) monkchain.Consensus {
+		return NewYesModel(g)
+	}, false)
+	// noone allowed anything
+	RegisterModel("no", func(g *GenesisConfig) monkchain.Consensus {
+		return NewNoModel(g)
+	}, false)
+	// ethereum
+	RegisterModel("eth", func(g *GenesisConfig) monkchain.Consensus {
+		return NewEthModel(g)
+	}, true)
+	// validator rounds with instant finality
+	// validators are read from genesis accounts
+	RegisterModel("bft", func(g *GenesisConfig) monkchain.Consensus {
+		return NewBftModel(g)
+	}, true)
+
+	DefaultGenesis = defaultGenesis()
+}
+
+// Is name registered as never deploying a gendoug
+func noGenDougModel(name string) bool {
+	modelMut.RLock()
+	defer modelMut.RUnlock()
+	m, ok := models[name]
+	return ok && m.noGenDoug
+}
+
+// Make a consensus model available under name.
+// noGenDoug models run without a gendoug, so genesis skips deploying one.
+// Registering a name again replaces the old constructor
+func RegisterModel(name string, ctor func(*GenesisConfig) monkchain.Consensus, noGenDoug bool) {
+	modelMut.Lock()
+	defer modelMut.Unlock()
+	models[name] = &registeredModel{ctor, noGenDoug}
+}
+
+// Sorted names of the registered models
+func ModelNames() []string {
+	modelMut.RLock()
+	defer modelMut.RUnlock()
+	names := []string{}
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Find the constructor for a model name.
+// The empty name is the DefaultModel
+func lookupModel(name string) (func(*GenesisConfig) monkchain.Consensus, error) {
+	if name == "" {
+		name = DefaultModel
+	}
+	modelMut.RLock()
+	m, ok := models[name]
+	modelMut.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown consensus model %q. Registered models are: %s", name, strings.Join(ModelNames(), ", "))
+	}
+	return m.ctor, nil
+}
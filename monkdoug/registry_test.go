@@ -0,0 +1,57 @@
+package monkdoug
+
+import (
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+)
+
+type testModel struct {
+	monkchain.Consensus
+}
+
+func TestRegisterModel(t *testing.T) {
+	RegisterModel("test-model", func(g *GenesisConfig) monkchain.Consensus {
+		return &testModel{NewYesModel(g)}
+	}, false)
+	defer func() {
+		modelMut.Lock()
+		delete(models, "test-model")
+		modelMut.Unlock()
+	}()
+
+	g := &GenesisConfig{ModelName: "test-model"}
+	model, err := NewPermModel(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := model.(*testModel); !ok {
+		t.Fatalf("expected the registered model, got %T", model)
+	}
+
+	// no gendoug falls back to the default
+	g.NoGenDoug = true
+	if model, _ = NewPermModel(g); model == nil {
+		t.Fatal("expected the default model")
+	}
+	if _, ok := model.(*testModel); ok {
+		t.Fatal("expected the default model without gendoug")
+	}
+
+	// models can run without a gendoug
+	RegisterModel("test-model", func(g *GenesisConfig) monkchain.Consensus {
+		return &testModel{NewYesModel(g)}
+	}, true)
+	g = &GenesisConfig{ModelName: "test-model"}
+	if model, _ = NewPermModel(g); !g.NoGenDoug {
+		t.Fatal("expected a no gendoug model to turn gendoug off")
+	}
+	if _, ok := model.(*testModel); !ok {
+		t.Fatalf("expected the registered model, got %T", model)
+	}
+
+	g = &GenesisConfig{ModelName: "proof-of-luck"}
+	if _, err := NewPermModel(g); err == nil {
+		t.Fatal("expected an error for an unknown model")
+	}
+}
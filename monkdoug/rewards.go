@@ -0,0 +1,132 @@
+package monkdoug
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+/*
+   Block rewards.
+   The policy from genesis.json is written into the genesis state,
+   at sha3("reward:policy") in the storage of RewardAddr,
+   so every node on a chain pays out the same way.
+   Chains without one get the ethereum rewards
+*/
+
+var RewardAddr = monkutil.LeftPadBytes([]byte("reward"), 20)
+
+type RewardConfig struct {
+	// none, fixed, halving, or treasury
+	Mode string `json:"mode"`
+	// Reward per block (before any halving)
+	Amount string `json:"amount"`
+	// Blocks between halvings
+	HalvingInterval uint64 `json:"halving-interval"`
+	// Hex address of the treasury (defaults to gendoug)
+	Treasury string `json:"treasury"`
+	// Percent of each reward paid to the treasury
+	TreasuryShare uint64 `json:"treasury-share"`
+	// Allow (and reward) uncles
+	Uncles bool `json:"uncles"`
+}
+
+func (r *RewardConfig) validate(g *GenesisConfig) []error {
+	errs := []error{}
+	switch r.Mode {
+	case "none":
+	case "fixed", "halving", "treasury":
+		// policy reads it with monkutil.Big, so hex is fine too
+		if _, ok := new(big.Int).SetString(r.Amount, 0); !ok {
+			errs = append(errs, fmt.Errorf("Malformed reward amount: %s", r.Amount))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("Unknown reward mode %q", r.Mode))
+	}
+	if r.Mode == "halving" && r.HalvingInterval == 0 {
+		errs = append(errs, fmt.Errorf("Halving rewards need a halving-interval"))
+	}
+	if r.Mode == "treasury" {
+		if r.TreasuryShare > 100 {
+			errs = append(errs, fmt.Errorf("Treasury share is a percent, got %d", r.TreasuryShare))
+		}
+		if r.Treasury == "" && g.NoGenDoug {
+			errs = append(errs, fmt.Errorf("Treasury rewards without gendoug need a treasury address"))
+		} else if r.Treasury != "" {
+			if b, err := hex.DecodeString(strings.TrimPrefix(r.Treasury, "0x")); err != nil || len(b) != 20 {
+				errs = append(errs, fmt.Errorf("Malformed treasury address: %s", r.Treasury))
+			}
+		}
+	}
+	return errs
+}
+
+func (r *RewardConfig) policy(g *GenesisConfig) *monkchain.RewardPolicy {
+	p := &monkchain.RewardPolicy{
+		Mode:            r.Mode,
+		Amount:          monkutil.Big(r.Amount),
+		HalvingInterval: r.HalvingInterval,
+		TreasuryShare:   r.TreasuryShare,
+		Uncles:          r.Uncles,
+	}
+	if r.Treasury != "" {
+		p.Treasury = monkutil.Hex2Bytes(strings.TrimPrefix(r.Treasury, "0x"))
+	} else {
+		p.Treasury = g.byteAddr
+	}
+	return p
+}
+
+func rewardKey() *big.Int {
+	return monkutil.BigD(monkcrypto.Sha3Bin([]byte("reward:policy")))
+}
+
+func rewardRlp(p *monkchain.RewardPolicy) []interface{} {
+	uncles := uint64(0)
+	if p.Uncles {
+		uncles = 1
+	}
+	return []interface{}{p.Mode, p.Amount, p.HalvingInterval, p.Treasury, p.TreasuryShare, uncles}
+}
+
+func SetRewardPolicy(p *monkchain.RewardPolicy, state *monkstate.State) {
+	obj := state.GetOrNewStateObject(RewardAddr)
+	obj.SetStorage(rewardKey(), monkutil.NewValue(monkutil.Encode(rewardRlp(p))))
+}
+
+// The policy in state, or nil if there isn't one
+func GetRewardPolicy(state *monkstate.State) *monkchain.RewardPolicy {
+	obj := state.GetStateObject(RewardAddr)
+	if obj == nil {
+		return nil
+	}
+	data := obj.GetStorage(rewardKey()).Bytes()
+	if len(data) == 0 {
+		return nil
+	}
+	val := monkutil.NewValueFromBytes(data)
+	return &monkchain.RewardPolicy{
+		Mode:            val.Get(0).Str(),
+		Amount:          val.Get(1).BigInt(),
+		HalvingInterval: val.Get(2).Uint(),
+		Treasury:        val.Get(3).Bytes(),
+		TreasuryShare:   val.Get(4).Uint(),
+		Uncles:          val.Get(5).Uint() == 1,
+	}
+}
+
+func (p *Protocol) RewardPolicy(state *monkstate.State) *monkchain.RewardPolicy {
+	return GetRewardPolicy(state)
+}
+
+func (g *GenesisConfig) deployRewards(block *monkchain.Block) {
+	if g.Reward != nil {
+		SetRewardPolicy(g.Reward.policy(g), block.State())
+	}
+}
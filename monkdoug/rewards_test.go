@@ -0,0 +1,50 @@
+package monkdoug
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monktrie"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func TestRewardPolicy(t *testing.T) {
+	g := &GenesisConfig{byteAddr: []byte("0000000000THISISDOUG")}
+	r := &RewardConfig{Mode: "treasury", Amount: "0x3e8", TreasuryShare: 25}
+	if errs := r.validate(g); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	state := monkstate.New(monktrie.New(monkutil.Config.Db, ""))
+	if GetRewardPolicy(state) != nil {
+		t.Fatal("expected no policy in an empty state")
+	}
+	SetRewardPolicy(r.policy(g), state)
+	p := GetRewardPolicy(state)
+	if p.Mode != "treasury" || p.Amount.Cmp(big.NewInt(1000)) != 0 || p.TreasuryShare != 25 || p.Uncles {
+		t.Fatalf("bad round trip: %v", p)
+	}
+	// treasury defaults to gendoug
+	if !bytes.Equal(p.Treasury, g.byteAddr) {
+		t.Fatalf("expected the gendoug treasury, got %x", p.Treasury)
+	}
+	coinbase, treasury := p.Split(p.Reward(big.NewInt(1)))
+	if coinbase.Cmp(big.NewInt(750)) != 0 || treasury.Cmp(big.NewInt(250)) != 0 {
+		t.Fatalf("bad split %v/%v", coinbase, treasury)
+	}
+
+	bad := []*RewardConfig{
+		{Mode: "lots"},
+		{Mode: "fixed", Amount: "1.5"},
+		{Mode: "halving", Amount: "100"},
+		{Mode: "treasury", Amount: "100", TreasuryShare: 101},
+		{Mode: "treasury", Amount: "100", Treasury: "abcd"},
+	}
+	for _, r := range bad {
+		if errs := r.validate(g); len(errs) == 0 {
+			t.Errorf("expected an error for %v", r)
+		}
+	}
+}
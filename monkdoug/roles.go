@@ -0,0 +1,411 @@
+package monkdoug
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+/*
+   Roles, per contract call permissions, expiry and delegation
+   for the StdLibModel. These sit on top of the flat eris-std-lib
+   permissions: an account has a permission if it has it flat,
+   was granted it directly, or holds a role that includes it.
+
+   Records live in GenDoug's storage at sha3 keys, like the stake:
+       sha3(rlp("perm:grant", addr, perm))   - expiry of a direct grant
+       sha3(rlp("role:member", addr, role))  - expiry of a role membership
+       sha3(rlp("role:nperms", role))        - number of perms in a role
+       sha3(rlp("role:perm", role, i))       - ith perm of a role
+       sha3(rlp("roles:count"))              - number of roles
+       sha3(rlp("roles", i))                 - ith role
+       sha3(rlp("call:restricted", addr))    - calls to addr need "call:<addr>"
+       sha3(rlp("block:number"))             - number of the block being processed
+
+   A grant lasts until the block number it expires at (NoExpiry for never).
+
+   Accounts with "admin" manage permissions with system txs to PermsAddr,
+   but may only hand out what they have themselves, for no longer than they have it.
+   Admin itself only comes from genesis, so one admin key can't mint more:
+       grant addr perm [expiry]
+       revoke addr perm
+       grant-role addr role [expiry]
+       revoke-role addr role
+       define-role role perm...      (new roles only, so members can't outlive the definer's grants)
+       restrict contract
+       unrestrict contract
+   Call permissions are written as two words: call contract
+*/
+
+// Receives the permission system txs
+var PermsAddr = monkutil.LeftPadBytes([]byte("perms"), 20)
+
+const NoExpiry = math.MaxUint64
+
+// Permission to call a restricted contract
+func CallPerm(contract []byte) string {
+	return "call:" + monkutil.Bytes2Hex(contract)
+}
+
+func roleKey(name string, args ...interface{}) *big.Int {
+	return monkutil.BigD(monkcrypto.Sha3Bin(monkutil.Encode(append([]interface{}{name}, args...))))
+}
+
+func getRoleValue(doug []byte, key *big.Int, state *monkstate.State) *monkutil.Value {
+	obj := state.GetStateObject(doug)
+	if obj == nil {
+		return monkutil.NewValue(nil)
+	}
+	return obj.GetStorage(key)
+}
+
+// Numbers are stored as big endian bytes like the vm does;
+// a bare uint64 has no length and would be dropped on sync
+func setRoleValue(doug []byte, key *big.Int, value interface{}, state *monkstate.State) {
+	if n, ok := value.(uint64); ok {
+		value = new(big.Int).SetUint64(n).Bytes()
+	}
+	state.GetOrNewStateObject(doug).SetStorage(key, monkutil.NewValue(value))
+}
+
+// Number of the block being processed (0 at genesis)
+func blockNumber(doug []byte, state *monkstate.State) uint64 {
+	return getRoleValue(doug, roleKey("block:number"), state).Uint()
+}
+
+// Expiry of a record, or 0 if it's gone
+func liveExpiry(doug []byte, key *big.Int, state *monkstate.State) uint64 {
+	expiry := getRoleValue(doug, key, state).Uint()
+	if expiry <= blockNumber(doug, state) {
+		return 0
+	}
+	return expiry
+}
+
+func GrantPerm(doug, addr []byte, perm string, expiry uint64, state *monkstate.State) {
+	setRoleValue(doug, roleKey("perm:grant", addr, perm), expiry, state)
+}
+
+func RevokePerm(doug, addr []byte, perm string, state *monkstate.State) {
+	setRoleValue(doug, roleKey("perm:grant", addr, perm), uint64(0), state)
+}
+
+func GrantRole(doug, addr []byte, role string, expiry uint64, state *monkstate.State) {
+	setRoleValue(doug, roleKey("role:member", addr, role), expiry, state)
+}
+
+func RevokeRole(doug, addr []byte, role string, state *monkstate.State) {
+	setRoleValue(doug, roleKey("role:member", addr, role), uint64(0), state)
+}
+
+func GetRoles(doug []byte, state *monkstate.State) []string {
+	n := getRoleValue(doug, roleKey("roles:count"), state).Uint()
+	roles := make([]string, n)
+	for i := range roles {
+		roles[i] = string(getRoleValue(doug, roleKey("roles", uint64(i)), state).Bytes())
+	}
+	return roles
+}
+
+func RolePerms(doug []byte, role string, state *monkstate.State) []string {
+	n := getRoleValue(doug, roleKey("role:nperms", role), state).Uint()
+	perms := make([]string, n)
+	for i := range perms {
+		perms[i] = string(getRoleValue(doug, roleKey("role:perm", role, uint64(i)), state).Bytes())
+	}
+	return perms
+}
+
+// Add perms to a role, creating it if it's new
+func DefineRole(doug []byte, role string, perms []string, state *monkstate.State) {
+	known := false
+	for _, r := range GetRoles(doug, state) {
+		known = known || r == role
+	}
+	if !known {
+		n := uint64(len(GetRoles(doug, state)))
+		setRoleValue(doug, roleKey("roles", n), []byte(role), state)
+		setRoleValue(doug, roleKey("roles:count"), n+1, state)
+	}
+
+	have := RolePerms(doug, role, state)
+	for _, perm := range perms {
+		dup := false
+		for _, h := range have {
+			dup = dup || h == perm
+		}
+		if dup {
+			continue
+		}
+		setRoleValue(doug, roleKey("role:perm", role, uint64(len(have))), []byte(perm), state)
+		have = append(have, perm)
+	}
+	setRoleValue(doug, roleKey("role:nperms", role), uint64(len(have)), state)
+}
+
+func IsRestricted(doug, contract []byte, state *monkstate.State) bool {
+	return getRoleValue(doug, roleKey("call:restricted", contract), state).Uint() != 0
+}
+
+func SetRestricted(doug, contract []byte, restricted bool, state *monkstate.State) {
+	v := uint64(0)
+	if restricted {
+		v = 1
+	}
+	setRoleValue(doug, roleKey("call:restricted", contract), v, state)
+}
+
+// When addr's hold on perm runs out (NoExpiry for never, 0 if it doesn't have it)
+func (m *StdLibModel) PermExpiry(addr []byte, perm string, state *monkstate.State) uint64 {
+	// equivocators never mine again
+	if perm == "mine" && IsSlashed(m.doug, addr, state) {
+		return 0
+	}
+	if m.hasFlatPermission(addr, perm, state) {
+		return NoExpiry
+	}
+	expiry := liveExpiry(m.doug, roleKey("perm:grant", addr, perm), state)
+	for _, role := range GetRoles(m.doug, state) {
+		member := liveExpiry(m.doug, roleKey("role:member", addr, role), state)
+		if member <= expiry {
+			continue
+		}
+		for _, p := range RolePerms(m.doug, role, state) {
+			if p == perm {
+				expiry = member
+				break
+			}
+		}
+	}
+	return expiry
+}
+
+// Keep track of the block number for expiry
+func (m *StdLibModel) PreBlock(state *monkstate.State, block *monkchain.Block) *monkchain.HookReceipt {
+	setRoleValue(m.doug, roleKey("block:number"), block.Number.Uint64(), state)
+	return nil
+}
+
+func (m *StdLibModel) PostBlock(state *monkstate.State, block *monkchain.Block) *monkchain.HookReceipt {
+	return nil
+}
+
+// Nothing here can fail
+func (m *StdLibModel) HasHook(name string, state *monkstate.State) bool {
+	return false
+}
+
+// Read a perm from the words of a tx, starting at i.
+// Returns the perm and the index of the next word
+func permFromWords(words [][]byte, i int) (string, int, error) {
+	if i >= len(words) {
+		return "", i, fmt.Errorf("Missing permission")
+	}
+	perm := string(bytes.TrimLeft(words[i], "\x00"))
+	if perm == "call" {
+		if i+1 >= len(words) {
+			return "", i, fmt.Errorf("Missing contract address for call permission")
+		}
+		return CallPerm(words[i+1][12:]), i + 2, nil
+	}
+	return perm, i + 1, nil
+}
+
+// The sender's expiry on every perm, capped by the requested expiry
+func (m *StdLibModel) delegatedExpiry(sender []byte, perms []string, requested uint64, state *monkstate.State) (uint64, error) {
+	expiry := requested
+	if expiry == 0 {
+		expiry = NoExpiry
+	}
+	for _, perm := range perms {
+		have := m.PermExpiry(sender, perm, state)
+		if have == 0 {
+			return 0, fmt.Errorf("%x can not delegate %s, it doesn't have it", sender, perm)
+		}
+		if have < expiry {
+			expiry = have
+		}
+	}
+	return expiry, nil
+}
+
+// Admin can't be handed out, or one key could outvote the rest (eg. on a halt)
+func undelegable(perms []string) error {
+	for _, perm := range perms {
+		if perm == "admin" {
+			return fmt.Errorf("admin can not be delegated")
+		}
+	}
+	return nil
+}
+
+// Manage permissions. Only admins, and only with what they have
+func (m *StdLibModel) applyPermsTx(tx *monkchain.Transaction, state *monkstate.State, block *monkchain.Block) error {
+	if tx.Value.Sign() != 0 {
+		return fmt.Errorf("Permission txs do not take a value")
+	}
+	if len(tx.Data) < 64 || len(tx.Data)%32 != 0 {
+		return fmt.Errorf("Permission tx needs a command and an argument")
+	}
+	sender := tx.Sender()
+	if m.PermExpiry(sender, "admin", state) == 0 {
+		return monkchain.InvalidPermError(sender, "admin")
+	}
+	words := make([][]byte, len(tx.Data)/32)
+	for i := range words {
+		words[i] = tx.Data[32*i : 32*(i+1)]
+	}
+	cmd := string(bytes.TrimLeft(words[0], "\x00"))
+	str := func(i int) string { return string(bytes.TrimLeft(words[i], "\x00")) }
+	// optional expiry after the last argument
+	expiryAt := func(i int) uint64 {
+		if i < len(words) {
+			return monkutil.BigD(words[i]).Uint64()
+		}
+		return 0
+	}
+	switch cmd {
+	case "grant", "revoke":
+		if len(words) < 3 {
+			return fmt.Errorf("%s needs an address and a permission", cmd)
+		}
+		addr := words[1][12:]
+		perm, next, err := permFromWords(words, 2)
+		if err != nil {
+			return err
+		}
+		expiry, err := m.delegatedExpiry(sender, []string{perm}, expiryAt(next), state)
+		if err != nil {
+			return err
+		}
+		if cmd == "grant" {
+			if err := undelegable([]string{perm}); err != nil {
+				return err
+			}
+			GrantPerm(m.doug, addr, perm, expiry, state)
+		} else {
+			RevokePerm(m.doug, addr, perm, state)
+		}
+	case "grant-role", "revoke-role":
+		if len(words) < 3 {
+			return fmt.Errorf("%s needs an address and a role", cmd)
+		}
+		addr, role := words[1][12:], str(2)
+		perms := RolePerms(m.doug, role, state)
+		if len(perms) == 0 {
+			return fmt.Errorf("Unknown role %s", role)
+		}
+		expiry, err := m.delegatedExpiry(sender, perms, expiryAt(3), state)
+		if err != nil {
+			return err
+		}
+		if cmd == "grant-role" {
+			if err := undelegable(perms); err != nil {
+				return err
+			}
+			GrantRole(m.doug, addr, role, expiry, state)
+		} else {
+			RevokeRole(m.doug, addr, role, state)
+		}
+	case "define-role":
+		role := str(1)
+		perms := []string{}
+		for i := 2; i < len(words); {
+			perm, next, err := permFromWords(words, i)
+			if err != nil {
+				return err
+			}
+			perms = append(perms, perm)
+			i = next
+		}
+		if len(perms) == 0 {
+			return fmt.Errorf("Role %s needs permissions", role)
+		}
+		if len(RolePerms(m.doug, role, state)) > 0 {
+			return fmt.Errorf("Role %s already exists", role)
+		}
+		if err := undelegable(perms); err != nil {
+			return err
+		}
+		if _, err := m.delegatedExpiry(sender, perms, 0, state); err != nil {
+			return err
+		}
+		DefineRole(m.doug, role, perms, state)
+	case "restrict", "unrestrict":
+		SetRestricted(m.doug, words[1][12:], cmd == "restrict", state)
+	default:
+		return fmt.Errorf("Unknown permission command %s", cmd)
+	}
+	return nil
+}
+
+/*
+   Genesis
+*/
+
+// Expiry from genesis.json, where 0 means never
+func genesisExpiry(e int) uint64 {
+	if e <= 0 {
+		return NoExpiry
+	}
+	return uint64(e)
+}
+
+func (g *GenesisConfig) deployRoles(block *monkchain.Block) {
+	state := block.State()
+	names := []string{}
+	for role := range g.Roles {
+		names = append(names, role)
+	}
+	// map order is random, but the state root can't be
+	sort.Strings(names)
+	for _, role := range names {
+		DefineRole(g.byteAddr, role, g.Roles[role], state)
+	}
+	for _, contract := range g.Restricted {
+		SetRestricted(g.byteAddr, monkutil.Hex2Bytes(strings.TrimPrefix(contract, "0x")), true, state)
+	}
+}
+
+func (g *GenesisConfig) deployAccountRoles(account *Account, block *monkchain.Block) {
+	state := block.State()
+	for role, expiry := range account.Roles {
+		GrantRole(g.byteAddr, account.byteAddr, role, genesisExpiry(expiry), state)
+	}
+	for perm, expiry := range account.Grants {
+		GrantPerm(g.byteAddr, account.byteAddr, perm, genesisExpiry(expiry), state)
+	}
+}
+
+func (g *GenesisConfig) validateRoles() []error {
+	errs := []error{}
+	for role, perms := range g.Roles {
+		if len(role) > 32 {
+			errs = append(errs, fmt.Errorf("Role name %s is longer than 32 bytes", role))
+		}
+		if len(perms) == 0 {
+			errs = append(errs, fmt.Errorf("Role %s has no permissions", role))
+		}
+	}
+	for i, acc := range g.Accounts {
+		for role := range acc.Roles {
+			if _, ok := g.Roles[role]; !ok {
+				errs = append(errs, fmt.Errorf("Account %d (%s) has undefined role %s", i, acc.Name, role))
+			}
+		}
+	}
+	for _, contract := range g.Restricted {
+		if len(monkutil.Hex2Bytes(strings.TrimPrefix(contract, "0x"))) != 20 {
+			errs = append(errs, fmt.Errorf("Malformed restricted contract address %s", contract))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,138 @@
+package monkdoug
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func TestRolesAndExpiry(t *testing.T) {
+	m, state := newStakeTestModel()
+	alice := monkcrypto.GenerateNewKeyPair().Address()
+
+	DefineRole(m.doug, "operator", []string{"mine", "transact"}, state)
+	DefineRole(m.doug, "operator", []string{"transact", "checkpoint"}, state)
+	if perms := RolePerms(m.doug, "operator", state); len(perms) != 3 {
+		t.Fatalf("expected the role to grow to 3 perms, got %v", perms)
+	}
+
+	GrantRole(m.doug, alice, "operator", 10, state)
+	GrantPerm(m.doug, alice, "create", NoExpiry, state)
+	for _, perm := range []string{"mine", "transact", "checkpoint", "create"} {
+		if !m.HasPermission(alice, perm, state) {
+			t.Fatalf("expected alice to have %s", perm)
+		}
+	}
+	if m.HasPermission(alice, "admin", state) {
+		t.Fatal("alice shouldn't be an admin")
+	}
+
+	// the role runs out at block 10
+	m.PreBlock(state, blockAt(10))
+	if m.HasPermission(alice, "mine", state) {
+		t.Fatal("expected the role to have expired")
+	}
+	if !m.HasPermission(alice, "create", state) {
+		t.Fatal("direct grant shouldn't expire")
+	}
+	RevokePerm(m.doug, alice, "create", state)
+	if m.HasPermission(alice, "create", state) {
+		t.Fatal("expected the grant to be revoked")
+	}
+}
+
+func TestDelegation(t *testing.T) {
+	m, state := newStakeTestModel()
+	admin, bob, carol := monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair()
+	contract := monkutil.LeftPadBytes([]byte("contract"), 20)
+	hexContract := "0x" + monkutil.Bytes2Hex(contract)
+
+	GrantPerm(m.doug, admin.Address(), "admin", NoExpiry, state)
+	GrantPerm(m.doug, admin.Address(), "transact", 50, state)
+	GrantPerm(m.doug, admin.Address(), CallPerm(contract), NoExpiry, state)
+
+	// only what the admin has
+	if err := applySystemTx(m, state, admin, systemTx(PermsAddr, 0, "grant", "0x"+monkutil.Bytes2Hex(bob.Address()), "mine"), 1); err == nil {
+		t.Fatal("expected an error delegating a permission the admin doesn't have")
+	}
+	// and no longer than it has it
+	if err := applySystemTx(m, state, admin, systemTx(PermsAddr, 0, "grant", "0x"+monkutil.Bytes2Hex(bob.Address()), "transact", "0x64"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if e := m.PermExpiry(bob.Address(), "transact", state); e != 50 {
+		t.Fatalf("expected bob's grant to be capped at 50, got %d", e)
+	}
+	if err := applySystemTx(m, state, admin, systemTx(PermsAddr, 0, "define-role", "caller", "call", hexContract), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := applySystemTx(m, state, admin, systemTx(PermsAddr, 0, "grant-role", "0x"+monkutil.Bytes2Hex(bob.Address()), "caller"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if !m.HasPermission(bob.Address(), CallPerm(contract), state) {
+		t.Fatal("expected bob to be able to call the contract through the role")
+	}
+	// an existing role can't be extended, or its members would get transact past 50
+	if err := applySystemTx(m, state, admin, systemTx(PermsAddr, 0, "define-role", "caller", "transact"), 1); err == nil {
+		t.Fatal("expected an error extending an existing role")
+	}
+	if perms := RolePerms(m.doug, "caller", state); len(perms) != 1 {
+		t.Fatalf("expected the role to be unchanged, got %v", perms)
+	}
+
+	// bob isn't an admin
+	if err := applySystemTx(m, state, bob, systemTx(PermsAddr, 0, "grant", "0x"+monkutil.Bytes2Hex(carol.Address()), "transact"), 1); err == nil {
+		t.Fatal("expected a non admin to be refused")
+	}
+
+	if err := applySystemTx(m, state, admin, systemTx(PermsAddr, 0, "restrict", hexContract), 2); err != nil {
+		t.Fatal(err)
+	}
+	if !IsRestricted(m.doug, contract, state) {
+		t.Fatal("expected the contract to be restricted")
+	}
+	if err := applySystemTx(m, state, admin, systemTx(PermsAddr, 0, "revoke-role", "0x"+monkutil.Bytes2Hex(bob.Address()), "caller"), 2); err != nil {
+		t.Fatal(err)
+	}
+	if m.HasPermission(bob.Address(), CallPerm(contract), state) {
+		t.Fatal("expected the role to be revoked")
+	}
+
+	// bob can transact, but not with the restricted contract
+	tx := monkchain.NewTransactionMessage(contract, big.NewInt(0), big.NewInt(100), big.NewInt(0), nil)
+	tx.Sign(bob.PrivateKey)
+	if _, ok := m.ValidateTx(tx, state).(*monkchain.InvalidPermErr); !ok {
+		t.Fatal("expected a permission error calling the restricted contract")
+	}
+	tx = monkchain.NewTransactionMessage(carol.Address(), big.NewInt(0), big.NewInt(100), big.NewInt(0), nil)
+	tx.Sign(bob.PrivateKey)
+	if err := m.ValidateTx(tx, state); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRolesSurviveSync(t *testing.T) {
+	m, state := newStakeTestModel()
+	alice := monkcrypto.GenerateNewKeyPair().Address()
+	GrantPerm(m.doug, alice, "mine", NoExpiry, state)
+	Slash(m.doug, alice, state)
+	m.PreBlock(state, blockAt(3))
+
+	// flush to the db and read it all back
+	state.Update()
+	state.Sync()
+	if e := m.PermExpiry(alice, "mine", state); e != 0 {
+		t.Fatal("expected slashed grant to stay dead, got", e)
+	}
+	if !IsSlashed(m.doug, alice, state) {
+		t.Fatal("expected slashing to survive a sync")
+	}
+	if blockNumber(m.doug, state) != 3 {
+		t.Fatal("expected the block number to survive a sync")
+	}
+	if getRoleValue(m.doug, roleKey("perm:grant", alice, "mine"), state).Uint() != NoExpiry {
+		t.Fatal("expected the grant to survive a sync")
+	}
+}
@@ -0,0 +1,170 @@
+package monkdoug
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+/*
+   Bonded stake for the "stake-weight" consensus.
+
+   Records live in GenDoug's storage next to the eris-std-lib vars,
+   at sha3 keys so they can't collide with them:
+       sha3("stake:bonded", addr)      - bonded stake of addr
+       sha3("stake:total")             - total bonded stake
+       sha3("stake:unbonding", addr)   - stake waiting to be withdrawn
+       sha3("stake:release", addr)     - block number it can be withdrawn at
+
+   Bonded funds are held by StakeAddr. System txs to StakeAddr take a
+   command as the first word of data (see monkutil.PackTxDataArgs2):
+       bond           - bond the tx value
+       unbond amount  - stop counting amount, withdrawable after the unbond delay
+       withdraw       - pay out unbonded stake whose delay has passed
+*/
+
+// Holds bonded funds and receives the system txs
+var StakeAddr = monkutil.LeftPadBytes([]byte("stake"), 20)
+
+// Default number of blocks between unbonding and withdrawal
+var DefaultUnbondDelay = 100
+
+func stakeKey(name string, addr []byte) *big.Int {
+	return monkutil.BigD(monkcrypto.Sha3Bin(append([]byte(name), addr...)))
+}
+
+func getStakeValue(doug []byte, name string, addr []byte, state *monkstate.State) *big.Int {
+	obj := state.GetStateObject(doug)
+	if obj == nil {
+		return new(big.Int)
+	}
+	// copy, so we never modify the cached storage value
+	return new(big.Int).Set(obj.GetStorage(stakeKey(name, addr)).BigInt())
+}
+
+func setStakeValue(doug []byte, name string, addr []byte, value *big.Int, state *monkstate.State) {
+	obj := state.GetOrNewStateObject(doug)
+	obj.SetStorage(stakeKey(name, addr), monkutil.NewValue(value))
+}
+
+// Stake bonded by addr
+func GetStake(doug, addr []byte, state *monkstate.State) *big.Int {
+	return getStakeValue(doug, "stake:bonded", addr, state)
+}
+
+// Total stake bonded by everyone
+func GetTotalStake(doug []byte, state *monkstate.State) *big.Int {
+	return getStakeValue(doug, "stake:total", nil, state)
+}
+
+// Stake of addr that is unbonding, and the block it can be withdrawn at
+func GetUnbonding(doug, addr []byte, state *monkstate.State) (*big.Int, uint64) {
+	amount := getStakeValue(doug, "stake:unbonding", addr, state)
+	release := getStakeValue(doug, "stake:release", addr, state)
+	return amount, release.Uint64()
+}
+
+// Add to (or with a negative amount, take from) the bonded stake of addr.
+// Does not move any funds
+func AddStake(doug, addr []byte, amount *big.Int, state *monkstate.State) {
+	stake := new(big.Int).Add(GetStake(doug, addr, state), amount)
+	total := new(big.Int).Add(GetTotalStake(doug, state), amount)
+	setStakeValue(doug, "stake:bonded", addr, stake, state)
+	setStakeValue(doug, "stake:total", nil, total, state)
+}
+
+func (m *StdLibModel) unbondDelay(state *monkstate.State) uint64 {
+	def := uint64(DefaultUnbondDelay)
+	if m.g.UnbondDelay > 0 {
+		def = uint64(m.g.UnbondDelay)
+	}
+	return singleUint(m.doug, "unbonddelay", state, def)
+}
+
+// Stake, permission, evidence and halt txs
+func (m *StdLibModel) IsSystemTx(tx *monkchain.Transaction, state *monkstate.State) bool {
+	return bytes.Equal(tx.Recipient, StakeAddr) || bytes.Equal(tx.Recipient, PermsAddr) || bytes.Equal(tx.Recipient, EvidenceAddr) || bytes.Equal(tx.Recipient, HaltAddr)
+}
+
+// Bond, unbond or withdraw. The tx value has already been sent to StakeAddr.
+// Nothing is changed if there's an error
+func (m *StdLibModel) ApplySystemTx(tx *monkchain.Transaction, state *monkstate.State, block *monkchain.Block) error {
+	if bytes.Equal(tx.Recipient, PermsAddr) {
+		return m.applyPermsTx(tx, state, block)
+	}
+	if bytes.Equal(tx.Recipient, EvidenceAddr) {
+		return m.applyEvidenceTx(tx, state, block)
+	}
+	if bytes.Equal(tx.Recipient, HaltAddr) {
+		return m.applyHaltTx(tx, state, block)
+	}
+	if len(tx.Data) < 32 {
+		return fmt.Errorf("Stake tx missing command")
+	}
+	sender := tx.Sender()
+	cmd := string(bytes.TrimLeft(tx.Data[:32], "\x00"))
+
+	switch cmd {
+	case "bond":
+		if tx.Value.Sign() <= 0 {
+			return fmt.Errorf("Nothing to bond")
+		}
+		AddStake(m.doug, sender, tx.Value, state)
+	case "unbond":
+		if tx.Value.Sign() != 0 {
+			return fmt.Errorf("Unbond does not take a value")
+		}
+		if len(tx.Data) < 64 {
+			return fmt.Errorf("Unbond missing amount")
+		}
+		amount := monkutil.BigD(tx.Data[32:64])
+		if amount.Sign() <= 0 || amount.Cmp(GetStake(m.doug, sender, state)) > 0 {
+			return fmt.Errorf("Can not unbond %v from %x", amount, sender)
+		}
+		AddStake(m.doug, sender, new(big.Int).Neg(amount), state)
+
+		// unbonding again pushes back the release
+		unbonding, _ := GetUnbonding(m.doug, sender, state)
+		release := new(big.Int).Add(block.Number, new(big.Int).SetUint64(m.unbondDelay(state)))
+		setStakeValue(m.doug, "stake:unbonding", sender, unbonding.Add(unbonding, amount), state)
+		setStakeValue(m.doug, "stake:release", sender, release, state)
+	case "withdraw":
+		if tx.Value.Sign() != 0 {
+			return fmt.Errorf("Withdraw does not take a value")
+		}
+		unbonding, release := GetUnbonding(m.doug, sender, state)
+		if unbonding.Sign() == 0 {
+			return fmt.Errorf("Nothing to withdraw for %x", sender)
+		}
+		if block.Number.Uint64() < release {
+			return fmt.Errorf("Stake for %x is unbonding until block %d", sender, release)
+		}
+		state.GetOrNewStateObject(StakeAddr).SubAmount(unbonding)
+		state.GetOrNewStateObject(sender).AddAmount(unbonding)
+		setStakeValue(m.doug, "stake:unbonding", sender, new(big.Int), state)
+		setStakeValue(m.doug, "stake:release", sender, new(big.Int), state)
+	default:
+		return fmt.Errorf("Unknown stake command %s", cmd)
+	}
+	return nil
+}
+
+// Difficulty scales with the inverse of the miner's share of stake,
+// so a miner's chance of finding the next block goes with its share.
+// No stake means no chance
+func stakeDifficulty(base, stake, total *big.Int) *big.Int {
+	if stake.Sign() <= 0 {
+		return monkutil.BigPow(2, 256)
+	}
+	diff := new(big.Int).Mul(base, total)
+	diff.Div(diff, stake)
+	if diff.Sign() == 0 {
+		diff.SetInt64(1)
+	}
+	return diff
+}
@@ -0,0 +1,231 @@
+package monkdoug
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monktrie"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+func newStakeTestModel() (*StdLibModel, *monkstate.State) {
+	g := &GenesisConfig{UnbondDelay: 10}
+	g.byteAddr = []byte("0000000000THISISDOUG")
+	m := &StdLibModel{base: new(big.Int), doug: g.byteAddr, g: g}
+	state := monkstate.New(monktrie.New(monkutil.Config.Db, ""))
+	return m, state
+}
+
+func blockAt(n int64) *monkchain.Block {
+	block := monkchain.CreateBlock(nil, monkchain.ZeroHash256, nil, big.NewInt(1), nil, "")
+	block.Number = big.NewInt(n)
+	return block
+}
+
+// Send a stake tx the way the state transition does:
+// move the value to StakeAddr, then apply (or hand it back)
+
+func TestStakeBondUnbondWithdraw(t *testing.T) {
+	m, state := newStakeTestModel()
+	alice, bob := monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair()
+	state.GetOrNewStateObject(alice.Address()).SetBalance(big.NewInt(1000))
+	state.GetOrNewStateObject(bob.Address()).SetBalance(big.NewInt(1000))
+
+	if err := applySystemTx(m, state, alice, systemTx(StakeAddr, 100, "bond"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := applySystemTx(m, state, bob, systemTx(StakeAddr, 300, "bond"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := applySystemTx(m, state, bob, systemTx(StakeAddr, 0, "bond"), 1); err == nil {
+		t.Fatal("expected error bonding nothing")
+	}
+	if s := GetStake(m.doug, alice.Address(), state); s.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("alice has stake %v, expected 100", s)
+	}
+	if total := GetTotalStake(m.doug, state); total.Cmp(big.NewInt(400)) != 0 {
+		t.Fatalf("total stake %v, expected 400", total)
+	}
+	if b := state.GetStateObject(StakeAddr).Balance; b.Cmp(big.NewInt(400)) != 0 {
+		t.Fatalf("stake address holds %v, expected 400", b)
+	}
+
+	// can't unbond more than is bonded
+	if err := applySystemTx(m, state, alice, systemTx(StakeAddr, 0, "unbond", "0x96"), 5); err == nil {
+		t.Fatal("expected error unbonding 150 of 100")
+	}
+	if err := applySystemTx(m, state, alice, systemTx(StakeAddr, 0, "unbond", "0x28"), 5); err != nil {
+		t.Fatal(err)
+	}
+	if s := GetStake(m.doug, alice.Address(), state); s.Cmp(big.NewInt(60)) != 0 {
+		t.Fatalf("alice has stake %v after unbonding, expected 60", s)
+	}
+	if total := GetTotalStake(m.doug, state); total.Cmp(big.NewInt(360)) != 0 {
+		t.Fatalf("total stake %v after unbonding, expected 360", total)
+	}
+	amount, release := GetUnbonding(m.doug, alice.Address(), state)
+	if amount.Cmp(big.NewInt(40)) != 0 || release != 15 {
+		t.Fatalf("unbonding %v until %d, expected 40 until 15", amount, release)
+	}
+
+	// the funds are locked until the delay is up
+	if err := applySystemTx(m, state, alice, systemTx(StakeAddr, 0, "withdraw"), 14); err == nil {
+		t.Fatal("expected error withdrawing before the unbond delay")
+	}
+	if err := applySystemTx(m, state, alice, systemTx(StakeAddr, 0, "withdraw"), 15); err != nil {
+		t.Fatal(err)
+	}
+	if b := state.GetStateObject(alice.Address()).Balance; b.Cmp(big.NewInt(940)) != 0 {
+		t.Fatalf("alice has balance %v after withdrawing, expected 940", b)
+	}
+	if err := applySystemTx(m, state, alice, systemTx(StakeAddr, 0, "withdraw"), 16); err == nil {
+		t.Fatal("expected error withdrawing twice")
+	}
+	if err := applySystemTx(m, state, alice, systemTx(StakeAddr, 5, "steal"), 16); err == nil {
+		t.Fatal("expected error for unknown command")
+	}
+	if b := state.GetStateObject(alice.Address()).Balance; b.Cmp(big.NewInt(940)) != 0 {
+		t.Fatalf("failed stake tx kept the value, alice has %v", b)
+	}
+}
+
+func TestStakeDifficulty(t *testing.T) {
+	base := big.NewInt(1024)
+	total := big.NewInt(100)
+
+	if d := stakeDifficulty(base, big.NewInt(0), total); d.Cmp(monkutil.BigPow(2, 256)) != 0 {
+		t.Fatalf("no stake should be unmineable, got difficulty %v", d)
+	}
+	if d := stakeDifficulty(base, total, total); d.Cmp(base) != 0 {
+		t.Fatalf("all the stake should get the base difficulty, got %v", d)
+	}
+	half := stakeDifficulty(base, big.NewInt(50), total)
+	quarter := stakeDifficulty(base, big.NewInt(25), total)
+	if quarter.Cmp(new(big.Int).Mul(half, big.NewInt(2))) != 0 {
+		t.Fatalf("half the stake should double the difficulty (%v, %v)", half, quarter)
+	}
+}
+
+// Miners with equal hash power race to find blocks at their own
+// stake weighted difficulty. Each one's share of the blocks should
+// track its share of the stake
+func TestStakeBlockFrequency(t *testing.T) {
+	m, state := newStakeTestModel()
+	stakes := []int64{1, 2, 5}
+	miners := make([][]byte, len(stakes))
+	for i, s := range stakes {
+		miners[i] = monkcrypto.GenerateNewKeyPair().Address()
+		AddStake(m.doug, miners[i], big.NewInt(s), state)
+	}
+
+	base := big.NewInt(64)
+	total := GetTotalStake(m.doug, state)
+	diffs := make([]*big.Int, len(miners))
+	for i, miner := range miners {
+		diffs[i] = stakeDifficulty(base, GetStake(m.doug, miner, state), total)
+	}
+
+	pow := &monkchain.EasyPow{}
+	r := rand.New(rand.NewSource(1))
+	hash := monkcrypto.Sha3Bin([]byte("block"))
+	nBlocks := 1000
+	wins := make([]int, len(miners))
+	for b := 0; b < nBlocks; b++ {
+	race:
+		for {
+			// everyone tries a nonce, in random order
+			for _, i := range r.Perm(len(miners)) {
+				nonce := monkcrypto.Sha3Bin(big.NewInt(r.Int63()).Bytes())
+				if pow.Verify(hash, diffs[i], nonce) {
+					wins[i]++
+					break race
+				}
+			}
+		}
+	}
+
+	for i, s := range stakes {
+		share := float64(wins[i]) / float64(nBlocks)
+		expected := float64(s) / float64(total.Int64())
+		if math.Abs(share-expected) > 0.05 {
+			t.Errorf("miner with stake %d/%v mined %d of %d blocks (%.3f, expected %.3f)", s, total, wins[i], nBlocks, share, expected)
+		}
+	}
+}
+
+// A parent block in the db, where a bare ChainManager can find it.
+// setup runs on its state before it's stored
+func storedParent(setup func(state *monkstate.State)) *monkchain.Block {
+	parent := monkchain.CreateBlock(nil, monkchain.ZeroHash256, nil, big.NewInt(1), nil, "")
+	parent.Number = big.NewInt(0)
+	parent.Time = 1000
+	setup(parent.State())
+	parent.State().Update()
+	parent.Sync()
+	monkutil.Config.Db.Put(parent.Hash(), parent.RlpEncode())
+	return parent
+}
+
+// A signed block on parent that passes the proof of work
+func sealedBlock(m *StdLibModel, keys *monkcrypto.KeyPair, parent *monkchain.Block, diff *big.Int) *monkchain.Block {
+	block := monkchain.CreateBlock(parent.State().Trie.Root, parent.Hash(), keys.Address(), nil, nil, "")
+	block.Number = big.NewInt(1)
+	block.Time = parent.Time + 1
+	block.Difficulty = diff
+	if diff == nil {
+		block.Difficulty = m.Difficulty(block, parent)
+	}
+	for i := int64(0); ; i++ {
+		block.Nonce = monkcrypto.Sha3Bin(big.NewInt(i).Bytes())
+		if m.pow.Verify(block.HashNoNonce(), block.Difficulty, block.Nonce) {
+			break
+		}
+	}
+	block.Sign(keys.PrivateKey)
+	return block
+}
+
+func TestStakeValidateBlock(t *testing.T) {
+	m, _ := newStakeTestModel()
+	m.pow = &monkchain.EasyPow{}
+	big3, small, none := monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair(), monkcrypto.GenerateNewKeyPair()
+
+	parent := storedParent(func(state *monkstate.State) {
+		addFork(&Fork{Number: 1, Consensus: "stake-weight"}, state)
+		activateForks(state, 0)
+		AddStake(m.doug, big3.Address(), big.NewInt(3), state)
+		AddStake(m.doug, small.Address(), big.NewInt(1), state)
+		for _, k := range []*monkcrypto.KeyPair{big3, small, none} {
+			GrantPerm(m.doug, k.Address(), "mine", NoExpiry, state)
+		}
+	})
+	bc := &monkchain.ChainManager{}
+
+	if err := m.ValidateBlock(sealedBlock(m, big3, parent, nil), bc); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ValidateBlock(sealedBlock(m, small, parent, nil), bc); err != nil {
+		t.Fatal(err)
+	}
+
+	// less stake is harder
+	bigDiff := m.Difficulty(sealedBlock(m, big3, parent, nil), parent)
+	smallDiff := m.Difficulty(sealedBlock(m, small, parent, nil), parent)
+	if smallDiff.Cmp(bigDiff) <= 0 {
+		t.Fatalf("expected the small staker to have the higher difficulty (%v <= %v)", smallDiff, bigDiff)
+	}
+	// and can't claim the big staker's difficulty
+	err := m.ValidateBlock(sealedBlock(m, small, parent, bigDiff), bc)
+	if _, ok := err.(*monkchain.InvalidDifficultyErr); !ok {
+		t.Fatal("expected a difficulty error, got", err)
+	}
+	// no stake, no block
+	if err := m.ValidateBlock(sealedBlock(m, none, parent, big.NewInt(1)), bc); err == nil {
+		t.Fatal("expected an error for a miner with no stake")
+	}
+}
@@ -0,0 +1,41 @@
+package monkdoug
+
+import (
+	"math/big"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkcrypto"
+	"github.com/eris-ltd/thelonious/monkstate"
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+// A StdLibModel or a Protocol
+type systemTxApplier interface {
+	monkchain.SystemTxs
+	monkchain.BlockHooks
+}
+
+func systemTx(addr []byte, value int64, args ...string) *monkchain.Transaction {
+	return monkchain.NewTransactionMessage(addr, big.NewInt(value), big.NewInt(10000), big.NewInt(1), monkutil.PackTxDataArgs2(args...))
+}
+
+// Sign the tx and apply it in block number the way the block manager would,
+// sending the value first and giving it back on an error
+func applySystemTx(sys systemTxApplier, state *monkstate.State, keys *monkcrypto.KeyPair, tx *monkchain.Transaction, number int64) error {
+	tx.Sign(keys.PrivateKey)
+	if !sys.IsSystemTx(tx, state) {
+		return monkchain.ValidationError("not a system tx")
+	}
+
+	sender := state.GetOrNewStateObject(tx.Sender())
+	recipient := state.GetOrNewStateObject(tx.Recipient)
+	sender.SubAmount(tx.Value)
+	recipient.AddAmount(tx.Value)
+	sys.PreBlock(state, blockAt(number))
+	err := sys.ApplySystemTx(tx, state, blockAt(number))
+	if err != nil {
+		recipient.SubAmount(tx.Value)
+		sender.AddAmount(tx.Value)
+	}
+	return err
+}
@@ -211,6 +211,20 @@ func (m *VmModel) EvmCall(code, data []byte, stateObject *monkstate.StateObject,
 	return ret
 }
 
+// Gas for each run of a block hook
+var BlockHookGas = big.NewInt(10000000)
+
+// Run a block hook contract with its own gas, in an env that
+// can see the block. Returns the output and the gas used
+func (m *VmModel) hookCall(obj *monkstate.StateObject, data []byte, state *monkstate.State, block *monkchain.Block) ([]byte, *big.Int, error) {
+	msg := &monkstate.Message{}
+	closure := monkvm.NewClosure(msg, obj, obj, obj.Code, new(big.Int).Set(BlockHookGas), new(big.Int))
+
+	env := &blockEnv{NewEnv(state, nil, block, m.g.protocol)}
+	vm := monkvm.New(env)
+	return closure.Call(vm, data)
+}
+
 type VMEnv struct {
 	protocol monkchain.Protocol
 	state    *monkstate.State
@@ -241,3 +255,30 @@ func (self *VMEnv) Doug() []byte            { return self.protocol.Doug() }
 func (self *VMEnv) DougValidate(addr []byte, role string, state *monkstate.State) error {
 	return self.protocol.ValidatePerm(addr, role, state)
 }
+
+// An env for code run as part of processing a block.
+// The hash isn't known until the block is sealed, so it's left out
+type blockEnv struct {
+	*VMEnv
+}
+
+func (self *blockEnv) BlockNumber() *big.Int { return self.block.Number }
+func (self *blockEnv) PrevHash() []byte      { return self.block.PrevHash }
+func (self *blockEnv) Coinbase() []byte      { return self.block.Coinbase }
+func (self *blockEnv) Time() int64           { return self.block.Time }
+func (self *blockEnv) Difficulty() *big.Int  { return self.block.Difficulty }
+
+// Does the certificate have at least threshold (and at least one)
+// distinct signers that pass the permission check
+func certified(cert *monkchain.CheckpointCert, threshold int, hasPerm func(addr []byte) bool) bool {
+	if threshold < 1 {
+		threshold = 1
+	}
+	n := 0
+	for _, signer := range cert.Signers() {
+		if hasPerm(signer) {
+			n++
+		}
+	}
+	return n >= threshold
+}
@@ -0,0 +1,149 @@
+package monkminer
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/eris-ltd/thelonious/monkchain"
+	"github.com/eris-ltd/thelonious/monkdoug"
+	"github.com/eris-ltd/thelonious/monkreact"
+	"github.com/eris-ltd/thelonious/monkutil"
+	"github.com/eris-ltd/thelonious/monkwire"
+)
+
+// The bft miner takes part in validator rounds instead of
+// searching for nonces. It drives a monkdoug.BftState
+// with blocks from the tx pool and commits the results
+type BftMiner struct {
+	thelonious monkchain.NodeManager
+	coinbase   []byte
+	state      *monkdoug.BftState
+
+	reactChan chan monkreact.Event
+	quitChan  chan bool
+}
+
+func NewBftMiner(coinbase []byte, thelonious monkchain.NodeManager, model *monkdoug.BftModel) *BftMiner {
+	miner := &BftMiner{
+		thelonious: thelonious,
+		coinbase:   coinbase,
+	}
+	height := thelonious.ChainManager().CurrentBlockNumber() + 1
+	miner.state = monkdoug.NewBftState(model, miner, thelonious.KeyManager().KeyPair(), height)
+	return miner
+}
+
+func (self *BftMiner) Start() {
+	self.reactChan = make(chan monkreact.Event, 10)
+	self.quitChan = make(chan bool)
+
+	// consensus messages come in from peers through the reactor
+	reactor := self.thelonious.Reactor()
+	reactor.Subscribe("bft", self.reactChan)
+
+	go self.listener()
+	self.state.Start()
+
+	reactor.Post("miner:start", self)
+	logger.Infoln("Started bft rounds")
+}
+
+func (self *BftMiner) listener() {
+	for {
+		select {
+		case <-self.quitChan:
+			return
+		case ev := <-self.reactChan:
+			if msg, ok := ev.Resource.(*monkwire.Msg); ok {
+				self.state.Receive(msg.Type, msg.Data)
+			}
+		}
+	}
+}
+
+func (self *BftMiner) Stop() {
+	logger.Infoln("Stopping...")
+	self.state.Stop()
+	close(self.quitChan)
+
+	reactor := self.thelonious.Reactor()
+	reactor.Unsubscribe("bft", self.reactChan)
+	reactor.Post("miner:stop", self)
+}
+
+// Build a block from the tx pool, just like mining without the nonce
+func (self *BftMiner) Propose(height uint64) *monkchain.Block {
+	stateManager := self.thelonious.BlockManager()
+	chainMan := self.thelonious.ChainManager()
+	block := chainMan.NewBlock(self.coinbase)
+
+	parent := chainMan.GetBlock(block.PrevHash)
+	if parent == nil || block.Number.Uint64() != height {
+		return nil
+	}
+
+	txs := self.thelonious.TxPool().CurrentTransactions()
+	txs = monkchain.SortByPriceAndNonce(txs)
+
+	coinbase := block.State().GetOrNewStateObject(block.Coinbase)
+	coinbase.SetGasPool(block.CalcGasLimit(parent))
+	stateManager.PreBlock(block.State(), block)
+	receipts, txs, _, err := stateManager.ProcessTransactions(coinbase, block.State(), block, block, txs)
+	if err != nil {
+		logger.Debugln(err)
+	}
+	stateManager.PostBlock(block.State(), block)
+	block.SetTxHash(receipts)
+	block.SetReceipts(receipts, txs)
+	block.LogsBloom = monkchain.CreateHeaderBloom(block.TxReceipts())
+	stateManager.AccumelateRewards(block.State(), block, parent)
+	block.State().Update()
+
+	block.Sign(self.thelonious.KeyManager().KeyPair().PrivateKey)
+	logger.Infof("Proposing block %d. Includes %v transactions", block.Number, len(txs))
+	return block
+}
+
+// A proposal must extend our head and process cleanly
+func (self *BftMiner) Validate(block *monkchain.Block) error {
+	chainMan := self.thelonious.ChainManager()
+	if !bytes.Equal(block.PrevHash, chainMan.CurrentBlockHash()) {
+		return fmt.Errorf("Proposal %x does not extend head %x", block.Hash(), chainMan.CurrentBlockHash())
+	}
+	parent := chainMan.CurrentBlock()
+	receipts, err := self.thelonious.BlockManager().ApplyDiff(parent.State().Copy(), parent, block)
+	if err != nil {
+		return err
+	}
+	if txSha := monkchain.CreateTxSha(receipts); !bytes.Equal(txSha, block.TxSha) {
+		return fmt.Errorf("Error validating tx sha. Received %x, got %x", block.TxSha, txSha)
+	}
+	return self.thelonious.Protocol().ValidateBlock(block, chainMan)
+}
+
+// Insert the committed block and mark it final
+func (self *BftMiner) Commit(block *monkchain.Block, precommits []*monkdoug.BftVote) error {
+	chainMan := self.thelonious.ChainManager()
+	if !chainMan.HasBlock(block.Hash()) {
+		lchain := monkchain.NewChain(monkchain.Blocks{block})
+		if _, err := chainMan.TestChain(lchain); err != nil {
+			return err
+		}
+		chainMan.InsertChain(lchain)
+		// let the non-validators know
+		self.thelonious.Broadcast(monkwire.MsgBlockTy, []interface{}{block.Value().Val})
+	}
+	logger.Infof("Committed block %x with %d precommits\n", block.Hash(), len(precommits))
+
+	// finalize it here, and wherever the commit goes
+	commit := monkdoug.NewBftCommit(block, precommits).RlpData()
+	if _, err := chainMan.ReceiveCommit(monkutil.NewValueFromBytes(monkutil.Encode(commit))); err != nil {
+		return err
+	}
+	self.thelonious.Broadcast(monkwire.MsgBftCommitTy, commit)
+	return nil
+}
+
+func (self *BftMiner) Broadcast(msgType monkwire.MsgType, data []interface{}) {
+	self.thelonious.Broadcast(msgType, data)
+}
@@ -2,7 +2,7 @@ package monkminer
 
 import (
 	"bytes"
-	"sort"
+	"time"
 
 	"github.com/eris-ltd/thelonious/monkchain"
 	"github.com/eris-ltd/thelonious/monklog"
@@ -34,7 +34,7 @@ func (self *Miner) GetPow() monkchain.PoW {
 
 func NewDefaultMiner(coinbase []byte, thelonious monkchain.NodeManager) *Miner {
 	miner := Miner{
-		pow:        &monkchain.EasyPow{},
+		pow:        thelonious.Protocol().PoW(),
 		thelonious: thelonious,
 		coinbase:   coinbase,
 	}
@@ -75,6 +75,7 @@ func (miner *Miner) Start() {
 	// Only after the miner will find the sha
 	reactor.Subscribe("newBlock", miner.powQuitChan)
 	reactor.Subscribe("newTx:pre", miner.powQuitChan)
+	reactor.Subscribe("chain:rewind", miner.powQuitChan)
 
 	reactor.Post("miner:start", miner)
 }
@@ -164,6 +165,7 @@ func (miner *Miner) Stop() {
 	reactor := miner.thelonious.Reactor()
 	reactor.Unsubscribe("newBlock", miner.powQuitChan)
 	reactor.Unsubscribe("newTx:pre", miner.powQuitChan)
+	reactor.Unsubscribe("chain:rewind", miner.powQuitChan)
 	reactor.Unsubscribe("newBlock", miner.reactChan)
 	reactor.Unsubscribe("newTx:pre", miner.reactChan)
 
@@ -187,27 +189,60 @@ func (self *Miner) mineNewBlock() {
 		return
 	}
 
-	// Apply uncles
-	if len(self.uncles) > 0 {
+	// if blocks are sealed by signature, there's no nonce to find.
+	// just wait our turn, then build the block fresh
+	var sealing bool
+	if sealer, ok := self.thelonious.Protocol().(monkchain.Sealer); ok {
+		var sealTime time.Time
+		if sealTime, sealing = sealer.SealTime(self.coinbase, parent); sealing {
+			if !self.waitSeal(sealTime) {
+				return
+			}
+			self.block = chainMan.NewBlock(self.coinbase)
+		}
+	}
+
+	// Apply uncles (if the chain allows them)
+	if len(self.uncles) > 0 && stateManager.RewardPolicy(self.block.State()).Uncles {
 		self.block.SetUncles(self.uncles)
 	}
 
-	// Sort the transactions by nonce in case of odd network propagation
-	sort.Sort(monkchain.TxByNonce{self.txs})
+	// while halted, only admin txs make it in.
+	// The rest wait for the chain to resume
+	var held monkchain.Transactions
+	if halter, ok := self.thelonious.Protocol().(monkchain.Halter); ok && halter.Halted(parent.State()) {
+		logger.Infoln("Chain is halted. Mining a heartbeat block")
+		admin := monkchain.Transactions{}
+		for _, tx := range self.txs {
+			if self.thelonious.Protocol().ValidatePerm(tx.Sender(), "admin", parent.State()) == nil {
+				admin = append(admin, tx)
+			} else {
+				held = append(held, tx)
+			}
+		}
+		self.txs = admin
+	}
+
+	// Sort the transactions by gas price, keeping each sender's in nonce order
+	self.txs = monkchain.SortByPriceAndNonce(self.txs)
 
 	// Accumulate all valid transactions and apply them to the new state
 	// Error may be ignored. It's not important during mining
 	coinbase := self.block.State().GetOrNewStateObject(self.block.Coinbase)
 	coinbase.SetGasPool(self.block.CalcGasLimit(parent))
+	stateManager.PreBlock(self.block.State(), self.block)
 	receipts, txs, unhandledTxs, err := stateManager.ProcessTransactions(coinbase, self.block.State(), self.block, self.block, self.txs)
 	if err != nil {
 		logger.Debugln(err)
 	}
+	stateManager.PostBlock(self.block.State(), self.block)
 	self.txs = append(txs, unhandledTxs...)
+	self.txs = append(self.txs, held...)
 	self.block.SetTxHash(receipts)
 
 	// Set the transactions to the block so the new SHA3 can be calculated
 	self.block.SetReceipts(receipts, txs)
+	self.block.LogsBloom = monkchain.CreateHeaderBloom(self.block.TxReceipts())
 
 	// Accumulate the rewards included for this block
 	stateManager.AccumelateRewards(self.block.State(), self.block, parent)
@@ -216,8 +251,12 @@ func (self *Miner) mineNewBlock() {
 
 	logger.Infof("Mining on block %d. Includes %v transactions", self.block.Number, len(self.txs))
 
-	// Find a valid nonce
-	self.block.Nonce = self.pow.Search(self.block, self.powQuitChan)
+	// Find a valid nonce (or skip it if the signature is the seal)
+	if sealing {
+		self.block.Nonce = make([]byte, 32)
+	} else {
+		self.block.Nonce = self.pow.Search(self.block, self.powQuitChan)
+	}
 	if self.block.Nonce != nil {
 		// sign the block
 		keypair := self.thelonious.KeyManager().KeyPair()
@@ -251,3 +290,18 @@ func (self *Miner) mineNewBlock() {
 			}*/
 	}
 }
+
+// Wait for the seal time. Returns false if a new block
+// or tx (or a stop) interrupts first
+func (self *Miner) waitSeal(sealTime time.Time) bool {
+	timer := time.NewTimer(sealTime.Sub(time.Now()))
+	defer timer.Stop()
+
+	logger.Infoln("Waiting to seal until", sealTime)
+	select {
+	case <-timer.C:
+		return true
+	case <-self.powQuitChan:
+		return false
+	}
+}
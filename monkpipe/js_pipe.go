@@ -34,6 +34,61 @@ func (self *JSPipe) BlockByNumber(num int32) *JSBlock {
 	return NewJSBlock(self.obj.ChainManager().GetBlockByNumber(uint64(num)))
 }
 
+// A canonical tx by hash, with where it was included
+func (self *JSPipe) TransactionByHash(strHash string) *JSTransaction {
+	tx, block, i := self.obj.ChainManager().GetTransaction(monkutil.Hex2Bytes(strHash))
+	if tx == nil {
+		return nil
+	}
+	jstx := NewJSTx(tx)
+	jstx.BlockHash = monkutil.Bytes2Hex(block.Hash())
+	jstx.BlockNumber = int(block.Number.Uint64())
+	jstx.Index = int(i)
+	return jstx
+}
+
+// The receipt of a canonical tx
+func (self *JSPipe) Receipt(strHash string) *JSTxReceipt {
+	hash := monkutil.Hex2Bytes(strHash)
+	chain := self.obj.ChainManager()
+	receipt := chain.GetReceipt(hash)
+	if receipt == nil {
+		return nil
+	}
+	_, block, i := chain.GetTransaction(hash)
+	if block == nil {
+		return nil
+	}
+	return NewJSTxReceipt(receipt, block, i)
+}
+
+// The blocks we've rejected, newest first
+func (self *JSPipe) BadBlocks() []*JSBadBlock {
+	bad := self.obj.ChainManager().BadBlocks()
+	blocks := make([]*JSBadBlock, len(bad))
+	for i, b := range bad {
+		blocks[i] = NewJSBadBlock(b, false)
+	}
+	return blocks
+}
+
+// A rejected block with its rlp, so it can be replayed
+func (self *JSPipe) BadBlock(strHash string) *JSBadBlock {
+	bad := self.obj.ChainManager().GetBadBlock(monkutil.Hex2Bytes(strHash))
+	if bad == nil {
+		return nil
+	}
+	return NewJSBadBlock(bad, true)
+}
+
+// Rewind the chain to block num and return the new head
+func (self *JSPipe) SetHead(num int) (*JSBlock, error) {
+	if err := self.obj.BlockManager().SetHead(uint64(num)); err != nil {
+		return nil, err
+	}
+	return NewJSBlock(self.obj.ChainManager().CurrentBlock()), nil
+}
+
 func (self *JSPipe) Block(v interface{}) *JSBlock {
 	if n, ok := v.(int32); ok {
 		return self.BlockByNumber(n)
@@ -106,6 +161,11 @@ func (self *JSPipe) TxCountAt(address string) int {
 	return int(self.obj.BlockManager().TransState().GetOrNewStateObject(monkutil.Hex2Bytes(address)).Nonce)
 }
 
+// Lowest gas price the tx pool will take
+func (self *JSPipe) MinGasPrice() string {
+	return self.obj.TxPool().MinGasPrice().String()
+}
+
 func (self *JSPipe) CodeAt(address string) string {
 	return monkutil.Bytes2Hex(self.World().SafeGet(monkutil.Hex2Bytes(address)).Code)
 }
@@ -230,6 +290,7 @@ func (self *JSPipe) Transact(key, toStr, valueStr, gasStr, gasPriceStr, codeStr
 	acc.Nonce += 1
 	self.obj.BlockManager().TransState().UpdateStateObject(acc)
 
+	self.DoWork(tx, keyPair.Address())
 	tx.Sign(keyPair.PrivateKey)
 	self.obj.TxPool().QueueTransaction(tx)
 
@@ -81,6 +81,10 @@ type JSTransaction struct {
 	Contract        bool   `json:"isContract"`
 	CreatesContract bool   `json:"createsContract"`
 	Confirmations   int    `json:"confirmations"`
+	// where it was included, if we looked it up by hash
+	BlockHash   string `json:"blockHash,omitempty"`
+	BlockNumber int    `json:"blockNumber,omitempty"`
+	Index       int    `json:"index,omitempty"`
 }
 
 func NewJSTx(tx *monkchain.Transaction) *JSTransaction {
@@ -191,6 +195,63 @@ func NewJSReciept(contractCreation bool, creationAddress, hash, address []byte)
 	}
 }
 
+// What a processed tx did
+type JSTxReceipt struct {
+	TxHash            string  `json:"txHash"`
+	BlockHash         string  `json:"blockHash"`
+	BlockNumber       int     `json:"blockNumber"`
+	Index             int     `json:"index"`
+	Failed            bool    `json:"failed"`
+	Error             string  `json:"error,omitempty"`
+	GasUsed           string  `json:"gasUsed"`
+	CumulativeGasUsed string  `json:"cumulativeGasUsed"`
+	ContractAddress   string  `json:"contractAddress,omitempty"`
+	Output            string  `json:"output"`
+	Logs              []JSLog `json:"logs"`
+}
+
+func NewJSTxReceipt(receipt *monkchain.TxReceipt, block *monkchain.Block, index uint64) *JSTxReceipt {
+	logs := make([]JSLog, len(receipt.Logs))
+	for i, l := range receipt.Logs {
+		logs[i] = NewJSLog(l)
+	}
+	var contract string
+	if len(receipt.ContractAddress) != 0 {
+		contract = monkutil.Bytes2Hex(receipt.ContractAddress)
+	}
+	return &JSTxReceipt{
+		TxHash:            monkutil.Bytes2Hex(receipt.TxHash),
+		BlockHash:         monkutil.Bytes2Hex(block.Hash()),
+		BlockNumber:       int(block.Number.Uint64()),
+		Index:             int(index),
+		Failed:            receipt.Failed(),
+		Error:             receipt.Err,
+		GasUsed:           receipt.GasUsed.String(),
+		CumulativeGasUsed: receipt.CumulativeGasUsed.String(),
+		ContractAddress:   contract,
+		Output:            monkutil.Bytes2Hex(receipt.Output),
+		Logs:              logs,
+	}
+}
+
+type JSLog struct {
+	Address string   `json:"address"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
+}
+
+func NewJSLog(log *monkstate.Log) JSLog {
+	topics := make([]string, len(log.Topics))
+	for i, t := range log.Topics {
+		topics[i] = monkutil.Bytes2Hex(t)
+	}
+	return JSLog{
+		Address: monkutil.Bytes2Hex(log.Address),
+		Topics:  topics,
+		Data:    monkutil.Bytes2Hex(log.Data),
+	}
+}
+
 type JSMessage struct {
 	To        string `json:"to"`
 	From      string `json:"from"`
@@ -220,3 +281,27 @@ func NewJSMessage(message *monkstate.Message) JSMessage {
 		Value:     message.Value.String(),
 	}
 }
+
+// A block we rejected. Rlp is only filled in when asked for by hash
+type JSBadBlock struct {
+	Hash   string `json:"hash"`
+	Number int    `json:"number"`
+	Error  string `json:"error"`
+	Peer   string `json:"peer"`
+	Time   int64  `json:"time"`
+	Rlp    string `json:"rlp,omitempty"`
+}
+
+func NewJSBadBlock(bad *monkchain.BadBlock, withRlp bool) *JSBadBlock {
+	b := &JSBadBlock{
+		Hash:   monkutil.Bytes2Hex(bad.Hash),
+		Number: int(bad.Number),
+		Error:  bad.Err,
+		Peer:   bad.Peer,
+		Time:   bad.Time,
+	}
+	if withRlp {
+		b.Rlp = monkutil.Bytes2Hex(bad.Raw)
+	}
+	return b
+}
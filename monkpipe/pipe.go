@@ -157,6 +157,7 @@ func (self *Pipe) Transact(key *monkcrypto.KeyPair, rec []byte, value, gas, pric
 	tx.Nonce = acc.Nonce
 	acc.Nonce += 1
 	self.stateManager.TransState().UpdateStateObject(acc)
+	self.DoWork(tx, key.Address())
 	tx.Sign(key.PrivateKey)
 	self.obj.TxPool().QueueTransaction(tx)
 
@@ -169,6 +170,13 @@ func (self *Pipe) Transact(key *monkcrypto.KeyPair, rec []byte, value, gas, pric
 	return tx.Hash(), nil
 }
 
+// Do any work the chain wants on tx from sender
+func (self *Pipe) DoWork(tx *monkchain.Transaction, sender []byte) {
+	if work, ok := self.obj.Protocol().(monkchain.TxWork); ok {
+		tx.DoWork(work.TxWorkDifficulty(self.stateManager.CurrentState()), sender)
+	}
+}
+
 func (self *Pipe) PushTx(tx *monkchain.Transaction) ([]byte, error) {
 	self.obj.TxPool().QueueTransaction(tx)
 	if tx.Recipient == nil {
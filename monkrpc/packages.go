@@ -74,7 +74,97 @@ func (p *TheloniousApi) GetBlock(args *GetBlockArgs, reply *string) error {
 		return err
 	}
 
-	block := p.pipe.BlockByHash(args.Hash)
+	var block *monkpipe.JSBlock
+	if args.Hash != "" {
+		block = p.pipe.BlockByHash(args.Hash)
+	} else {
+		block = p.pipe.BlockByNumber(int32(args.BlockNumber))
+	}
+	*reply = NewSuccessRes(block)
+	return nil
+}
+
+type GetTransactionArgs struct {
+	Hash string `json:"hash"`
+}
+
+func (a *GetTransactionArgs) requirements() error {
+	if a.Hash == "" {
+		return NewErrorResponse("GetTransaction requires a tx 'hash' as argument")
+	}
+	return nil
+}
+
+func (p *TheloniousApi) GetTransaction(args *GetTransactionArgs, reply *string) error {
+	err := args.requirements()
+	if err != nil {
+		return err
+	}
+	tx := p.pipe.TransactionByHash(args.Hash)
+	if tx == nil {
+		return NewErrorResponse("Unknown transaction " + args.Hash)
+	}
+	*reply = NewSuccessRes(tx)
+	return nil
+}
+
+func (p *TheloniousApi) GetReceipt(args *GetTransactionArgs, reply *string) error {
+	err := args.requirements()
+	if err != nil {
+		return err
+	}
+	receipt := p.pipe.Receipt(args.Hash)
+	if receipt == nil {
+		return NewErrorResponse("No receipt for transaction " + args.Hash)
+	}
+	*reply = NewSuccessRes(receipt)
+	return nil
+}
+
+func (p *TheloniousApi) GetBadBlocks(args *interface{}, reply *string) error {
+	*reply = NewSuccessRes(p.pipe.BadBlocks())
+	return nil
+}
+
+func (p *TheloniousApi) GetBadBlock(args *GetTransactionArgs, reply *string) error {
+	if args.Hash == "" {
+		return NewErrorResponse("GetBadBlock requires a block 'hash' as argument")
+	}
+	bad := p.pipe.BadBlock(args.Hash)
+	if bad == nil {
+		return NewErrorResponse("Unknown bad block " + args.Hash)
+	}
+	*reply = NewSuccessRes(bad)
+	return nil
+}
+
+// Calls that change the chain under the node.
+// Only served if the server has admin enabled
+type AdminApi struct {
+	pipe *monkpipe.JSPipe
+}
+
+type SetHeadArgs struct {
+	// a pointer so a missing number isn't taken as 0
+	BlockNumber *int `json:"number"`
+}
+
+func (a *SetHeadArgs) requirements() error {
+	if a.BlockNumber == nil || *a.BlockNumber < 0 {
+		return NewErrorResponse("SetHead requires a positive block 'number' as argument")
+	}
+	return nil
+}
+
+func (p *AdminApi) SetHead(args *SetHeadArgs, reply *string) error {
+	err := args.requirements()
+	if err != nil {
+		return err
+	}
+	block, err := p.pipe.SetHead(*args.BlockNumber)
+	if err != nil {
+		return NewErrorResponse(err.Error())
+	}
 	*reply = NewSuccessRes(block)
 	return nil
 }
@@ -250,6 +340,15 @@ func (p *TheloniousApi) GetIsMining(args *interface{}, reply *string) error {
 	return nil
 }
 
+type GetMinGasPriceRes struct {
+	MinGasPrice string `json:"minGasPrice"`
+}
+
+func (p *TheloniousApi) GetMinGasPrice(args *interface{}, reply *string) error {
+	*reply = NewSuccessRes(GetMinGasPriceRes{MinGasPrice: p.pipe.MinGasPrice()})
+	return nil
+}
+
 type GetTxCountArgs struct {
 	Address string `json:"address"`
 }
@@ -15,6 +15,7 @@ type JsonRpcServer struct {
 	quit     chan bool
 	listener net.Listener
 	pipe     *monkpipe.JSPipe
+	admin    bool
 }
 
 func (s *JsonRpcServer) exitHandler() {
@@ -38,6 +39,10 @@ func (s *JsonRpcServer) Start() {
 	logger.Infoln("Starting JSON-RPC server")
 	go s.exitHandler()
 	rpc.Register(&TheloniousApi{pipe: s.pipe})
+	if s.admin {
+		logger.Infoln("Serving the admin api")
+		rpc.Register(&AdminApi{pipe: s.pipe})
+	}
 	rpc.HandleHTTP()
 
 	for {
@@ -50,6 +55,11 @@ func (s *JsonRpcServer) Start() {
 	}
 }
 
+// Serve the AdminApi as well. Call before Start
+func (s *JsonRpcServer) EnableAdmin() {
+	s.admin = true
+}
+
 func NewJsonRpcServer(pipe *monkpipe.JSPipe, addr string) (*JsonRpcServer, error) {
 	l, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -0,0 +1,56 @@
+package monkstate
+
+import (
+	"fmt"
+
+	"github.com/eris-ltd/thelonious/monkutil"
+)
+
+// An event emitted by a contract
+type Log struct {
+	Address []byte
+	Topics  [][]byte
+	Data    []byte
+}
+
+func NewLogFromValue(val *monkutil.Value) *Log {
+	l := &Log{}
+	l.Address = val.Get(0).Bytes()
+	topics := val.Get(1)
+	l.Topics = make([][]byte, topics.Len())
+	for i := range l.Topics {
+		l.Topics[i] = topics.Get(i).Bytes()
+	}
+	l.Data = val.Get(2).Bytes()
+	return l
+}
+
+func (self *Log) RlpData() interface{} {
+	topics := make([]interface{}, len(self.Topics))
+	for i, t := range self.Topics {
+		topics[i] = t
+	}
+	return []interface{}{self.Address, topics, self.Data}
+}
+
+func (self *Log) String() string {
+	return fmt.Sprintf("Log{address: %x topics: %x data: %x}", self.Address, self.Topics, self.Data)
+}
+
+type Logs []*Log
+
+func NewLogsFromValue(val *monkutil.Value) Logs {
+	logs := make(Logs, val.Len())
+	for i := range logs {
+		logs[i] = NewLogFromValue(val.Get(i))
+	}
+	return logs
+}
+
+func (self Logs) RlpData() interface{} {
+	data := make([]interface{}, len(self))
+	for i, l := range self {
+		data[i] = l.RlpData()
+	}
+	return data
+}
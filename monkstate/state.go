@@ -24,6 +24,9 @@ type State struct {
 
 	manifest *Manifest
 
+	// logs emitted since the last EmptyLogs
+	logs Logs
+
 	mut sync.Mutex // for locking the cache
 }
 
@@ -151,6 +154,7 @@ func (self *State) Copy() *State {
 		for k, stateObject := range self.stateObjects {
 			state.stateObjects[k] = stateObject.Copy()
 		}
+		state.logs = append(Logs(nil), self.logs...)
 
 		return state
 	}
@@ -166,6 +170,19 @@ func (self *State) Set(state *State) {
 	defer self.mut.Unlock()
 	self.Trie = state.Trie
 	self.stateObjects = state.stateObjects
+	self.logs = state.logs
+}
+
+func (self *State) AddLog(log *Log) {
+	self.logs = append(self.logs, log)
+}
+
+func (self *State) Logs() Logs {
+	return self.logs
+}
+
+func (self *State) EmptyLogs() {
+	self.logs = nil
 }
 
 func (s *State) Root() interface{} {
@@ -38,6 +38,9 @@ func (db *MemDatabase) Delete(key []byte) error {
 func (db *MemDatabase) Print()              {}
 func (db *MemDatabase) Close()              {}
 func (db *MemDatabase) LastKnownTD() []byte { return nil }
+func (db *MemDatabase) NewBatch() monkutil.Batch {
+	return monkutil.NewQueuedBatch(db)
+}
 
 func NewTrie() (*MemDatabase, *Trie) {
 	db, _ := NewMemDatabase()
@@ -9,4 +9,49 @@ type Database interface {
 	LastKnownTD() []byte
 	Close()
 	Print()
+	NewBatch() Batch
+}
+
+// Writes queued on a batch hit the db together on Write,
+// so a crash can't leave half of them behind
+type Batch interface {
+	Put(key []byte, value []byte)
+	Delete(key []byte)
+	Write() error
+}
+
+type batchOp struct {
+	key, value []byte
+	del        bool
+}
+
+// A batch that replays its writes on the db in order.
+// Only for in-memory dbs, which a crash takes down whole anyway
+type QueuedBatch struct {
+	db  Database
+	ops []batchOp
+}
+
+func NewQueuedBatch(db Database) *QueuedBatch {
+	return &QueuedBatch{db: db}
+}
+
+func (b *QueuedBatch) Put(key []byte, value []byte) {
+	b.ops = append(b.ops, batchOp{key: CopyBytes(key), value: CopyBytes(value)})
+}
+
+func (b *QueuedBatch) Delete(key []byte) {
+	b.ops = append(b.ops, batchOp{key: CopyBytes(key), del: true})
+}
+
+func (b *QueuedBatch) Write() error {
+	for _, op := range b.ops {
+		if op.del {
+			b.db.Delete(op.key)
+		} else {
+			b.db.Put(op.key, op.value)
+		}
+	}
+	b.ops = nil
+	return nil
 }
@@ -20,6 +20,9 @@ var (
 	GasMemory  = big.NewInt(1)
 	GasData    = big.NewInt(5)
 	GasTx      = big.NewInt(500)
+	GasLog     = big.NewInt(20)
+	GasTopic   = big.NewInt(20)
+	GasLogData = big.NewInt(1)
 
 	Pow256 = monkutil.BigPow(2, 256)
 
@@ -0,0 +1,77 @@
+package monkvm
+
+import (
+	"math/big"
+	"reflect"
+	"sync"
+)
+
+// Gas prices for running the vm.
+// Forks can switch the schedule by name (see RegisterGasSchedule)
+type GasSchedule struct {
+	Step    *big.Int
+	Sha     *big.Int
+	SLoad   *big.Int
+	SStore  *big.Int
+	Balance *big.Int
+	Nonce   *big.Int
+	Create  *big.Int
+	Call    *big.Int
+	Memory  *big.Int
+	Data    *big.Int
+	Tx      *big.Int
+	// LOGn costs Log + n*Topic + LogData per byte
+	Log     *big.Int
+	Topic   *big.Int
+	LogData *big.Int
+}
+
+// The prices in common.go
+var DefaultGasSchedule = &GasSchedule{
+	Step:    GasStep,
+	Sha:     GasSha,
+	SLoad:   GasSLoad,
+	SStore:  GasSStore,
+	Balance: GasBalance,
+	Nonce:   GasNonce,
+	Create:  GasCreate,
+	Call:    GasCall,
+	Memory:  GasMemory,
+	Data:    GasData,
+	Tx:      GasTx,
+	Log:     GasLog,
+	Topic:   GasTopic,
+	LogData: GasLogData,
+}
+
+var (
+	gasSchedules   = map[string]*GasSchedule{"default": DefaultGasSchedule}
+	gasScheduleMut sync.RWMutex
+)
+
+// Make a gas schedule available by name. Prices it leaves nil
+// (like ones added to the vm after it was written) are the defaults
+func RegisterGasSchedule(name string, schedule *GasSchedule) {
+	filled := *schedule
+	v, def := reflect.ValueOf(&filled).Elem(), reflect.ValueOf(DefaultGasSchedule).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).IsNil() {
+			v.Field(i).Set(def.Field(i))
+		}
+	}
+
+	gasScheduleMut.Lock()
+	defer gasScheduleMut.Unlock()
+	gasSchedules[name] = &filled
+}
+
+// Look up a gas schedule. The empty name is the default
+func GetGasSchedule(name string) (*GasSchedule, bool) {
+	if name == "" {
+		return DefaultGasSchedule, true
+	}
+	gasScheduleMut.RLock()
+	defer gasScheduleMut.RUnlock()
+	schedule, ok := gasSchedules[name]
+	return schedule, ok
+}
@@ -47,6 +47,11 @@ type Vm struct {
 	queue *list.List
 
 	callStack *[][]byte // list of addrs
+
+	// gas prices
+	gas *GasSchedule
+	// LOG0-LOG4 are invalid opcodes without it
+	logs bool
 }
 
 type Environment interface {
@@ -75,7 +80,16 @@ func New(env Environment) *Vm {
 		lt = LogTyDiff
 	}
 
-	return &Vm{env: env, logTy: lt, Recoverable: true, queue: list.New(), callStack: new([][]byte)}
+	return &Vm{env: env, logTy: lt, Recoverable: true, queue: list.New(), callStack: new([][]byte), gas: DefaultGasSchedule, logs: true}
+}
+
+func (self *Vm) SetGasSchedule(schedule *GasSchedule) {
+	self.gas = schedule
+}
+
+// Chains from before the LOG opcodes keep them invalid until a fork
+func (self *Vm) SetLogs(on bool) {
+	self.logs = on
 }
 
 func calcMemSize(off, l *big.Int) *big.Int {
@@ -194,7 +208,7 @@ func (self *Vm) RunClosure(closure *Closure) (ret []byte, err error) {
 			}
 		}
 
-		addStepGasUsage(GasStep)
+		addStepGasUsage(self.gas.Step)
 		var newMemSize *big.Int = monkutil.Big0
 		switch op {
 		case STOP:
@@ -202,7 +216,7 @@ func (self *Vm) RunClosure(closure *Closure) (ret []byte, err error) {
 		case SUICIDE:
 			gas.Set(monkutil.Big0)
 		case SLOAD:
-			gas.Set(GasSLoad)
+			gas.Set(self.gas.SLoad)
 		case SSTORE:
 			var mult *big.Int
 			y, x := stack.Peekn()
@@ -214,11 +228,11 @@ func (self *Vm) RunClosure(closure *Closure) (ret []byte, err error) {
 			} else {
 				mult = monkutil.Big1
 			}
-			gas = new(big.Int).Mul(mult, GasSStore)
+			gas = new(big.Int).Mul(mult, self.gas.SStore)
 		case BALANCE:
-			gas.Set(GasBalance)
+			gas.Set(self.gas.Balance)
 		case NONCE:
-			gas.Set(GasNonce)
+			gas.Set(self.gas.Nonce)
 		case MSTORE:
 			require(2)
 			newMemSize = calcMemSize(stack.Peek(), u256(32))
@@ -236,7 +250,7 @@ func (self *Vm) RunClosure(closure *Closure) (ret []byte, err error) {
 		case SHA3:
 			require(2)
 
-			gas.Set(GasSha)
+			gas.Set(self.gas.Sha)
 
 			newMemSize = calcMemSize(stack.Peek(), stack.data[stack.Len()-2])
 		case CALLDATACOPY:
@@ -253,7 +267,7 @@ func (self *Vm) RunClosure(closure *Closure) (ret []byte, err error) {
 			newMemSize = calcMemSize(stack.data[stack.Len()-2], stack.data[stack.Len()-4])
 		case CALL, CALLSTATELESS:
 			require(7)
-			gas.Set(GasCall)
+			gas.Set(self.gas.Call)
 			addStepGasUsage(stack.data[stack.Len()-1])
 
 			x := calcMemSize(stack.data[stack.Len()-6], stack.data[stack.Len()-7])
@@ -269,10 +283,24 @@ func (self *Vm) RunClosure(closure *Closure) (ret []byte, err error) {
 				}
 			}
 			require(3)
-			gas.Set(GasCreate)
+			gas.Set(self.gas.Create)
 
 			newMemSize = calcMemSize(stack.data[stack.Len()-2], stack.data[stack.Len()-3])
 
+		case LOG0, LOG1, LOG2, LOG3, LOG4:
+			if !self.logs {
+				// priced like any other invalid opcode
+				break
+			}
+			n := int(op - LOG0)
+			require(n + 2)
+			size, offset := stack.Peekn()
+			gas.Set(self.gas.Log)
+			gas.Add(gas, new(big.Int).Mul(big.NewInt(int64(n)), self.gas.Topic))
+			gas.Add(gas, new(big.Int).Mul(size, self.gas.LogData))
+
+			newMemSize = calcMemSize(offset, size)
+
 		case RLPDECODE:
 			require(3)
 			size, offset := stack.Peekn()
@@ -300,7 +328,7 @@ func (self *Vm) RunClosure(closure *Closure) (ret []byte, err error) {
 
 			if newMemSize.Cmp(u256(int64(mem.Len()))) > 0 {
 				memGasUsage := new(big.Int).Sub(newMemSize, u256(int64(mem.Len())))
-				memGasUsage.Mul(GasMemory, memGasUsage)
+				memGasUsage.Mul(self.gas.Memory, memGasUsage)
 				memGasUsage.Div(memGasUsage, u256(32))
 
 				addStepGasUsage(memGasUsage)
@@ -325,6 +353,21 @@ func (self *Vm) RunClosure(closure *Closure) (ret []byte, err error) {
 			stack.Print()
 		case LOGMEM:
 			mem.Print()
+		case LOG0, LOG1, LOG2, LOG3, LOG4:
+			if !self.logs {
+				vmlogger.Debugf("(pc) %-3v Invalid opcode %x\n", pc, op)
+				return closure.Return(nil), fmt.Errorf("Invalid opcode %x", op)
+			}
+			n := int(op - LOG0)
+			size, offset := stack.Popn()
+			topics := make([][]byte, n)
+			for i := range topics {
+				topics[i] = monkutil.LeftPadBytes(stack.Pop().Bytes(), 32)
+			}
+			data := monkutil.CopyBytes(mem.Get(offset.Int64(), size.Int64()))
+			self.env.State().AddLog(&monkstate.Log{Address: closure.Address(), Topics: topics, Data: data})
+
+			self.Printf(" => %x (%d topics)", data, n)
 			// 0x20 range
 		case ADD:
 			require(2)
@@ -41,8 +41,15 @@ const (
 	MsgGetBlocksTy      = 0x15
 	MsgBlockTy          = 0x16
 
-	MsgGetStateTy = 0x20
-	MsgStateTy    = 0x21
+	MsgGetStateTy   = 0x20
+	MsgStateTy      = 0x21
+	MsgCheckpointTy = 0x22
+	MsgEvidenceTy   = 0x23
+
+	MsgBftProposalTy  = 0x30
+	MsgBftPrevoteTy   = 0x31
+	MsgBftPrecommitTy = 0x32
+	MsgBftCommitTy    = 0x33
 )
 
 var msgTypeToString = map[MsgType]string{
@@ -61,6 +68,12 @@ var msgTypeToString = map[MsgType]string{
 	MsgGetBlocksTy:      "Get blocks",
 	MsgGetStateTy:       "Get state",
 	MsgStateTy:          "State",
+	MsgCheckpointTy:     "Checkpoint",
+	MsgEvidenceTy:       "Evidence",
+	MsgBftProposalTy:    "Bft proposal",
+	MsgBftPrevoteTy:     "Bft prevote",
+	MsgBftPrecommitTy:   "Bft precommit",
+	MsgBftCommitTy:      "Bft commit",
 }
 
 func (mt MsgType) String() string {
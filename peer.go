@@ -25,8 +25,11 @@ var peerlogger = monklog.NewLogger("PEER")
 const (
 	// The size of the output buffer for writing messages
 	outputBufferSize = 50
-	// Current protocol version
-	ProtocolVersion = 33
+	// Current protocol version.
+	// 34: genesis certificates in the handshake, and chainIds signed over
+	// the final genesis state (with the new gendoug singles). Genesis
+	// configs deployed before 34 give a different genesis and chainId
+	ProtocolVersion = 34
 	// Current P2P version
 	P2PVersion = 0
 	// Thelonious network version
@@ -332,7 +335,8 @@ out:
 		case msg := <-p.outputQueue:
 			if !p.StatusKnown() {
 				switch msg.Type {
-				case monkwire.MsgGetTxsTy, monkwire.MsgTxTy, monkwire.MsgGetBlockHashesTy, monkwire.MsgBlockHashesTy, monkwire.MsgGetBlocksTy, monkwire.MsgBlockTy:
+				case monkwire.MsgGetTxsTy, monkwire.MsgTxTy, monkwire.MsgGetBlockHashesTy, monkwire.MsgBlockHashesTy, monkwire.MsgGetBlocksTy, monkwire.MsgBlockTy,
+					monkwire.MsgBftProposalTy, monkwire.MsgBftPrevoteTy, monkwire.MsgBftPrecommitTy, monkwire.MsgBftCommitTy, monkwire.MsgCheckpointTy, monkwire.MsgEvidenceTy:
 					break skip
 				}
 			}
@@ -500,6 +504,10 @@ func (p *Peer) HandleInbound() {
 						block := p.thelonious.ChainManager().GetBlock(hash)
 						if block != nil {
 							blocks = append(blocks, block.Value().Raw())
+							// send commits ahead of the blocks that need them
+							if commit := p.thelonious.ChainManager().GetCommit(hash); commit != nil {
+								p.QueueMessage(monkwire.NewMessage(monkwire.MsgBftCommitTy, commit.Slice()))
+							}
 						}
 					}
 
@@ -568,6 +576,32 @@ func (p *Peer) HandleInbound() {
 					}
 					newTrie.Sync()
 					p.thelonious.Reactor().Post("chainReady", nil)
+
+				case monkwire.MsgBftProposalTy, monkwire.MsgBftPrevoteTy, monkwire.MsgBftPrecommitTy:
+					// consensus messages go to the bft miner, if we're running one
+					p.thelonious.Reactor().Post("bft", msg)
+
+				case monkwire.MsgBftCommitTy:
+					// a quorum committed a block. Pass it on if it's news
+					if news, err := p.thelonious.ChainManager().ReceiveCommit(msg.Data); err != nil {
+						peerlogger.Debugln("Bad commit:", err)
+					} else if news {
+						p.thelonious.Broadcast(monkwire.MsgBftCommitTy, msg.Data.Slice())
+					}
+
+				case monkwire.MsgCheckpointTy:
+					// merge the signatures and pass on anything new
+					cert := monkchain.NewCheckpointCertFromValue(msg.Data)
+					if news, ok := p.thelonious.ChainManager().ReceiveCheckpointCert(cert); news != nil {
+						p.thelonious.Broadcast(monkwire.MsgCheckpointTy, news.RlpData())
+						if ok {
+							p.thelonious.Reactor().Post("checkpoint", news)
+						}
+					}
+
+				case monkwire.MsgEvidenceTy:
+					// someone caught a miner equivocating
+					p.thelonious.ReportEvidence(monkchain.NewEvidenceFromValue(msg.Data))
 				}
 
 			}
@@ -745,7 +779,8 @@ func (self *Peer) pushStatus() {
 		uint32(NetVersion),
 		self.thelonious.ChainManager().TD,
 		self.thelonious.ChainManager().CurrentBlock().Hash(),
-		self.thelonious.ChainManager().Genesis().Hash(),
+		self.thelonious.ChainManager().ChainID(),
+		self.thelonious.ChainManager().GenesisCert().RlpData(),
 	})
 
 	self.QueueMessage(msg)
@@ -767,6 +802,17 @@ func (self *Peer) handleStatus(msg *monkwire.Msg) {
 		return
 	}
 
+	// the peer must prove who made its genesis
+	if c.Len() < 6 {
+		monklogger.Warnf("Missing genesis certificate for chainId %x. Disabling [eth]\n", chainId)
+		return
+	}
+	cert := monkchain.NewGenesisCertFromValue(c.Get(5))
+	if err := self.thelonious.Protocol().ValidateChainID(chainId, cert.Block); err != nil {
+		monklogger.Warnf("Could not authenticate genesis for chainId %x: %v. Disabling [eth]\n", chainId, err)
+		return
+	}
+
 	if netVersion != NetVersion {
 		monklogger.Warnf("Invalid network version %d. Disabling [eth]\n", netVersion)
 		return
@@ -388,6 +388,68 @@ func (s *Thelonious) BroadcastMsg(msg *monkwire.Msg) {
 	})
 }
 
+// Sign a checkpoint certificate for a canonical block with our key
+// and gossip it. Peers merge signatures until there are enough
+func (s *Thelonious) SignCheckpoint(hash []byte) error {
+	block := s.blockChain.GetBlockCanonical(hash)
+	if block == nil {
+		return fmt.Errorf("Can not checkpoint unknown block %x", hash)
+	}
+	cert := monkchain.NewCheckpointCert(hash, block.Number.Uint64())
+	cert.Sign(s.keyManager.KeyPair().PrivateKey)
+
+	if news, ok := s.blockChain.ReceiveCheckpointCert(cert); news != nil {
+		s.Broadcast(monkwire.MsgCheckpointTy, news.RlpData())
+		if ok {
+			s.reactor.Post("checkpoint", news)
+		}
+	}
+	return nil
+}
+
+// Gossip new evidence of equivocation and, if the protocol punishes it,
+// submit it for inclusion in a block with a tx from our key.
+// The tx may need work, so it's made in the background
+func (s *Thelonious) ReportEvidence(ev *monkchain.Evidence) {
+	if !s.blockChain.ReceiveEvidence(ev) {
+		return
+	}
+	s.Broadcast(monkwire.MsgEvidenceTy, ev.RlpData())
+	s.reactor.Post("evidence", ev)
+
+	if _, ok := s.protocol.(monkchain.Equivocation); ok {
+		go s.submitEvidence(ev)
+	}
+}
+
+func (s *Thelonious) submitEvidence(ev *monkchain.Evidence) {
+	if s.keyManager == nil || s.keyManager.KeyPair() == nil {
+		return
+	}
+	key := s.keyManager.KeyPair()
+	current := s.blockChain.CurrentBlock().State()
+	// no point if the tx won't get in
+	if err := s.protocol.ValidatePerm(key.Address(), "transact", current); err != nil {
+		monklogger.Debugln("Not submitting evidence:", err)
+		return
+	}
+	tx := s.protocol.(monkchain.Equivocation).EvidenceTx(ev, current)
+	if tx == nil {
+		return
+	}
+	tx.GasPrice = s.txPool.MinGasPrice()
+	state := s.blockManager.TransState()
+	acc := state.GetOrNewStateObject(key.Address())
+	tx.Nonce = acc.Nonce
+	acc.Nonce += 1
+	state.UpdateStateObject(acc)
+	if work, ok := s.protocol.(monkchain.TxWork); ok {
+		tx.DoWork(work.TxWorkDifficulty(current), key.Address())
+	}
+	tx.Sign(key.PrivateKey)
+	s.txPool.QueueTransaction(tx)
+}
+
 func (s *Thelonious) Peers() *list.List {
 	return s.peers
 }
@@ -684,10 +746,12 @@ func (self *Thelonious) GetFilter(id int) *monkchain.Filter {
 func (self *Thelonious) filterLoop() {
 	blockChan := make(chan monkreact.Event, 5)
 	messageChan := make(chan monkreact.Event, 5)
+	logChan := make(chan monkreact.Event, 5)
 	// Subscribe to events
 	reactor := self.Reactor()
 	reactor.Subscribe("newBlock", blockChan)
 	reactor.Subscribe("messages", messageChan)
+	reactor.Subscribe("logs", logChan)
 out:
 	for {
 		select {
@@ -712,6 +776,17 @@ out:
 					}
 				}
 			}
+		case ev := <-logChan:
+			if logs, ok := ev.Resource.(monkstate.Logs); ok {
+				for _, filter := range self.filters {
+					if filter.LogCallback != nil {
+						matched := filter.FilterLogs(logs)
+						if len(matched) > 0 {
+							filter.LogCallback(matched)
+						}
+					}
+				}
+			}
 		}
 	}
 }
@@ -722,6 +797,8 @@ func bootstrapDb(db monkutil.Database) {
 
 	if protov == 0 {
 		db.Put([]byte("ProtocolVersion"), monkutil.NewValue(ProtocolVersion).Bytes())
+	} else if protov < ProtocolVersion {
+		monklogger.Warnf("Database is from protocol version %d, we're on %d. Its genesis and chainId won't match a chain deployed now (see the README)\n", protov, ProtocolVersion)
 	}
 }
 